@@ -4,4 +4,5 @@ package main
 
 import (
 	_ "github.com/centralmind/gateway/connectors/duckdb"
+	_ "github.com/centralmind/gateway/connectors/files"
 )
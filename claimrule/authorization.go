@@ -0,0 +1,105 @@
+package claimrule
+
+import (
+	"strings"
+
+	"github.com/centralmind/gateway/errors"
+	"golang.org/x/xerrors"
+)
+
+// AuthorizationRule defines an authorization rule for a method or group of methods
+type AuthorizationRule struct {
+	// Methods defines the list of methods to which the rule applies
+	Methods []string `yaml:"methods"`
+
+	// AllowPublic allows public access without a token
+	AllowPublic bool `yaml:"allow_public"`
+
+	// RequireAllClaims determines if all ClaimRules must be true (AND)
+	// If false, one true rule is sufficient (OR)
+	RequireAllClaims bool `yaml:"require_all_claims"`
+
+	// ClaimRules list of claim validation rules
+	ClaimRules []Rule `yaml:"claim_rules"`
+}
+
+// Authorize verifies that claims and call params pass authorization rules for a method,
+// it returns errors.ErrNotAuthorized otherwise
+func Authorize(rules []AuthorizationRule, method string, claims, params map[string]interface{}) error {
+	return check(rules, method, func(rule Rule) (bool, error) {
+		return rule.Match(claims, params)
+	})
+}
+
+// AuthorizeVisible verifies that a method can be authorized for given claims.
+// Request parameters are unknown at this point, so templated claim rules are treated as matching.
+func AuthorizeVisible(rules []AuthorizationRule, method string, claims map[string]interface{}) error {
+	return check(rules, method, func(rule Rule) (bool, error) {
+		if strings.Contains(rule.Value, "{{") {
+			return true, nil
+		}
+		return rule.Match(claims, nil)
+	})
+}
+
+// check applies authorization rules for a method using evaluate to check each claim rule
+func check(rules []AuthorizationRule, method string, evaluate func(rule Rule) (bool, error)) error {
+	// no rules == not authorization, only authentication
+	if len(rules) == 0 {
+		return nil
+	}
+	// Check rules for the method
+	var applicableRules []AuthorizationRule
+	for _, rule := range rules {
+		if contains(rule.Methods, method) {
+			applicableRules = append(applicableRules, rule)
+		}
+	}
+	if len(applicableRules) == 0 {
+		for _, rule := range rules {
+			if len(rule.Methods) == 1 && rule.Methods[0] == "*" {
+				// wildcard match
+				applicableRules = append(applicableRules, rule)
+			}
+		}
+	}
+	for _, rule := range applicableRules {
+		// If method is public, allow access
+		if rule.AllowPublic {
+			return nil
+		}
+
+		// Check all claim rules
+		matches := 0
+		for _, claimRule := range rule.ClaimRules {
+			ok, err := evaluate(claimRule)
+			if err != nil {
+				return xerrors.Errorf("failed to evaluate claim rule: %w", err)
+			}
+			if ok {
+				matches++
+				// If not requiring all rules, one match is sufficient
+				if !rule.RequireAllClaims {
+					return nil
+				}
+			}
+		}
+
+		// If requiring all rules and all matched
+		if rule.RequireAllClaims && matches == len(rule.ClaimRules) {
+			return nil
+		}
+	}
+
+	return errors.ErrNotAuthorized
+}
+
+// contains checks if a slice contains an item
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
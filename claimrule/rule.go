@@ -0,0 +1,187 @@
+// Package claimrule matches claims of authenticated callers against configured rules.
+package claimrule
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"golang.org/x/xerrors"
+)
+
+// Rule represents a rule for checking a claim value
+type Rule struct {
+	// Claim defines the path to the value in JWT or user data (e.g., "email", "groups[0]", "org.name")
+	Claim string `yaml:"claim"`
+
+	// Operation defines the comparison operation ("eq", "ne", "contains", "regex", "exists")
+	Operation string `yaml:"operation"`
+
+	// Value is the expected value for comparison
+	Value string `yaml:"value"`
+}
+
+// Match checks if the claim value matches the rule, Value may be a text/template over params
+func (rule Rule) Match(claims map[string]interface{}, params map[string]interface{}) (bool, error) {
+	// Get claim value by path
+	value, ok := Value(claims, rule.Claim)
+	if !ok {
+		// If claim doesn't exist
+		if rule.Operation == "exists" {
+			return false, nil
+		}
+		return false, nil
+	}
+
+	// If operation only checks existence
+	if rule.Operation == "exists" {
+		return true, nil
+	}
+
+	// Process template in rule value
+	tmpl, err := template.New("rule").Parse(rule.Value)
+	if err != nil {
+		return false, xerrors.Errorf("invalid rule template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, params); err != nil {
+		return false, xerrors.Errorf("failed to execute template: %w", err)
+	}
+	expectedValue := buf.String()
+
+	// Check value based on operation
+	switch rule.Operation {
+	case "eq":
+		return compareEqual(value, expectedValue), nil
+	case "ne":
+		return !compareEqual(value, expectedValue), nil
+	case "contains":
+		return containsValue(value, expectedValue), nil
+	case "regex":
+		return matchRegex(value, expectedValue)
+	default:
+		return false, xerrors.Errorf("unsupported operation: %s", rule.Operation)
+	}
+}
+
+// MatchAny reports whether claims match one of rules without params.
+// Rules that fail to evaluate don't match, so a broken rule never grants anything.
+func MatchAny(rules []Rule, claims map[string]interface{}) bool {
+	for _, rule := range rules {
+		if ok, err := rule.Match(claims, nil); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Value retrieves a value from claims by path (supports nesting via dots and array indices)
+func Value(claims map[string]interface{}, path string) (interface{}, bool) {
+	parts := strings.Split(path, ".")
+	current := claims
+
+	for i, part := range parts {
+		// Check for array access
+		if idx := strings.Index(part, "["); idx != -1 {
+			arrayName := part[:idx]
+			indexStr := part[idx+1 : len(part)-1]
+
+			// Get array
+			array, ok := current[arrayName].([]interface{})
+			if !ok {
+				return nil, false
+			}
+
+			// Validate index
+			index := 0
+			_, err := fmt.Sscanf(indexStr, "%d", &index)
+			if err != nil || index < 0 || index >= len(array) {
+				return nil, false
+			}
+
+			if i == len(parts)-1 {
+				return array[index], true
+			}
+
+			// If not the last part, continue navigation
+			if next, ok := array[index].(map[string]interface{}); ok {
+				current = next
+			} else {
+				return nil, false
+			}
+			continue
+		}
+
+		// Regular field
+		if i == len(parts)-1 {
+			val, ok := current[part]
+			return val, ok
+		}
+
+		next, ok := current[part].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current = next
+	}
+
+	return nil, false
+}
+
+// compareEqual compares two values for equality
+func compareEqual(actual interface{}, expected string) bool {
+	switch v := actual.(type) {
+	case string:
+		return v == expected
+	case bool:
+		return strings.ToLower(expected) == strconv.FormatBool(v)
+	case float64:
+		expectedFloat, err := strconv.ParseFloat(expected, 64)
+		if err != nil {
+			return false
+		}
+		return v == expectedFloat
+	case int:
+		expectedInt, err := strconv.Atoi(expected)
+		if err != nil {
+			return false
+		}
+		return v == expectedInt
+	default:
+		return fmt.Sprintf("%v", actual) == expected
+	}
+}
+
+// containsValue checks if an array contains a value
+func containsValue(actual interface{}, expected string) bool {
+	switch v := actual.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if compareEqual(item, expected) {
+				return true
+			}
+		}
+	case []string:
+		for _, item := range v {
+			if item == expected {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchRegex checks if a value matches a regular expression
+func matchRegex(actual interface{}, pattern string) (bool, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, xerrors.Errorf("invalid regex pattern: %w", err)
+	}
+
+	str := fmt.Sprintf("%v", actual)
+	return re.MatchString(str), nil
+}
@@ -53,6 +53,7 @@ The command performs the following steps:
 			if err != nil {
 				return xerrors.Errorf("Failed to create connector: %w", err)
 			}
+			defer connector.Close()
 			// Retrieve table data and verify connection
 			tablesData, err := TablesData(splitTables(tables), connector)
 			if err != nil {
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"context"
+	"strings"
+
+	"github.com/centralmind/gateway/connectors"
+	gw_model "github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/plugins"
+	"github.com/centralmind/gateway/sqlguard"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+)
+
+// openDatabase creates the single connector shared by REST, Swagger and MCP:
+// query limits are applied first, then plugin wrappers of the scope. The SQL guard is built
+// before wrapping, since cost estimation needs the connector's Explainer.
+// Caller owns the connector and must Close it.
+func openDatabase(ctx context.Context, gw *gw_model.Config, scope *plugins.Scope) (connectors.Connector, *sqlguard.Guard, error) {
+	connector, err := connectors.New(gw.Database.Type, gw.Database.Connection)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("unable to init connector: %w", err)
+	}
+	connector = connectors.WithLimits(connector, gw.Database.Limits)
+	guard, err := sqlguard.New(gw.Raw, connector)
+	if err != nil {
+		_ = connector.Close()
+		return nil, nil, xerrors.Errorf("unable to init sql guard: %w", err)
+	}
+	wrapped, err := scope.Wrap(connector)
+	if err != nil {
+		_ = connector.Close()
+		return nil, nil, xerrors.Errorf("unable to init connector plugins: %w", err)
+	}
+	logPluginChain(gw.Plugins)
+	if err := wrapped.Ping(ctx); err != nil {
+		_ = wrapped.Close()
+		return nil, nil, xerrors.Errorf("unable to ping: %w", err)
+	}
+	return wrapped, guard, nil
+}
+
+// logPluginChain reports the order plugins are applied in, it is fixed for a given config
+func logPluginChain(pluginsCfg map[string]any) {
+	for tag := range pluginsCfg {
+		if _, ok := plugins.KnownPlugin(tag); !ok {
+			logrus.Warnf("Unknown plugin %s is ignored", tag)
+		}
+	}
+	chain, err := plugins.Chain(pluginsCfg)
+	if err != nil || len(chain) == 0 {
+		return
+	}
+	logrus.Infof("Plugin chain: %s", strings.Join(chain, " -> "))
+}
@@ -108,6 +108,7 @@ and ensures they follow best practices for AI agent interactions.`,
 			if err != nil {
 				return xerrors.Errorf("Failed to create connector: %w", err)
 			}
+			defer connector.Close()
 
 			resolvedTables, err := TablesData(splitTables(tables), connector)
 			if err != nil {
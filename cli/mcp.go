@@ -2,13 +2,16 @@ package cli
 
 import (
 	"context"
-	"github.com/centralmind/gateway/connectors"
+	"errors"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	"github.com/centralmind/gateway/logger"
 	"github.com/centralmind/gateway/mcpgenerator"
 	gw_model "github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/plugins"
 	"github.com/spf13/cobra"
 	"golang.org/x/xerrors"
 )
@@ -46,25 +49,47 @@ optimized for AI agent interactions.`,
 				}
 			}
 
-			srv, err := mcpgenerator.New(gw.Plugins)
+			scope, err := plugins.NewScope(*gw)
 			if err != nil {
-				return xerrors.Errorf("unable to init mcp generator: %w", err)
+				return xerrors.Errorf("unable to init plugins: %w", err)
 			}
-			connector, err := connectors.New(gw.Database.Type, gw.Database.Connection)
+			srv, err := mcpgenerator.New(scope)
 			if err != nil {
-				return xerrors.Errorf("unable to init connector: %w", err)
+				return xerrors.Errorf("unable to init mcp generator: %w", err)
 			}
-			if err := srv.SetConnector(connector); err != nil {
-				return xerrors.Errorf("unable to set connector: %w", err)
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			connector, guard, err := openDatabase(ctx, gw, scope)
+			if err != nil {
+				return err
 			}
+			defer connector.Close()
+			srv.SetConnector(connector)
+			srv.SetGuard(guard)
+			srv.SetResultBudget(gw.MCP.Result)
 			if rawMode {
 				srv.EnableRawProtocol()
 			}
+			if gw.MCP.Ask != nil {
+				if err := srv.EnableAskTool(*gw.MCP.Ask); err != nil {
+					return xerrors.Errorf("unable to enable ask tool: %w", err)
+				}
+			}
 			if len(gw.Database.Endpoints) > 0 {
 				srv.SetTools(gw.Database.Endpoints)
 			}
+			plugs, err := plugins.Plugins[plugins.MCPToolEnricher](gw.Plugins)
+			if err != nil {
+				return xerrors.Errorf("unable to load plugins: %w", err)
+			}
+			for _, plug := range plugs {
+				plug.EnrichMCP(srv)
+			}
 
-			return srv.ServeStdio().Listen(context.Background(), os.Stdin, os.Stdout)
+			if err := srv.ServeStdio().Listen(ctx, os.Stdin, os.Stdout); err != nil && !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
 		},
 	}
 
@@ -1,14 +1,19 @@
 package cli
 
 import (
+	"context"
 	"fmt"
-	"github.com/centralmind/gateway/connectors"
-	"github.com/centralmind/gateway/plugins"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"path"
 	"strings"
+	"syscall"
+	"time"
+
+	"github.com/centralmind/gateway/plugins"
+	"github.com/centralmind/gateway/server"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -19,6 +24,9 @@ import (
 	"github.com/centralmind/gateway/restgenerator"
 )
 
+// shutdownTimeout bounds the time in-flight requests get to finish after SIGTERM
+const shutdownTimeout = 30 * time.Second
+
 func StartCommand() *cobra.Command {
 	var gatewayParams string
 	var addr string
@@ -90,9 +98,21 @@ Upon successful startup, the terminal will display URLs for both services.`,
 				return xerrors.Errorf("unable to parse config file: %w", err)
 			}
 		}
-		mux := http.NewServeMux()
-		a, err := restgenerator.New(*gw, prefix)
+		ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		// a single connector and connection pool serve REST, Swagger and MCP
+		scope, err := plugins.NewScope(*gw)
+		if err != nil {
+			return xerrors.Errorf("unable to init plugins: %w", err)
+		}
+		connector, guard, err := openDatabase(ctx, gw, scope)
+		if err != nil {
+			return err
+		}
+		defer connector.Close()
 
+		mux := http.NewServeMux()
+		a, err := restgenerator.New(*gw, prefix, connector, guard, scope)
 		if err != nil {
 			return xerrors.Errorf("unable to init api: %w", err)
 		}
@@ -120,21 +140,22 @@ Upon successful startup, the terminal will display URLs for both services.`,
 
 		// Initialize the MCP (Message Communication Protocol) generator
 		// This provides real-time communication capabilities optimized for AI agents
-		srv, err := mcpgenerator.New(gw.Plugins)
+		srv, err := mcpgenerator.New(scope)
 		if err != nil {
 			return xerrors.Errorf("unable to init mcp generator: %w", err)
 		}
-		connector, err := connectors.New(gw.Database.Type, gw.Database.Connection)
-		if err != nil {
-			return xerrors.Errorf("unable to init connector: %w", err)
-		}
-		if err := srv.SetConnector(connector); err != nil {
-			return xerrors.Errorf("unable to set connector: %w", err)
-		}
+		srv.SetConnector(connector)
+		srv.SetGuard(guard)
+		srv.SetResultBudget(gw.MCP.Result)
 		// Enable raw protocol mode for AI agent communication if specified
 		if rawMode {
 			srv.EnableRawProtocol()
 		}
+		if gw.MCP.Ask != nil {
+			if err := srv.EnableAskTool(*gw.MCP.Ask); err != nil {
+				return xerrors.Errorf("unable to enable ask tool: %w", err)
+			}
+		}
 		if len(gw.Database.Endpoints) > 0 {
 			srv.SetTools(gw.Database.Endpoints)
 		}
@@ -143,6 +164,7 @@ Upon successful startup, the terminal will display URLs for both services.`,
 		}
 
 		logrus.Infof("Gateway server started successfully!")
+		var sse *server.SSEServer
 		if enableMCP {
 			plugs, err := plugins.Plugins[plugins.MCPToolEnricher](gw.Plugins)
 			if err != nil {
@@ -151,7 +173,7 @@ Upon successful startup, the terminal will display URLs for both services.`,
 			for _, plug := range plugs {
 				plug.EnrichMCP(srv)
 			}
-			sse := srv.ServeSSE(serverAddresses[0], prefix)
+			sse = srv.ServeSSE(serverAddresses[0], prefix)
 			mux.Handle(path.Join("/", prefix, "sse"), sse)
 			mux.Handle(path.Join("/", prefix, "message"), sse)
 			// Set up SSE (Server-Sent Events) endpoints for real-time event streaming
@@ -166,7 +188,28 @@ Upon successful startup, the terminal will display URLs for both services.`,
 			}
 		}
 
-		return http.ListenAndServe(addr, mux)
+		httpServer := &http.Server{Addr: addr, Handler: mux}
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- httpServer.ListenAndServe()
+		}()
+		select {
+		case err := <-errCh:
+			return err
+		case <-ctx.Done():
+		}
+
+		logrus.Infof("Shutting down gateway server...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if sse != nil {
+			// SSE streams never finish on their own, so sessions are closed before waiting for requests
+			_ = sse.Shutdown(shutdownCtx)
+		}
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			return xerrors.Errorf("unable to shutdown server: %w", err)
+		}
+		return nil
 	}
 
 	RegisterCommand(cmd, Stdio(&gatewayParams))
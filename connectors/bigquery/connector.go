@@ -4,6 +4,7 @@ import (
 	"context"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -12,9 +13,12 @@ import (
 	"cloud.google.com/go/bigquery"
 	"github.com/centralmind/gateway/castx"
 	"github.com/centralmind/gateway/connectors"
+	gw_errors "github.com/centralmind/gateway/errors"
 	"github.com/centralmind/gateway/logger"
 	"github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/xcontext"
 	"golang.org/x/xerrors"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"gopkg.in/yaml.v3"
@@ -276,6 +280,7 @@ func (c *Connector) Query(ctx context.Context, endpoint model.Endpoint, params m
 
 	// Create query with parameters
 	q := c.client.Query(endpoint.Query)
+	q.JobTimeout = endpoint.Timeout
 
 	// Set query parameters
 	for name, value := range processed {
@@ -288,14 +293,33 @@ func (c *Connector) Query(ctx context.Context, endpoint model.Endpoint, params m
 		})
 	}
 
+	// Dry run reports scanned bytes before any are billed
+	details, err := c.dryRun(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.MaxBytesBilled > 0 {
+		if details.TotalBytesProcessed > endpoint.MaxBytesBilled {
+			return nil, fmt.Errorf("%w: query would scan %d bytes, limit is %d, select fewer columns or filter on partitioned columns", gw_errors.ErrTooExpensive, details.TotalBytesProcessed, endpoint.MaxBytesBilled)
+		}
+		// estimates may be off, BigQuery enforces the limit on its side as well
+		q.MaxBytesBilled = endpoint.MaxBytesBilled
+	}
+
 	// Run query
 	it, err := q.Read(ctx)
 	if err != nil {
+		if bytesBilledLimitExceeded(err) {
+			return nil, fmt.Errorf("%w: %v", gw_errors.ErrTooExpensive, err)
+		}
 		return nil, xerrors.Errorf("error executing query: %w", err)
 	}
 
 	var results []map[string]any
 	for {
+		if err := connectors.CheckRowLimit(endpoint, len(results)+1); err != nil {
+			return nil, err
+		}
 		var row map[string]bigquery.Value
 		err := it.Next(&row)
 		if err == iterator.Done {
@@ -344,22 +368,9 @@ func (c *Connector) InferResultColumns(ctx context.Context, query string) ([]mod
 	if !strings.HasPrefix(strings.ToLower(query), "select") {
 		return nil, nil
 	}
-	q := c.client.Query(query)
-	q.DryRun = true
-
-	job, err := q.Run(ctx)
+	details, err := c.dryRun(ctx, c.client.Query(query))
 	if err != nil {
-		return nil, xerrors.Errorf("error in dry run: %w", err)
-	}
-
-	status := job.LastStatus()
-	if status.Statistics == nil || status.Statistics.Details == nil {
-		return nil, xerrors.New("no schema information available")
-	}
-
-	details, ok := status.Statistics.Details.(*bigquery.QueryStatistics)
-	if !ok {
-		return nil, xerrors.New("unexpected statistics type")
+		return nil, err
 	}
 
 	if details.Schema == nil {
@@ -381,6 +392,55 @@ func (c *Connector) InferQuery(ctx context.Context, query string) ([]model.Colum
 	return c.InferResultColumns(ctx, query)
 }
 
+// Explain implements connectors.Explainer, cost is the number of bytes the query would scan
+func (c *Connector) Explain(ctx context.Context, query string) (float64, error) {
+	details, err := c.dryRun(ctx, c.client.Query(query))
+	if err != nil {
+		return 0, err
+	}
+	return float64(details.TotalBytesProcessed), nil
+}
+
+// dryRun validates q without running it and reports the estimated bytes to the context stats
+func (c *Connector) dryRun(ctx context.Context, q *bigquery.Query) (*bigquery.QueryStatistics, error) {
+	dry := *q
+	dry.DryRun = true
+	job, err := dry.Run(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("error in dry run: %w", err)
+	}
+
+	status := job.LastStatus()
+	if status.Statistics == nil || status.Statistics.Details == nil {
+		return nil, xerrors.New("no statistics available for dry run")
+	}
+	details, ok := status.Statistics.Details.(*bigquery.QueryStatistics)
+	if !ok {
+		return nil, xerrors.New("unexpected statistics type")
+	}
+	if stats := xcontext.Stats(ctx); stats != nil {
+		stats.EstimatedBytes = details.TotalBytesProcessed
+	}
+	return details, nil
+}
+
+// bytesBilledLimitExceeded reports whether BigQuery refused a query over maximum_bytes_billed
+func bytesBilledLimitExceeded(err error) bool {
+	var bqErr *bigquery.Error
+	if errors.As(err, &bqErr) {
+		return bqErr.Reason == "bytesBilledLimitExceeded"
+	}
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		for _, item := range apiErr.Errors {
+			if item.Reason == "bytesBilledLimitExceeded" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Close releases any resources held by the connector
 func (c *Connector) Close() error {
 	return c.client.Close()
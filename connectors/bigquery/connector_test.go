@@ -3,8 +3,11 @@ package bigquery
 import (
 	"testing"
 
+	"cloud.google.com/go/bigquery"
 	"github.com/centralmind/gateway/model"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+	"google.golang.org/api/googleapi"
 )
 
 func TestConfig_Type(t *testing.T) {
@@ -41,3 +44,10 @@ func TestConnector_GuessColumnType(t *testing.T) {
 		})
 	}
 }
+
+func TestBytesBilledLimitExceeded(t *testing.T) {
+	assert.True(t, bytesBilledLimitExceeded(xerrors.Errorf("job failed: %w", &bigquery.Error{Reason: "bytesBilledLimitExceeded"})))
+	assert.True(t, bytesBilledLimitExceeded(&googleapi.Error{Errors: []googleapi.ErrorItem{{Reason: "bytesBilledLimitExceeded"}}}))
+	assert.False(t, bytesBilledLimitExceeded(&bigquery.Error{Reason: "invalidQuery"}))
+	assert.False(t, bytesBilledLimitExceeded(xerrors.New("boom")))
+}
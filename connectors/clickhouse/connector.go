@@ -3,8 +3,10 @@ package clickhouse
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
 
@@ -12,6 +14,7 @@ import (
 
 	_ "github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/centralmind/gateway/castx"
+	gw_errors "github.com/centralmind/gateway/errors"
 	"github.com/centralmind/gateway/model"
 	"github.com/jmoiron/sqlx"
 	"golang.org/x/xerrors"
@@ -28,6 +31,7 @@ func init() {
 		if err != nil {
 			return nil, xerrors.Errorf("unable to open ClickHouse db: %w", err)
 		}
+		cfg.Pool.Apply(db.DB)
 		return &Connector{
 			config: *cfg,
 			db:     db,
@@ -44,8 +48,9 @@ type Config struct {
 	Password   string
 	Port       int
 	Secure     bool
-	ConnString string `yaml:"conn_string"` // Direct connection string
-	IsReadonly bool   `yaml:"is_readonly"`
+	ConnString string                `yaml:"conn_string"` // Direct connection string
+	IsReadonly bool                  `yaml:"is_readonly"`
+	Pool       connectors.PoolConfig `yaml:"pool"`
 }
 
 func (c *Config) Readonly() bool {
@@ -224,29 +229,66 @@ func (c Connector) Ping(ctx context.Context) error {
 	return c.db.PingContext(ctx)
 }
 
+func (c Connector) Close() error {
+	return c.db.Close()
+}
+
 func (c Connector) Query(ctx context.Context, endpoint model.Endpoint, params map[string]any) ([]map[string]any, error) {
 	processed, err := castx.ParamsE(endpoint, params)
 	if err != nil {
 		return nil, xerrors.Errorf("unable to process params: %w", err)
 	}
 
-	rows, err := c.db.NamedQuery(endpoint.Query, processed)
+	settings := clickhouse.Settings{}
+	if endpoint.Timeout > 0 {
+		// whole seconds, rounded up so that short timeouts don't turn into "unlimited"
+		settings["max_execution_time"] = int((endpoint.Timeout + time.Second - 1) / time.Second)
+	}
+	if endpoint.MaxRows > 0 {
+		settings["max_result_rows"] = endpoint.MaxRows
+		settings["result_overflow_mode"] = "throw"
+	}
+	if len(settings) > 0 {
+		ctx = clickhouse.Context(ctx, clickhouse.WithSettings(settings))
+	}
+
+	rows, err := sqlx.NamedQueryContext(ctx, c.db, endpoint.Query, processed)
 	if err != nil {
-		return nil, xerrors.Errorf("unable to query db: %w", err)
+		return nil, queryError(err, endpoint)
 	}
 	defer rows.Close()
 
 	res := make([]map[string]any, 0)
 	for rows.Next() {
+		if err := connectors.CheckRowLimit(endpoint, len(res)+1); err != nil {
+			return nil, err
+		}
 		row := map[string]any{}
 		if err := rows.MapScan(row); err != nil {
 			return nil, xerrors.Errorf("unable to scan row: %w", err)
 		}
 		res = append(res, row)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(err, endpoint)
+	}
 	return res, nil
 }
 
+// queryError maps TIMEOUT_EXCEEDED (159) and TOO_MANY_ROWS_OR_BYTES (396) to gateway errors
+func queryError(err error, endpoint model.Endpoint) error {
+	var exception *clickhouse.Exception
+	if errors.As(err, &exception) {
+		switch exception.Code {
+		case 159:
+			return fmt.Errorf("%w: query exceeded %s: %v", gw_errors.ErrTimeout, endpoint.Timeout, err)
+		case 396:
+			return fmt.Errorf("%w: result has more than %d rows, narrow the query down", gw_errors.ErrTooManyRows, endpoint.MaxRows)
+		}
+	}
+	return xerrors.Errorf("unable to query db: %w", err)
+}
+
 func (c Connector) LoadsColumns(ctx context.Context, tableName string) ([]model.ColumnSchema, error) {
 	// Use default database if not specified
 	dbName := c.config.Database
@@ -347,3 +389,22 @@ func (c *Connector) InferResultColumns(ctx context.Context, query string) ([]mod
 func (c *Connector) InferQuery(ctx context.Context, query string) ([]model.ColumnSchema, error) {
 	return c.base.InferResultColumns(ctx, query, c)
 }
+
+// Explain implements connectors.Explainer, cost is the estimated number of rows to read
+func (c *Connector) Explain(ctx context.Context, query string) (float64, error) {
+	rows, err := c.db.QueryxContext(ctx, "EXPLAIN ESTIMATE "+query)
+	if err != nil {
+		return 0, xerrors.Errorf("unable to explain query: %w", err)
+	}
+	defer rows.Close()
+	var total float64
+	for rows.Next() {
+		var database, table string
+		var parts, estimate, marks uint64
+		if err := rows.Scan(&database, &table, &parts, &estimate, &marks); err != nil {
+			return 0, xerrors.Errorf("unable to scan estimate: %w", err)
+		}
+		total += float64(estimate)
+	}
+	return total, rows.Err()
+}
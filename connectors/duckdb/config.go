@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"path/filepath"
 
+	"github.com/centralmind/gateway/connectors"
 	"gopkg.in/yaml.v3"
 )
 
@@ -13,12 +14,13 @@ var docString string
 
 // Config represents the configuration for a DuckDB connection
 type Config struct {
-	Hosts      []string `json:"hosts" yaml:"hosts"`             // List of database file paths
-	Database   string   `json:"database" yaml:"database"`       // Database file name
-	ReadOnly   bool     `json:"read_only" yaml:"read_only"`     // Whether to open database in read-only mode
-	Memory     bool     `json:"memory" yaml:"memory"`           // Whether to create an in-memory database
-	ConnString string   `json:"conn_string" yaml:"conn_string"` // Direct connection string
-	InitSQL    string   `json:"init_sql" yaml:"init_sql"`       // SQL commands to execute on connection initialization
+	Hosts      []string              `json:"hosts" yaml:"hosts"`             // List of database file paths
+	Database   string                `json:"database" yaml:"database"`       // Database file name
+	ReadOnly   bool                  `json:"read_only" yaml:"read_only"`     // Whether to open database in read-only mode
+	Memory     bool                  `json:"memory" yaml:"memory"`           // Whether to create an in-memory database
+	ConnString string                `json:"conn_string" yaml:"conn_string"` // Direct connection string
+	InitSQL    string                `json:"init_sql" yaml:"init_sql"`       // SQL commands to execute on connection initialization
+	Pool       connectors.PoolConfig `json:"pool" yaml:"pool"`               // Connection pool settings
 }
 
 func (c Config) Readonly() bool {
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/centralmind/gateway/connectors"
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/pkg/errors"
 )
@@ -23,6 +24,8 @@ type Config struct {
 	EnableTLS  bool     `yaml:"enableTLS"` // Enable TLS/SSL connection
 	CertFile   string   `yaml:"certFile"`
 	IsReadonly bool     `yaml:"is_readonly"`
+	// Pool limits HTTP connections per node, conn_max_lifetime is not supported
+	Pool connectors.PoolConfig `yaml:"pool"`
 }
 
 func (c Config) Readonly() bool {
@@ -39,6 +42,8 @@ func (c Config) ExtraPrompt() []string {
 		"For Elasticsearch, queries must be written using Mustache syntax.",
 		"Use double curly braces {{param}} for dynamic variables.",
 		"Hierarchical data should use 'nested' fields or parent-child relationships.",
+		"Queries that do not start with '{' are ES|QL queries sent to the _query endpoint, they reference parameters as ?param.",
+		"Endpoints return aggregation buckets as rows when search.aggregation names the aggregation, use 'parent>child' for nested aggregations.",
 		"The final output must contain *only valid single JSON* with no additional commentary, explanations, or markdown formatting!",
 	}
 }
@@ -121,7 +126,10 @@ func (c Config) MakeConfig() (*elasticsearch.Config, error) {
 		Username:  c.Username,
 		Password:  c.Password,
 		Transport: &http.Transport{
-			TLSClientConfig: tlsConfig,
+			TLSClientConfig:     tlsConfig,
+			MaxConnsPerHost:     c.Pool.MaxOpenConns,
+			MaxIdleConnsPerHost: c.Pool.MaxIdleConns,
+			IdleConnTimeout:     c.Pool.ConnMaxIdleTime,
 		},
 	}, nil
 }
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"strings"
 
 	"github.com/centralmind/gateway/castx"
@@ -27,9 +28,11 @@ func init() {
 		if err != nil {
 			return nil, xerrors.Errorf("unable to create Elasticsearch client: %w", err)
 		}
+		transport, _ := config.Transport.(*http.Transport)
 		return &Connector{
-			config: cfg,
-			client: client,
+			config:    cfg,
+			client:    client,
+			transport: transport,
 		}, nil
 	})
 }
@@ -38,8 +41,9 @@ var _ connectors.Connector = (*Connector)(nil)
 
 // Connector implements the connectors.Connector interface for Elasticsearch
 type Connector struct {
-	config Config
-	client *elasticsearch.Client
+	config    Config
+	client    *elasticsearch.Client
+	transport *http.Transport
 }
 
 func (c *Connector) Config() connectors.Config {
@@ -56,12 +60,27 @@ func (c *Connector) Ping(ctx context.Context) error {
 	return nil
 }
 
-// Query executes a search query in Elasticsearch
+// Close drops idle connections, the client itself holds no other resources
+func (c *Connector) Close() error {
+	if c.transport != nil {
+		c.transport.CloseIdleConnections()
+	}
+	return nil
+}
+
+// Query executes a search template, or an ES|QL query when the query is not a JSON template
 func (c *Connector) Query(ctx context.Context, endpoint model.Endpoint, params map[string]any) ([]map[string]any, error) {
 	processed, err := castx.ParamsE(endpoint, params)
 	if err != nil {
 		return nil, xerrors.Errorf("unable to process params: %w", err)
 	}
+	if isESQL(endpoint.Query) {
+		res, err := c.esql(ctx, endpoint.Query, processed)
+		if err != nil {
+			return nil, err
+		}
+		return res.rows(), nil
+	}
 
 	finalQuery := map[string]interface{}{
 		"source": endpoint.Query,
@@ -102,33 +121,46 @@ func (c *Connector) Query(ctx context.Context, endpoint model.Endpoint, params m
 		return nil, xerrors.Errorf("failed to parse Elasticsearch response: %w", err)
 	}
 
-	var hits []interface{}
-	if hitsMap, ok := result["hits"].(map[string]interface{}); ok {
-		if hitsList, ok := hitsMap["hits"].([]interface{}); ok {
-			hits = hitsList
-		} else {
-			return nil, xerrors.Errorf("'hits' key is missing or not a list")
-		}
-	} else {
-		return nil, xerrors.Errorf("'hits' key is missing or not a map")
+	return searchRows(result, endpoint.Search)
+}
+
+// esql runs an ES|QL query through the _query API
+func (c *Connector) esql(ctx context.Context, query string, params map[string]any) (*esqlResponse, error) {
+	request := map[string]any{
+		"query": query,
+	}
+	if len(params) > 0 {
+		request["params"] = esqlParams(params)
 	}
-	// Process the results
-	results := make([]map[string]interface{}, 0)
-	for _, hit := range hits {
-		hitMap, ok := hit.(map[string]interface{})
-		if !ok {
-			continue
-		}
 
-		source, ok := hitMap["_source"].(map[string]interface{})
-		if !ok {
-			continue
-		}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(request); err != nil {
+		return nil, xerrors.Errorf("unable to encode ES|QL query: %w", err)
+	}
 
-		results = append(results, source)
+	res, err := c.client.EsqlQuery(
+		&buf,
+		c.client.EsqlQuery.WithContext(ctx),
+		c.client.EsqlQuery.WithFormat("json"),
+	)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to execute ES|QL query: %w", err)
 	}
+	defer res.Body.Close()
 
-	return results, nil
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read ES|QL response: %w", err)
+	}
+	if res.IsError() {
+		return nil, xerrors.Errorf("Elasticsearch returned an error: %s", body)
+	}
+
+	var result esqlResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, xerrors.Errorf("failed to parse ES|QL response: %w", err)
+	}
+	return &result, nil
 }
 
 // Discovery retrieves available indices in Elasticsearch
@@ -162,6 +194,12 @@ func (c *Connector) Discovery(ctx context.Context, tablesList []string) ([]model
 	if err := json.Unmarshal(body, &indices); err != nil {
 		return nil, xerrors.Errorf("failed to parse indices response: %w", err)
 	}
+
+	mappings, err := c.getMappings(ctx, tablesList)
+	if err != nil {
+		return nil, err
+	}
+
 	// Process only a subset of indices (if necessary)
 	var tables []model.Table
 	for _, index := range indices {
@@ -175,11 +213,7 @@ func (c *Connector) Discovery(ctx context.Context, tablesList []string) ([]model
 			continue
 		}
 
-		// Instead of fetching all mappings, **sample documents** to infer fields
-		columns, err := c.sampleIndexFields(ctx, indexName)
-		if err != nil {
-			return nil, xerrors.Errorf("failed to infer schema for index %s: %w", indexName, err)
-		}
+		columns := c.mappingColumns(mappings[indexName].Mappings.Properties)
 
 		// Get document count efficiently
 		rowCount, err := c.getDocumentCount(ctx, indexName)
@@ -290,6 +324,22 @@ func (c *Connector) Sample(ctx context.Context, table model.Table) ([]map[string
 }
 
 func (c *Connector) InferQuery(ctx context.Context, query string) ([]model.ColumnSchema, error) {
+	if isESQL(query) {
+		// ES|QL reports result columns even when no rows are returned, parameters are bound to null
+		res, err := c.esql(ctx, query+"\n| LIMIT 0", esqlPlaceholders(query))
+		if err != nil {
+			return nil, err
+		}
+		columns := make([]model.ColumnSchema, 0, len(res.Columns))
+		for _, column := range res.Columns {
+			columns = append(columns, model.ColumnSchema{
+				Name: column.Name,
+				Type: c.GuessColumnType(column.Type),
+			})
+		}
+		return columns, nil
+	}
+
 	// Query multiple documents for better inference
 	// Ensure the query is properly formatted JSON
 	var esQuery map[string]interface{}
@@ -338,15 +388,15 @@ func (c *Connector) InferQuery(ctx context.Context, query string) ([]model.Colum
 
 func (c *Connector) GuessColumnType(sqlType string) model.ColumnType {
 	switch strings.ToLower(sqlType) {
-	case "text", "keyword":
+	case "text", "keyword", "match_only_text", "constant_keyword", "wildcard":
 		return model.TypeString
-	case "long", "integer", "short", "byte":
+	case "long", "integer", "short", "byte", "unsigned_long", "counter_long", "counter_integer":
 		return model.TypeInteger
-	case "float", "double", "half_float", "scaled_float":
+	case "float", "double", "half_float", "scaled_float", "counter_double":
 		return model.TypeNumber
 	case "boolean":
 		return model.TypeBoolean
-	case "date":
+	case "date", "date_nanos", "datetime":
 		return model.TypeDatetime
 	case "object", "nested":
 		return model.TypeObject
@@ -357,52 +407,37 @@ func (c *Connector) GuessColumnType(sqlType string) model.ColumnType {
 	}
 }
 
-func (c *Connector) sampleIndexFields(ctx context.Context, indexName string) ([]model.ColumnSchema, error) {
-	N := 100 // Sample up to 100 documents
-	query := map[string]interface{}{
-		"size": N,
-		"query": map[string]interface{}{
-			"match_all": map[string]interface{}{},
-		},
-	}
-
-	// Convert query to JSON
-	queryBytes, err := json.Marshal(query)
-	if err != nil {
-		return nil, xerrors.Errorf("failed to marshal sample query: %w", err)
-	}
+// indexMapping is an entry of the _mapping API response
+type indexMapping struct {
+	Mappings struct {
+		Properties map[string]any `json:"properties"`
+	} `json:"mappings"`
+}
 
-	// Execute search request
-	res, err := c.client.Search(
-		c.client.Search.WithContext(ctx),
-		c.client.Search.WithIndex(indexName),
-		c.client.Search.WithBody(bytes.NewReader(queryBytes)),
+// getMappings fetches mappings of the indices in a single request, all indices when none are given
+func (c *Connector) getMappings(ctx context.Context, indices []string) (map[string]indexMapping, error) {
+	res, err := c.client.Indices.GetMapping(
+		c.client.Indices.GetMapping.WithContext(ctx),
+		c.client.Indices.GetMapping.WithIndex(indices...),
 	)
 	if err != nil {
-		return nil, xerrors.Errorf("failed to execute sample query: %w", err)
+		return nil, xerrors.Errorf("failed to get mappings: %w", err)
 	}
 	defer res.Body.Close()
 
-	// Read response body
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
-		return nil, xerrors.Errorf("failed to read search response: %w", err)
+		return nil, xerrors.Errorf("failed to read mappings response: %w", err)
 	}
-
-	// Parse JSON response
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, xerrors.Errorf("failed to parse search response: %w", err)
+	if res.IsError() {
+		return nil, xerrors.Errorf("Elasticsearch returned an error: %s", body)
 	}
 
-	// Extract search hits (sample documents)
-	hits, ok := result["hits"].(map[string]interface{})["hits"].([]interface{})
-	if !ok {
-		return nil, xerrors.Errorf("unexpected response structure from Elasticsearch")
+	var mappings map[string]indexMapping
+	if err := json.Unmarshal(body, &mappings); err != nil {
+		return nil, xerrors.Errorf("failed to parse mappings response: %w", err)
 	}
-
-	// Use the helper function
-	return c.extractColumnsFromHits(hits)
+	return mappings, nil
 }
 
 func (c *Connector) extractColumnsFromHits(hits []interface{}) ([]model.ColumnSchema, error) {
@@ -148,4 +148,31 @@ func TestElasticsearchConnectorWithAuth(t *testing.T) {
 		}
 		assert.Equal(t, expected, rows[0])
 	})
+
+	t.Run("Query Aggregation Buckets", func(t *testing.T) {
+		endpoint := model.Endpoint{
+			Query:  `{"size": 0, "aggs": {"by_age": {"terms": {"field": "age", "order": {"_key": "asc"}}}}}`,
+			Search: &model.SearchResult{Aggregation: "by_age"},
+		}
+		rows, err := connector.Query(ctx, endpoint, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []map[string]any{
+			{"by_age": float64(28), "doc_count": float64(1)},
+			{"by_age": float64(35), "doc_count": float64(1)},
+		}, rows)
+	})
+
+	t.Run("Query Hit Metadata", func(t *testing.T) {
+		endpoint := model.Endpoint{
+			Query:  `{"query": {"match": {"job": "{{job}}"}}}`,
+			Params: []model.EndpointParams{{Name: "job", Type: "string", Required: true}},
+			Search: &model.SearchResult{Metadata: true},
+		}
+		rows, err := connector.Query(ctx, endpoint, map[string]any{"job": "Designer"})
+		assert.NoError(t, err)
+		require.Len(t, rows, 1)
+		assert.Equal(t, "2", rows[0]["_id"])
+		assert.Equal(t, "test_users", rows[0]["_index"])
+		assert.Equal(t, float64(1), rows[0]["_total"])
+	})
 }
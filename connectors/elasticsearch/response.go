@@ -0,0 +1,269 @@
+package elasticsearch
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/centralmind/gateway/model"
+	"golang.org/x/xerrors"
+)
+
+// isESQL reports whether the query is an ES|QL query rather than a JSON search template
+func isESQL(query string) bool {
+	return !strings.HasPrefix(strings.TrimSpace(query), "{")
+}
+
+// esqlParams converts params into ES|QL named parameters, referenced in the query as ?name
+func esqlParams(params map[string]any) []map[string]any {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	res := make([]map[string]any, 0, len(names))
+	for _, name := range names {
+		res = append(res, map[string]any{name: params[name]})
+	}
+	return res
+}
+
+// esqlPlaceholders binds every named ?param of an ES|QL query to null, so the query can run without values.
+// Strings and comments are skipped.
+func esqlPlaceholders(query string) map[string]any {
+	res := map[string]any{}
+	for i := 0; i < len(query); i++ {
+		switch {
+		case strings.HasPrefix(query[i:], `"""`):
+			end := strings.Index(query[i+3:], `"""`)
+			if end < 0 {
+				return res
+			}
+			i += end + 5
+		case query[i] == '"':
+			for i++; i < len(query) && query[i] != '"'; i++ {
+				if query[i] == '\\' {
+					i++
+				}
+			}
+		case strings.HasPrefix(query[i:], "//"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return res
+			}
+			i += end
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return res
+			}
+			i += end + 3
+		case query[i] == '?':
+			end := i + 1
+			for end < len(query) && (query[end] == '_' || unicode.IsLetter(rune(query[end])) || unicode.IsDigit(rune(query[end]))) {
+				end++
+			}
+			// positional ?1 parameters can't be bound by name
+			if end > i+1 && !unicode.IsDigit(rune(query[i+1])) {
+				res[query[i+1:end]] = nil
+			}
+			i = end - 1
+		}
+	}
+	return res
+}
+
+// esqlResponse is the columnar result of the _query API
+type esqlResponse struct {
+	Columns []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"columns"`
+	Values [][]any `json:"values"`
+}
+
+func (r esqlResponse) rows() []map[string]any {
+	res := make([]map[string]any, 0, len(r.Values))
+	for _, values := range r.Values {
+		row := make(map[string]any, len(r.Columns))
+		for i, column := range r.Columns {
+			if i < len(values) {
+				row[column.Name] = values[i]
+			}
+		}
+		res = append(res, row)
+	}
+	return res
+}
+
+// searchRows turns a search response into rows: hit sources by default,
+// or aggregation buckets when the endpoint asks for them
+func searchRows(result map[string]any, opts *model.SearchResult) ([]map[string]any, error) {
+	if opts != nil && opts.Aggregation != "" {
+		aggs, _ := result["aggregations"].(map[string]any)
+		return aggregationRows(aggs, strings.Split(opts.Aggregation, ">"), map[string]any{})
+	}
+
+	hitsMap, ok := result["hits"].(map[string]any)
+	if !ok {
+		return nil, xerrors.Errorf("'hits' key is missing or not a map")
+	}
+	hits, ok := hitsMap["hits"].([]any)
+	if !ok {
+		return nil, xerrors.Errorf("'hits' key is missing or not a list")
+	}
+	metadata := opts != nil && opts.Metadata
+	var total any
+	if t, ok := hitsMap["total"].(map[string]any); ok {
+		total = t["value"]
+	} else {
+		// rest_total_hits_as_int returns a plain number
+		total = hitsMap["total"]
+	}
+
+	results := make([]map[string]any, 0, len(hits))
+	for _, hit := range hits {
+		hitMap, ok := hit.(map[string]any)
+		if !ok {
+			continue
+		}
+		source, ok := hitMap["_source"].(map[string]any)
+		if !ok {
+			if !metadata {
+				continue
+			}
+			source = map[string]any{}
+		}
+		if metadata {
+			source["_id"] = hitMap["_id"]
+			source["_index"] = hitMap["_index"]
+			source["_score"] = hitMap["_score"]
+			source["_total"] = total
+			if highlight, ok := hitMap["highlight"]; ok {
+				source["_highlight"] = highlight
+			}
+		}
+		results = append(results, source)
+	}
+	return results, nil
+}
+
+// aggregationRows flattens buckets of the aggregation at path into rows. Every row holds
+// the bucket keys of the path under the aggregation names, doc_count and sub-aggregation values.
+func aggregationRows(aggs map[string]any, path []string, parent map[string]any) ([]map[string]any, error) {
+	name := path[0]
+	agg, ok := aggs[name].(map[string]any)
+	if !ok {
+		return nil, xerrors.Errorf("aggregation %q is missing in the response", name)
+	}
+
+	var buckets []map[string]any
+	switch b := agg["buckets"].(type) {
+	case []any:
+		for _, item := range b {
+			if bucket, ok := item.(map[string]any); ok {
+				buckets = append(buckets, bucket)
+			}
+		}
+	case map[string]any:
+		// keyed buckets, e.g. filters aggregation
+		keys := make([]string, 0, len(b))
+		for key := range b {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if bucket, ok := b[key].(map[string]any); ok {
+				bucket["key"] = key
+				buckets = append(buckets, bucket)
+			}
+		}
+	default:
+		if len(path) > 1 {
+			return nil, xerrors.Errorf("aggregation %q has no buckets", name)
+		}
+		// a metric aggregation at the end of the path is a single row
+		row := copyRow(parent)
+		row[name] = metricValue(agg)
+		return []map[string]any{row}, nil
+	}
+
+	var res []map[string]any
+	for _, bucket := range buckets {
+		row := copyRow(parent)
+		row[name] = bucketKey(bucket)
+		if len(path) > 1 {
+			rows, err := aggregationRows(bucket, path[1:], row)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, rows...)
+			continue
+		}
+		for key, value := range bucket {
+			switch key {
+			case "key", "key_as_string":
+			case "doc_count":
+				row[key] = value
+			default:
+				if sub, ok := value.(map[string]any); ok {
+					row[key] = metricValue(sub)
+				}
+			}
+		}
+		res = append(res, row)
+	}
+	return res, nil
+}
+
+// bucketKey prefers the formatted key, e.g. dates of a date_histogram
+func bucketKey(bucket map[string]any) any {
+	if key, ok := bucket["key_as_string"]; ok {
+		return key
+	}
+	return bucket["key"]
+}
+
+// metricValue unwraps single value metrics, multi value metrics such as stats are kept as objects
+func metricValue(agg map[string]any) any {
+	if value, ok := agg["value"]; ok {
+		return value
+	}
+	if hits, ok := agg["hits"].(map[string]any); ok {
+		// top_hits
+		return hits["hits"]
+	}
+	return agg
+}
+
+func copyRow(row map[string]any) map[string]any {
+	res := make(map[string]any, len(row)+2)
+	for key, value := range row {
+		res[key] = value
+	}
+	return res
+}
+
+// mappingColumns builds columns from index mapping properties
+func (c *Connector) mappingColumns(properties map[string]any) []model.ColumnSchema {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	columns := make([]model.ColumnSchema, 0, len(names))
+	for _, name := range names {
+		field, _ := properties[name].(map[string]any)
+		fieldType, _ := field["type"].(string)
+		if fieldType == "" {
+			// fields with sub properties and no type are objects
+			fieldType = "object"
+		}
+		columns = append(columns, model.ColumnSchema{
+			Name: name,
+			Type: c.GuessColumnType(fieldType),
+		})
+	}
+	return columns
+}
@@ -0,0 +1,166 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/centralmind/gateway/model"
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseResponse(t *testing.T, body string) map[string]any {
+	var res map[string]any
+	require.NoError(t, json.Unmarshal([]byte(body), &res))
+	return res
+}
+
+func TestSearchRowsHits(t *testing.T) {
+	result := parseResponse(t, `{"hits": {"total": {"value": 42, "relation": "eq"}, "hits": [
+		{"_index": "users", "_id": "1", "_score": 1.5, "_source": {"name": "Alice"}, "highlight": {"name": ["<em>Alice</em>"]}},
+		{"_index": "users", "_id": "2", "_score": 0.5, "_source": {"name": "Bob"}}
+	]}}`)
+
+	rows, err := searchRows(result, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"name": "Alice"}, {"name": "Bob"}}, rows)
+
+	result = parseResponse(t, `{"hits": {"total": {"value": 42, "relation": "eq"}, "hits": [
+		{"_index": "users", "_id": "1", "_score": 1.5, "_source": {"name": "Alice"}, "highlight": {"name": ["<em>Alice</em>"]}}
+	]}}`)
+	rows, err = searchRows(result, &model.SearchResult{Metadata: true})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{
+		"name":       "Alice",
+		"_id":        "1",
+		"_index":     "users",
+		"_score":     1.5,
+		"_total":     float64(42),
+		"_highlight": map[string]any{"name": []any{"<em>Alice</em>"}},
+	}}, rows)
+
+	_, err = searchRows(map[string]any{}, nil)
+	assert.Error(t, err)
+}
+
+func TestSearchRowsAggregations(t *testing.T) {
+	result := parseResponse(t, `{"hits": {"hits": []}, "aggregations": {
+		"by_city": {"buckets": [
+			{"key": "NY", "doc_count": 2, "avg_age": {"value": 30.5}, "by_job": {"buckets": [
+				{"key": "Engineer", "doc_count": 1, "avg_age": {"value": 28}},
+				{"key": "Designer", "doc_count": 1, "avg_age": {"value": 33}}
+			]}},
+			{"key": "SF", "doc_count": 1, "avg_age": {"value": 35}, "by_job": {"buckets": []}}
+		]},
+		"by_day": {"buckets": [{"key": 1710201600000, "key_as_string": "2024-03-12", "doc_count": 3}]},
+		"statuses": {"buckets": {"active": {"doc_count": 5}, "blocked": {"doc_count": 1}}},
+		"max_age": {"value": 35}
+	}}`)
+
+	rows, err := searchRows(result, &model.SearchResult{Aggregation: "by_city"})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{
+		{"by_city": "NY", "doc_count": float64(2), "avg_age": 30.5, "by_job": map[string]any{"buckets": []any{
+			map[string]any{"key": "Engineer", "doc_count": float64(1), "avg_age": map[string]any{"value": float64(28)}},
+			map[string]any{"key": "Designer", "doc_count": float64(1), "avg_age": map[string]any{"value": float64(33)}},
+		}}},
+		{"by_city": "SF", "doc_count": float64(1), "avg_age": float64(35), "by_job": map[string]any{"buckets": []any{}}},
+	}, rows)
+
+	rows, err = searchRows(result, &model.SearchResult{Aggregation: "by_city>by_job"})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{
+		{"by_city": "NY", "by_job": "Engineer", "doc_count": float64(1), "avg_age": float64(28)},
+		{"by_city": "NY", "by_job": "Designer", "doc_count": float64(1), "avg_age": float64(33)},
+	}, rows)
+
+	rows, err = searchRows(result, &model.SearchResult{Aggregation: "by_day"})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"by_day": "2024-03-12", "doc_count": float64(3)}}, rows)
+
+	rows, err = searchRows(result, &model.SearchResult{Aggregation: "statuses"})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{
+		{"statuses": "active", "doc_count": float64(5)},
+		{"statuses": "blocked", "doc_count": float64(1)},
+	}, rows)
+
+	rows, err = searchRows(result, &model.SearchResult{Aggregation: "max_age"})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"max_age": float64(35)}}, rows)
+
+	_, err = searchRows(result, &model.SearchResult{Aggregation: "missing"})
+	assert.Error(t, err)
+	_, err = searchRows(result, &model.SearchResult{Aggregation: "max_age>by_job"})
+	assert.Error(t, err)
+}
+
+func TestESQL(t *testing.T) {
+	assert.True(t, isESQL("FROM users | WHERE age > ?min_age | LIMIT 10"))
+	assert.False(t, isESQL(` {"query": {"match_all": {}}}`))
+
+	assert.Equal(t, []map[string]any{{"a": 1}, {"b": "x"}}, esqlParams(map[string]any{"b": "x", "a": 1}))
+
+	var res esqlResponse
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"columns": [{"name": "city", "type": "keyword"}, {"name": "count", "type": "long"}],
+		"values": [["NY", 2], ["SF", 1]]
+	}`), &res))
+	assert.Equal(t, []map[string]any{
+		{"city": "NY", "count": float64(2)},
+		{"city": "SF", "count": float64(1)},
+	}, res.rows())
+}
+
+func TestInferESQL(t *testing.T) {
+	assert.Equal(t, map[string]any{"min_age": nil, "city": nil}, esqlPlaceholders(
+		`FROM users // ?comment
+| WHERE age > ?min_age AND city == ?city AND name != "?quoted" AND note != """?raw""" /* ?block */
+| LIMIT 10`))
+	assert.Empty(t, esqlPlaceholders("FROM users | WHERE age > ?1"))
+
+	var request map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"columns": [{"name": "name", "type": "keyword"}, {"name": "age", "type": "long"}], "values": []}`))
+	}))
+	defer server.Close()
+	client, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{server.URL}})
+	require.NoError(t, err)
+	c := &Connector{client: client}
+
+	columns, err := c.InferQuery(context.Background(), "FROM users | WHERE age > ?min_age | KEEP name, age")
+	require.NoError(t, err)
+	assert.Equal(t, []model.ColumnSchema{{Name: "name", Type: model.TypeString}, {Name: "age", Type: model.TypeInteger}}, columns)
+	assert.Equal(t, "FROM users | WHERE age > ?min_age | KEEP name, age\n| LIMIT 0", request["query"])
+	assert.Equal(t, []any{map[string]any{"min_age": nil}}, request["params"])
+}
+
+func TestMappingColumns(t *testing.T) {
+	c := &Connector{}
+	var properties map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"name": {"type": "text", "fields": {"raw": {"type": "keyword"}}},
+		"age": {"type": "integer"},
+		"created_at": {"type": "date"},
+		"address": {"properties": {"city": {"type": "keyword"}}},
+		"orders": {"type": "nested", "properties": {"total": {"type": "double"}}}
+	}`), &properties))
+
+	assert.Equal(t, []model.ColumnSchema{
+		{Name: "address", Type: model.TypeObject},
+		{Name: "age", Type: model.TypeInteger},
+		{Name: "created_at", Type: model.TypeDatetime},
+		{Name: "name", Type: model.TypeString},
+		{Name: "orders", Type: model.TypeObject},
+	}, c.mappingColumns(properties))
+}
@@ -0,0 +1,59 @@
+package files
+
+import (
+	_ "embed"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed readme.md
+var docString string
+
+// defaultReloadInterval is how often files are checked for changes when reload_interval is not set
+const defaultReloadInterval = 5 * time.Second
+
+// Config serves flat files as tables, queried with DuckDB SQL
+type Config struct {
+	Path           string        `yaml:"path"`            // Directory or glob pattern, e.g. ./data or ./data/*.csv
+	Delimiter      string        `yaml:"delimiter"`       // CSV delimiter, detected when empty
+	ReloadInterval time.Duration `yaml:"reload_interval"` // How often files are checked for changes
+}
+
+func (c Config) Readonly() bool {
+	return true
+}
+
+// UnmarshalYAML allows passing just the path as a string
+func (c *Config) UnmarshalYAML(value *yaml.Node) error {
+	var path string
+	if err := value.Decode(&path); err == nil && len(path) > 0 {
+		c.Path = path
+		return nil
+	}
+
+	type configAlias Config // Use alias to avoid infinite recursion
+	var alias configAlias
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+
+	*c = Config(alias)
+	return nil
+}
+
+func (c Config) Type() string {
+	return "files"
+}
+
+func (c Config) Doc() string {
+	return docString
+}
+
+func (c Config) ExtraPrompt() []string {
+	return []string{
+		"Database: DuckDB over flat files, every file is a table named after the file.",
+		"Use symbol ':' instead of '@' for named parameters in sql query",
+		"Query only the listed tables, do not read files with read_csv, read_json or read_parquet.",
+	}
+}
@@ -0,0 +1,282 @@
+package files
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/centralmind/gateway/connectors"
+	"github.com/centralmind/gateway/connectors/duckdb"
+	"github.com/centralmind/gateway/model"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+)
+
+func init() {
+	connectors.Register(func(cfg Config) (connectors.Connector, error) {
+		if cfg.Path == "" {
+			return nil, xerrors.New("files path is required")
+		}
+		if cfg.ReloadInterval <= 0 {
+			cfg.ReloadInterval = defaultReloadInterval
+		}
+		engine, err := connectors.New("duckdb", duckdb.Config{Memory: true})
+		if err != nil {
+			return nil, xerrors.Errorf("unable to start DuckDB engine: %w", err)
+		}
+		c := &Connector{
+			Connector: engine,
+			config:    cfg,
+			files:     map[string]fileState{},
+			stop:      make(chan struct{}),
+			done:      make(chan struct{}),
+		}
+		if err := c.scan(context.Background()); err != nil {
+			_ = engine.Close()
+			return nil, err
+		}
+		if err := c.restrict(context.Background()); err != nil {
+			_ = engine.Close()
+			return nil, err
+		}
+		go c.watch()
+		return c, nil
+	})
+}
+
+var _ connectors.Connector = (*Connector)(nil)
+
+// Connector loads every matching file into a table of an in-memory DuckDB database
+// and reloads tables when files change. Queries, discovery and sampling run on DuckDB.
+type Connector struct {
+	connectors.Connector // DuckDB engine
+	config               Config
+
+	// files are only touched by scan, which runs on start and then from the watch loop
+	files map[string]fileState
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// fileState tells whether a file changed since it was loaded
+type fileState struct {
+	table   string
+	modTime time.Time
+	size    int64
+}
+
+func (c *Connector) Config() connectors.Config {
+	return c.config
+}
+
+// Close stops watching files and closes the engine
+func (c *Connector) Close() error {
+	close(c.stop)
+	<-c.done
+	return c.Connector.Close()
+}
+
+func (c *Connector) watch() {
+	defer close(c.done)
+	ticker := time.NewTicker(c.config.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			if err := c.scan(context.Background()); err != nil {
+				logrus.Warnf("files: unable to reload %s: %v", c.config.Path, err)
+			}
+		}
+	}
+}
+
+// scan loads new and changed files and drops tables of removed ones.
+// A file that fails to load keeps its previous table and is retried on the next scan.
+func (c *Connector) scan(ctx context.Context) error {
+	paths, err := c.list()
+	if err != nil {
+		return err
+	}
+	tables := tableNames(paths)
+
+	var errs []string
+	seen := map[string]bool{}
+	for _, path := range paths {
+		seen[path] = true
+		info, err := os.Stat(path)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		prev, loaded := c.files[path]
+		state := fileState{table: tables[path], modTime: info.ModTime(), size: info.Size()}
+		if loaded && prev == state {
+			continue
+		}
+		if loaded && prev.table != state.table {
+			if err := c.exec(ctx, "DROP TABLE IF EXISTS "+quoteIdent(prev.table)); err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+		}
+		if err := c.exec(ctx, fmt.Sprintf("CREATE OR REPLACE TABLE %s AS SELECT * FROM %s", quoteIdent(state.table), c.reader(path))); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+		c.files[path] = state
+	}
+	for path, state := range c.files {
+		if seen[path] {
+			continue
+		}
+		if err := c.exec(ctx, "DROP TABLE IF EXISTS "+quoteIdent(state.table)); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		delete(c.files, path)
+	}
+	if len(errs) > 0 {
+		return xerrors.Errorf("unable to load files: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// restrict limits file access of DuckDB to the directory of configured files, so queries can't read
+// other files with table functions such as read_text. DuckDB doesn't allow lifting the restriction later.
+func (c *Connector) restrict(ctx context.Context) error {
+	dir, err := filepath.Abs(baseDir(c.config.Path))
+	if err != nil {
+		return xerrors.Errorf("invalid files path %s: %w", c.config.Path, err)
+	}
+	for _, query := range []string{
+		fmt.Sprintf("SET GLOBAL allowed_directories = [%s]", quoteString(strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))),
+		"SET GLOBAL enable_external_access = false",
+	} {
+		if err := c.exec(ctx, query); err != nil {
+			return xerrors.Errorf("unable to restrict file access: %w", err)
+		}
+	}
+	return nil
+}
+
+// baseDir returns the directory of path up to its first glob pattern element
+func baseDir(path string) string {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return path
+	}
+	dir := filepath.Dir(path)
+	for strings.ContainsAny(dir, "*?[") && dir != filepath.Dir(dir) {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
+
+// list returns supported files of the configured directory or glob, sorted for stable table names
+func (c *Connector) list() ([]string, error) {
+	// absolute paths, since DuckDB checks them against the allowed directory
+	pattern, err := filepath.Abs(c.config.Path)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid files path %s: %w", c.config.Path, err)
+	}
+	if info, err := os.Stat(pattern); err == nil && info.IsDir() {
+		pattern = filepath.Join(pattern, "*")
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid files path %s: %w", c.config.Path, err)
+	}
+	var paths []string
+	for _, path := range matches {
+		if _, ok := readers[extension(path)]; !ok {
+			continue
+		}
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// readers are DuckDB table functions reading a file by its extension
+var readers = map[string]string{
+	".csv":     "read_csv_auto(%s%s)",
+	".tsv":     "read_csv_auto(%s, delim = '\t'%s)",
+	".json":    "read_json_auto(%s%s)",
+	".ndjson":  "read_json_auto(%s, format = 'newline_delimited'%s)",
+	".jsonl":   "read_json_auto(%s, format = 'newline_delimited'%s)",
+	".parquet": "read_parquet(%s%s)",
+}
+
+func (c *Connector) reader(path string) string {
+	ext := extension(path)
+	var options string
+	if ext == ".csv" && c.config.Delimiter != "" {
+		options = ", delim = " + quoteString(c.config.Delimiter)
+	}
+	return fmt.Sprintf(readers[ext], quoteString(path), options)
+}
+
+func (c *Connector) exec(ctx context.Context, query string) error {
+	_, err := c.Connector.Query(ctx, model.Endpoint{Query: query}, nil)
+	return err
+}
+
+func extension(path string) string {
+	return strings.ToLower(filepath.Ext(path))
+}
+
+// tableNames names tables after files, files with the same name get their extension appended
+func tableNames(paths []string) map[string]string {
+	names := make(map[string]string, len(paths))
+	count := map[string]int{}
+	for _, path := range paths {
+		count[tableName(path, false)]++
+	}
+	used := map[string]bool{}
+	for _, path := range paths {
+		name := tableName(path, count[tableName(path, false)] > 1)
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%s_%d", tableName(path, true), i)
+		}
+		used[name] = true
+		names[path] = name
+	}
+	return names
+}
+
+func tableName(path string, withExt bool) string {
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	name := strings.TrimSuffix(base, ext)
+	if withExt {
+		name += ext
+	}
+	var res strings.Builder
+	for _, ch := range strings.ToLower(name) {
+		if ch >= 'a' && ch <= 'z' || ch >= '0' && ch <= '9' || ch == '_' {
+			res.WriteRune(ch)
+		} else {
+			res.WriteRune('_')
+		}
+	}
+	if res.Len() == 0 || res.String()[0] >= '0' && res.String()[0] <= '9' {
+		return "t_" + res.String()
+	}
+	return res.String()
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,170 @@
+package files
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/centralmind/gateway/connectors"
+	"github.com/centralmind/gateway/model"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/marcboeker/go-duckdb/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func writeParquet(t *testing.T, path string) {
+	db, err := sqlx.Connect("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec("COPY (SELECT * FROM (VALUES (1, 'north'), (2, 'south')) t(id, region)) TO '" + path + "' (FORMAT parquet)")
+	require.NoError(t, err)
+}
+
+func newConnector(t *testing.T, cfg Config) *Connector {
+	connector, err := connectors.New("files", cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = connector.Close() })
+	return connector.(*Connector)
+}
+
+func tableNamesOf(tables []model.Table) []string {
+	var names []string
+	for _, table := range tables {
+		names = append(names, table.Name)
+	}
+	return names
+}
+
+func TestFilesConnector(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "orders.csv"), "id,customer,total\n1,alice,10.5\n2,bob,20\n3,alice,7\n")
+	writeFile(t, filepath.Join(dir, "customers.json"), `[{"name": "alice", "city": "Paris"}, {"name": "bob", "city": "Oslo"}]`)
+	writeFile(t, filepath.Join(dir, "events.ndjson"), "{\"type\": \"click\"}\n{\"type\": \"view\"}\n")
+	writeFile(t, filepath.Join(dir, "notes.txt"), "not a table")
+	writeParquet(t, filepath.Join(dir, "Regions 2024.parquet"))
+
+	c := newConnector(t, Config{Path: dir})
+	ctx := context.Background()
+	require.NoError(t, c.Ping(ctx))
+
+	tables, err := c.Discovery(ctx, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"orders", "customers", "events", "regions_2024"}, tableNamesOf(tables))
+	for _, table := range tables {
+		if table.Name == "orders" {
+			assert.Equal(t, 3, table.RowCount)
+			assert.Equal(t, []model.ColumnSchema{
+				{Name: "id", Type: model.TypeInteger},
+				{Name: "customer", Type: model.TypeString},
+				{Name: "total", Type: model.TypeNumber},
+			}, table.Columns)
+		}
+	}
+
+	rows, err := c.Query(ctx, model.Endpoint{
+		Query:  "SELECT o.id, c.city FROM orders o JOIN customers c ON c.name = o.customer WHERE o.customer = :customer ORDER BY o.id",
+		Params: []model.EndpointParams{{Name: "customer", Type: "string"}},
+	}, map[string]any{"customer": "alice"})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"id": int64(1), "city": "Paris"}, {"id": int64(3), "city": "Paris"}}, rows)
+
+	sample, err := c.Sample(ctx, model.Table{Name: "regions_2024"})
+	require.NoError(t, err)
+	assert.Len(t, sample, 2)
+
+	columns, err := c.InferQuery(ctx, "SELECT type FROM events")
+	require.NoError(t, err)
+	assert.Equal(t, []model.ColumnSchema{{Name: "type", Type: model.TypeString}}, columns)
+}
+
+func TestFilesReload(t *testing.T) {
+	dir := t.TempDir()
+	orders := filepath.Join(dir, "orders.csv")
+	writeFile(t, orders, "id\n1\n")
+
+	// the watcher is effectively off, scans are triggered by the test
+	c := newConnector(t, Config{Path: filepath.Join(dir, "*"), ReloadInterval: time.Hour})
+	ctx := context.Background()
+	count := func(table string) int {
+		rows, err := c.Query(ctx, model.Endpoint{Query: "SELECT count(*) AS n FROM " + table}, nil)
+		require.NoError(t, err)
+		return int(rows[0]["n"].(int64))
+	}
+	assert.Equal(t, 1, count("orders"))
+
+	writeFile(t, orders, "id\n1\n2\n")
+	// make sure the change is visible even on file systems with coarse timestamps
+	require.NoError(t, os.Chtimes(orders, time.Now(), time.Now().Add(time.Second)))
+	writeFile(t, filepath.Join(dir, "returns.csv"), "id\n7\n")
+	require.NoError(t, c.scan(ctx))
+	assert.Equal(t, 2, count("orders"))
+	assert.Equal(t, 1, count("returns"))
+
+	require.NoError(t, os.Remove(orders))
+	require.NoError(t, c.scan(ctx))
+	tables, err := c.Discovery(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"returns"}, tableNamesOf(tables))
+
+	// a broken file keeps the previous table
+	events := filepath.Join(dir, "events.json")
+	writeFile(t, events, `[{"type": "click"}]`)
+	require.NoError(t, c.scan(ctx))
+	assert.Equal(t, 1, count("events"))
+	writeFile(t, events, `[{"type": `)
+	require.NoError(t, os.Chtimes(events, time.Now(), time.Now().Add(2*time.Second)))
+	assert.Error(t, c.scan(ctx))
+	assert.Equal(t, 1, count("events"))
+}
+
+func TestFilesAccess(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "orders.csv"), "id\n1\n")
+	secret := filepath.Join(t.TempDir(), "secret.csv")
+	writeFile(t, secret, "password\nhunter2\n")
+
+	c := newConnector(t, Config{Path: filepath.Join(dir, "*.csv")})
+	ctx := context.Background()
+	for _, query := range []string{
+		"SELECT * FROM read_csv_auto('" + secret + "')",
+		"SELECT * FROM read_text('/etc/passwd')",
+		"SELECT * FROM read_csv_auto('" + dir + "/../" + filepath.Base(filepath.Dir(secret)) + "/secret.csv')",
+		"SET GLOBAL enable_external_access = true",
+		"SET GLOBAL allowed_directories = ['/']",
+	} {
+		_, err := c.Query(ctx, model.Endpoint{Query: query}, nil)
+		assert.Error(t, err, query)
+	}
+	rows, err := c.Query(ctx, model.Endpoint{Query: "SELECT * FROM read_csv_auto('" + filepath.Join(dir, "orders.csv") + "')"}, nil)
+	require.NoError(t, err)
+	assert.Len(t, rows, 1)
+}
+
+func TestBaseDir(t *testing.T) {
+	dir := t.TempDir()
+	assert.Equal(t, dir, baseDir(dir))
+	assert.Equal(t, "data", baseDir("data/*.csv"))
+	assert.Equal(t, "data", baseDir("data/*/sales_*.csv"))
+}
+
+func TestTableNames(t *testing.T) {
+	assert.Equal(t, map[string]string{
+		"a/orders.csv":      "orders_csv",
+		"a/orders.json":     "orders_json",
+		"b/orders.csv":      "orders_csv_2",
+		"a/2024 Sales.tsv":  "t_2024_sales",
+		"a/customers.jsonl": "customers",
+	}, tableNames([]string{"a/orders.csv", "a/orders.json", "b/orders.csv", "a/2024 Sales.tsv", "a/customers.jsonl"}))
+}
+
+func TestFilesConfigErrors(t *testing.T) {
+	_, err := connectors.New("files", Config{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,82 @@
+package httpapi
+
+import (
+	_ "embed"
+	"time"
+
+	"github.com/centralmind/gateway/connectors"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed readme.md
+var docString string
+
+const (
+	defaultTimeout  = 30 * time.Second
+	defaultMaxPages = 10
+)
+
+// Config fronts JSON APIs, endpoint queries are request templates sent to them
+type Config struct {
+	BaseURL string `yaml:"base_url"` // Relative request URLs are appended to it, requests may not leave its host
+	// Headers are sent with every request, e.g. an API key of the upstream service
+	Headers map[string]string `yaml:"headers"`
+	// ForwardHeaders are copied from the incoming request, e.g. Authorization or X-Request-Id
+	ForwardHeaders []string `yaml:"forward_headers"`
+	// ForwardClaims sends claims of the caller as headers, claim name to header name
+	ForwardClaims map[string]string `yaml:"forward_claims"`
+	// Resources are requests listed as tables in discovery
+	Resources  []Resource    `yaml:"resources"`
+	MaxPages   int           `yaml:"max_pages"` // Pages followed per query at most
+	Timeout    time.Duration `yaml:"timeout"`   // Timeout of a single upstream request
+	IsReadonly bool          `yaml:"is_readonly"`
+	// Pool limits HTTP connections to upstream hosts, conn_max_lifetime is not supported
+	Pool connectors.PoolConfig `yaml:"pool"`
+}
+
+// Resource is a named request, discovery samples it to describe its rows
+type Resource struct {
+	Name    string  `yaml:"name"`
+	Request Request `yaml:"request"`
+}
+
+func (c Config) Readonly() bool {
+	return c.IsReadonly
+}
+
+// UnmarshalYAML allows passing just the base URL as a string
+func (c *Config) UnmarshalYAML(value *yaml.Node) error {
+	var baseURL string
+	if err := value.Decode(&baseURL); err == nil && len(baseURL) > 0 {
+		c.BaseURL = baseURL
+		return nil
+	}
+
+	type configAlias Config // Use alias to avoid infinite recursion
+	var alias configAlias
+	if err := value.Decode(&alias); err != nil {
+		return err
+	}
+
+	*c = Config(alias)
+	return nil
+}
+
+func (c Config) Type() string {
+	return "http"
+}
+
+func (c Config) Doc() string {
+	return docString
+}
+
+func (c Config) ExtraPrompt() []string {
+	return []string{
+		"Database: HTTP JSON API, a query is a request template rather than SQL.",
+		"A query is a JSON object: {\"method\": \"GET\", \"url\": \"/path/{param}?key={param}\", \"headers\": {...}, \"body\": {...}, \"rows\": \"$.data[*]\", \"pagination\": {...}}, all keys except url are optional.",
+		"Use {param_name} placeholders in url, headers and body; a body value that is just \"{param_name}\" keeps the parameter type.",
+		"rows is a JSONPath selecting result rows in the response, e.g. $.items or $.data.results[*].",
+		"pagination follows the next page with {\"next\": \"$.links.next\"}, {\"link\": true} for Link headers, {\"cursor\": \"$.meta.cursor\", \"param\": \"cursor\"} or {\"param\": \"page\", \"page\": 1} for numbered pages.",
+		"Use only resources and paths listed in the schema.",
+	}
+}
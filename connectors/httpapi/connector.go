@@ -0,0 +1,494 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/centralmind/gateway/castx"
+	"github.com/centralmind/gateway/connectors"
+	gw_errors "github.com/centralmind/gateway/errors"
+	"github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/xcontext"
+	"golang.org/x/xerrors"
+)
+
+func init() {
+	connectors.Register(func(cfg Config) (connectors.Connector, error) {
+		var base *url.URL
+		if cfg.BaseURL != "" {
+			var err error
+			base, err = url.Parse(cfg.BaseURL)
+			if err != nil || base.Scheme == "" || base.Host == "" {
+				return nil, xerrors.Errorf("invalid base_url %q, expected an absolute URL", cfg.BaseURL)
+			}
+			// relative request URLs extend the base path instead of replacing its last segment
+			if !strings.HasSuffix(base.Path, "/") {
+				base.Path += "/"
+			}
+		}
+		if cfg.Timeout <= 0 {
+			cfg.Timeout = defaultTimeout
+		}
+		if cfg.MaxPages <= 0 {
+			cfg.MaxPages = defaultMaxPages
+		}
+		for i := range cfg.Resources {
+			if err := cfg.Resources[i].Request.normalize(); err != nil {
+				return nil, xerrors.Errorf("invalid resource %s: %w", cfg.Resources[i].Name, err)
+			}
+		}
+		transport := &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			MaxConnsPerHost:     cfg.Pool.MaxOpenConns,
+			MaxIdleConnsPerHost: cfg.Pool.MaxIdleConns,
+			IdleConnTimeout:     cfg.Pool.ConnMaxIdleTime,
+		}
+		return &Connector{
+			config:    cfg,
+			base:      base,
+			transport: transport,
+			client: &http.Client{
+				Transport:     transport,
+				Timeout:       cfg.Timeout,
+				CheckRedirect: sameHostRedirect,
+			},
+		}, nil
+	})
+}
+
+var _ connectors.Connector = (*Connector)(nil)
+
+// Connector runs endpoint queries as requests to JSON APIs and selects rows from responses
+type Connector struct {
+	config    Config
+	base      *url.URL
+	transport *http.Transport
+	client    *http.Client
+}
+
+func (c *Connector) Config() connectors.Config {
+	return c.config
+}
+
+// Ping checks that the base URL answers, any HTTP status counts as reachable
+func (c *Connector) Ping(ctx context.Context) error {
+	if c.base == nil {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base.String(), nil)
+	if err != nil {
+		return xerrors.Errorf("unable to create ping request: %w", err)
+	}
+	c.setHeaders(ctx, req, nil)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return xerrors.Errorf("unable to reach %s: %w", c.base.Redacted(), err)
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+func (c *Connector) Close() error {
+	c.transport.CloseIdleConnections()
+	return nil
+}
+
+func (c *Connector) Query(ctx context.Context, endpoint model.Endpoint, params map[string]any) ([]map[string]any, error) {
+	processed, err := castx.ParamsE(endpoint, params)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to process params: %w", err)
+	}
+	req, err := parseRequest(endpoint.Query)
+	if err != nil {
+		return nil, err
+	}
+	return c.fetch(ctx, req, c.values(ctx, processed), endpoint, c.config.MaxPages)
+}
+
+// values resolves placeholders to query params, {claims.name} to claims of the caller
+func (c *Connector) values(ctx context.Context, params map[string]any) lookup {
+	return func(name string) (any, bool) {
+		if claim, ok := strings.CutPrefix(name, "claims."); ok {
+			v, ok := xcontext.Claims(ctx)[claim]
+			return v, ok
+		}
+		v, ok := params[name]
+		return v, ok
+	}
+}
+
+// fetch sends the request and follows pages until the last one, maxPages or the row limit
+func (c *Connector) fetch(ctx context.Context, req Request, values lookup, endpoint model.Endpoint, maxPages int) ([]map[string]any, error) {
+	if c.config.IsReadonly && req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return nil, xerrors.Errorf("%s requests are not allowed on a read-only connection", req.Method)
+	}
+	rowsPath, err := parsePath(req.Rows)
+	if err != nil {
+		return nil, err
+	}
+	pagination := Pagination{}
+	if req.Pagination != nil {
+		pagination = *req.Pagination
+	}
+	nextPath, err := parsePath(pagination.Next)
+	if err != nil {
+		return nil, err
+	}
+	cursorPath, err := parsePath(pagination.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered, err := renderURL(req.URL, values)
+	if err != nil {
+		return nil, err
+	}
+	target, err := c.resolve(nil, rendered)
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string]string{}
+	for key, value := range req.Headers {
+		if rendered := renderText(value, values, func(s string) string { return s }); rendered != "" {
+			headers[key] = rendered
+		}
+	}
+
+	var rows []map[string]any
+	for page := 0; page < maxPages; page++ {
+		body, isJSON, err := req.body(values)
+		if err != nil {
+			return nil, err
+		}
+		doc, respHeader, err := c.do(ctx, req.Method, target, headers, body, isJSON)
+		if err != nil {
+			return nil, err
+		}
+		pageRows := selectRows(rowsPath, doc)
+		rows = append(rows, pageRows...)
+		if err := connectors.CheckRowLimit(endpoint, len(rows)); err != nil {
+			return nil, err
+		}
+		if len(pageRows) == 0 {
+			break
+		}
+
+		var next string
+		switch {
+		case pagination.Next != "":
+			next = firstString(nextPath.find(doc))
+		case pagination.Link:
+			next = linkNext(respHeader.Get("Link"))
+		case pagination.Cursor != "":
+			if cursor := firstString(cursorPath.find(doc)); cursor != "" {
+				next = withParam(target, pagination.Param, cursor)
+			}
+		case pagination.Param != "":
+			current := pagination.Page
+			if n, err := strconv.Atoi(target.Query().Get(pagination.Param)); err == nil {
+				current = n
+			}
+			next = withParam(target, pagination.Param, strconv.Itoa(current+1))
+		}
+		if next == "" {
+			break
+		}
+		nextURL, err := c.resolve(target, next)
+		if err != nil {
+			return nil, err
+		}
+		if nextURL.String() == target.String() {
+			break
+		}
+		target = nextURL
+	}
+	return rows, nil
+}
+
+// sameHostRedirect keeps configured and forwarded credentials on the host they are meant for
+func sameHostRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return xerrors.New("stopped after 10 redirects")
+	}
+	if req.URL.Scheme != via[0].URL.Scheme || req.URL.Host != via[0].URL.Host {
+		return xerrors.Errorf("redirect to %s leaves %s", req.URL.Redacted(), via[0].URL.Host)
+	}
+	return nil
+}
+
+// resolve makes ref absolute against the current page or the base URL.
+// Requests carry credentials, so they may not leave the host of the base URL or of the first request.
+func (c *Connector) resolve(current *url.URL, ref string) (*url.URL, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid request url %q: %w", ref, err)
+	}
+	origin := c.base
+	if current != nil {
+		origin = current
+	}
+	if origin != nil {
+		u = origin.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, xerrors.Errorf("request url %q shall be absolute or base_url shall be set", ref)
+	}
+	for _, allowed := range []*url.URL{c.base, current} {
+		if allowed != nil && (u.Scheme != allowed.Scheme || u.Host != allowed.Host) {
+			return nil, xerrors.Errorf("request url %s leaves %s://%s", u.Redacted(), allowed.Scheme, allowed.Host)
+		}
+	}
+	return u, nil
+}
+
+func (c *Connector) do(ctx context.Context, method string, target *url.URL, headers map[string]string, body io.Reader, isJSON bool) (any, http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("unable to create request: %w", err)
+	}
+	if isJSON {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.setHeaders(ctx, req, headers)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("request to %s failed: %w", target.Redacted(), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, nil, statusError(resp)
+	}
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("unable to read response of %s: %w", target.Redacted(), err)
+	}
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil, resp.Header, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, nil, xerrors.Errorf("response of %s is not JSON: %w", target.Redacted(), err)
+	}
+	return convertNumbers(doc), resp.Header, nil
+}
+
+// setHeaders applies configured, forwarded and template headers, later ones win
+func (c *Connector) setHeaders(ctx context.Context, req *http.Request, headers map[string]string) {
+	req.Header.Set("Accept", "application/json")
+	for key, value := range c.config.Headers {
+		req.Header.Set(key, value)
+	}
+	for _, key := range c.config.ForwardHeaders {
+		if value := xcontext.Header(ctx, key); value != "" {
+			req.Header.Set(key, value)
+		}
+	}
+	claims := xcontext.Claims(ctx)
+	for claim, key := range c.config.ForwardClaims {
+		if value, ok := claims[claim]; ok && value != nil {
+			req.Header.Set(key, format(value))
+		}
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+}
+
+// statusError maps upstream failures to gateway errors, so callers get a matching status
+func statusError(resp *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	msg := strings.TrimSpace(string(raw))
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: upstream returned %d: %s", gw_errors.ErrNotAuthorized, resp.StatusCode, msg)
+	case http.StatusTooManyRequests:
+		return fmt.Errorf("%w: upstream returned %d: %s", gw_errors.ErrTooManyRequests, resp.StatusCode, msg)
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return fmt.Errorf("%w: upstream returned %d: %s", gw_errors.ErrTimeout, resp.StatusCode, msg)
+	}
+	return xerrors.Errorf("upstream returned %d: %s", resp.StatusCode, msg)
+}
+
+// selectRows returns objects matched by the path as rows, a matched array contributes its items.
+// Scalars become rows with a single value column.
+func selectRows(path jsonPath, doc any) []map[string]any {
+	if doc == nil {
+		return nil
+	}
+	matches := path.find(doc)
+	if len(matches) == 1 {
+		if items, ok := matches[0].([]any); ok {
+			matches = items
+		}
+	}
+	rows := make([]map[string]any, 0, len(matches))
+	for _, match := range matches {
+		switch v := match.(type) {
+		case map[string]any:
+			rows = append(rows, v)
+		case nil:
+		default:
+			rows = append(rows, map[string]any{"value": v})
+		}
+	}
+	return rows
+}
+
+func firstString(values []any) string {
+	for _, v := range values {
+		if v != nil {
+			return format(v)
+		}
+	}
+	return ""
+}
+
+func withParam(u *url.URL, key, value string) string {
+	q := u.Query()
+	q.Set(key, value)
+	next := *u
+	next.RawQuery = q.Encode()
+	return next.String()
+}
+
+// convertNumbers turns JSON numbers into int64 when they are whole, float64 otherwise
+func convertNumbers(node any) any {
+	switch v := node.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, child := range v {
+			v[key] = convertNumbers(child)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = convertNumbers(child)
+		}
+	}
+	return node
+}
+
+func (c *Connector) Discovery(ctx context.Context, tablesList []string) ([]model.Table, error) {
+	wanted := map[string]bool{}
+	for _, name := range tablesList {
+		wanted[name] = true
+	}
+	var tables []model.Table
+	for _, resource := range c.config.Resources {
+		if len(tablesList) > 0 && !wanted[resource.Name] {
+			continue
+		}
+		rows, err := c.fetch(ctx, resource.Request, c.values(ctx, nil), model.Endpoint{}, 1)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to sample resource %s: %w", resource.Name, err)
+		}
+		var columns []model.ColumnSchema
+		if len(rows) > 0 {
+			columns = c.columns(rows[0])
+		}
+		tables = append(tables, model.Table{
+			Name:     resource.Name,
+			Columns:  columns,
+			RowCount: len(rows),
+		})
+	}
+	return tables, nil
+}
+
+func (c *Connector) Sample(ctx context.Context, table model.Table) ([]map[string]any, error) {
+	for _, resource := range c.config.Resources {
+		if resource.Name != table.Name {
+			continue
+		}
+		rows, err := c.fetch(ctx, resource.Request, c.values(ctx, nil), model.Endpoint{}, 1)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to sample resource %s: %w", resource.Name, err)
+		}
+		if len(rows) > 5 {
+			rows = rows[:5]
+		}
+		return rows, nil
+	}
+	return nil, xerrors.Errorf("resource %s is not configured", table.Name)
+}
+
+// InferQuery sends the first page of a GET request without params, optional query parameters are left out
+func (c *Connector) InferQuery(ctx context.Context, query string) ([]model.ColumnSchema, error) {
+	req, err := parseRequest(query)
+	if err != nil {
+		return nil, err
+	}
+	if req.Method != http.MethodGet {
+		return nil, xerrors.Errorf("unable to infer columns of a %s request without sending it", req.Method)
+	}
+	rows, err := c.fetch(ctx, req, c.values(ctx, nil), model.Endpoint{}, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return c.columns(rows[0]), nil
+}
+
+func (c *Connector) columns(row map[string]any) []model.ColumnSchema {
+	var columns []model.ColumnSchema
+	for _, name := range sortedKeys(row) {
+		columns = append(columns, model.ColumnSchema{
+			Name: name,
+			Type: c.GuessColumnType(jsonType(row[name])),
+		})
+	}
+	return columns
+}
+
+func jsonType(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case int64:
+		return "integer"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	default:
+		return "string"
+	}
+}
+
+func (c *Connector) GuessColumnType(jsonType string) model.ColumnType {
+	switch jsonType {
+	case "integer":
+		return model.TypeInteger
+	case "number":
+		return model.TypeNumber
+	case "boolean":
+		return model.TypeBoolean
+	case "object":
+		return model.TypeObject
+	case "array":
+		return model.TypeArray
+	default:
+		return model.TypeString
+	}
+}
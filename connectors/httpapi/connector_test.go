@@ -0,0 +1,211 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/centralmind/gateway/connectors"
+	gw_errors "github.com/centralmind/gateway/errors"
+	"github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/xcontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fakeAPI(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	write := func(w http.ResponseWriter, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(v))
+	}
+	mux.HandleFunc("GET /api/orders", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		var data []any
+		if page <= 2 {
+			data = []any{
+				map[string]any{"id": page*10 + 1, "status": r.URL.Query().Get("status"), "user": r.Header.Get("X-User-Id")},
+				map[string]any{"id": page*10 + 2, "status": r.URL.Query().Get("status"), "user": r.Header.Get("X-User-Id")},
+			}
+		}
+		write(w, map[string]any{"data": data})
+	})
+	mux.HandleFunc("GET /api/customers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		write(w, map[string]any{"id": r.PathValue("id"), "score": 4.5, "tags": []string{"vip"}, "request": r.Header.Get("X-Request-Id")})
+	})
+	mux.HandleFunc("GET /api/events", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			write(w, map[string]any{"events": []string{"a", "b"}, "next": "c1"})
+		case "c1":
+			write(w, map[string]any{"events": []string{"c"}})
+		}
+	})
+	mux.HandleFunc("GET /api/links", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("p") == "" {
+			w.Header().Set("Link", `</api/links?p=2>; rel="next"`)
+			write(w, []any{map[string]any{"n": 1}})
+			return
+		}
+		write(w, []any{map[string]any{"n": 2}})
+	})
+	mux.HandleFunc("GET /api/next", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("after") {
+		case "":
+			write(w, map[string]any{"items": []any{map[string]any{"n": 1}}, "links": map[string]any{"next": "next?after=1"}})
+		case "1":
+			write(w, map[string]any{"items": []any{map[string]any{"n": 2}}, "links": map[string]any{"next": "https://evil.example/steal"}})
+		}
+	})
+	mux.HandleFunc("POST /api/search", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		write(w, map[string]any{"hits": []any{body}})
+	})
+	mux.HandleFunc("GET /api/busy", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte("slow down"))
+	})
+	mux.HandleFunc("GET /api/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://evil.example/", http.StatusFound)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newConnector(t *testing.T, cfg Config) connectors.Connector {
+	connector, err := connectors.New("http", cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = connector.Close() })
+	return connector
+}
+
+func TestHTTPConnector(t *testing.T) {
+	srv := fakeAPI(t)
+	c := newConnector(t, Config{
+		BaseURL:        srv.URL + "/api",
+		Headers:        map[string]string{"X-Api-Key": "secret"},
+		ForwardHeaders: []string{"X-Request-Id"},
+		ForwardClaims:  map[string]string{"sub": "X-User-Id"},
+		Resources: []Resource{
+			{Name: "orders", Request: Request{URL: "orders", Rows: "$.data"}},
+		},
+	})
+	ctx := xcontext.WithClaims(context.Background(), map[string]any{"sub": "u1", "tenant": "acme"})
+	ctx = xcontext.WithHeader(ctx, map[string][]string{"X-Request-Id": {"r1"}, "Authorization": {"Bearer token"}})
+	require.NoError(t, c.Ping(ctx))
+
+	t.Run("page numbers", func(t *testing.T) {
+		rows, err := c.Query(ctx, model.Endpoint{
+			Query:  `{"url": "orders?status={status}", "rows": "$.data[*]", "pagination": {"param": "page"}}`,
+			Params: []model.EndpointParams{{Name: "status", Type: "string"}},
+		}, map[string]any{"status": "open"})
+		require.NoError(t, err)
+		require.Len(t, rows, 4)
+		assert.Equal(t, map[string]any{"id": int64(11), "status": "open", "user": "u1"}, rows[0])
+		assert.Equal(t, int64(22), rows[3]["id"])
+	})
+
+	t.Run("max pages", func(t *testing.T) {
+		capped := newConnector(t, Config{BaseURL: srv.URL + "/api", Headers: map[string]string{"X-Api-Key": "secret"}, MaxPages: 1})
+		rows, err := capped.Query(ctx, model.Endpoint{Query: `{"url": "orders", "rows": "$.data", "pagination": {"param": "page"}}`}, nil)
+		require.NoError(t, err)
+		assert.Len(t, rows, 2)
+	})
+
+	t.Run("row limit", func(t *testing.T) {
+		_, err := c.Query(ctx, model.Endpoint{
+			Query:       `{"url": "orders", "rows": "$.data", "pagination": {"param": "page"}}`,
+			QueryLimits: model.QueryLimits{MaxRows: 3},
+		}, nil)
+		assert.ErrorIs(t, err, gw_errors.ErrTooManyRows)
+	})
+
+	t.Run("path params and forwarded headers", func(t *testing.T) {
+		rows, err := c.Query(ctx, model.Endpoint{Query: `{"url": "customers/{claims.tenant}"}`}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []map[string]any{{"id": "acme", "score": 4.5, "tags": []any{"vip"}, "request": "r1"}}, rows)
+	})
+
+	t.Run("cursor", func(t *testing.T) {
+		rows, err := c.Query(ctx, model.Endpoint{Query: `{"url": "events", "rows": "$.events", "pagination": {"cursor": "$.next", "param": "cursor"}}`}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []map[string]any{{"value": "a"}, {"value": "b"}, {"value": "c"}}, rows)
+	})
+
+	t.Run("link header", func(t *testing.T) {
+		rows, err := c.Query(ctx, model.Endpoint{Query: `{"url": "links", "pagination": {"link": true}}`}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []map[string]any{{"n": int64(1)}, {"n": int64(2)}}, rows)
+	})
+
+	t.Run("next url stays on the host", func(t *testing.T) {
+		_, err := c.Query(ctx, model.Endpoint{Query: `{"url": "next", "rows": "$.items", "pagination": {"next": "$.links.next"}}`}, nil)
+		assert.ErrorContains(t, err, "evil.example")
+	})
+
+	t.Run("other hosts", func(t *testing.T) {
+		_, err := c.Query(ctx, model.Endpoint{Query: `{"url": "https://evil.example/api"}`}, nil)
+		assert.ErrorContains(t, err, "leaves")
+		_, err = c.Query(ctx, model.Endpoint{Query: `{"url": "redirect"}`}, nil)
+		assert.ErrorContains(t, err, "leaves")
+	})
+
+	t.Run("json body", func(t *testing.T) {
+		rows, err := c.Query(ctx, model.Endpoint{
+			Query:  `{"method": "POST", "url": "search", "body": {"size": "{size}", "user": "{claims.sub}"}, "rows": "$.hits"}`,
+			Params: []model.EndpointParams{{Name: "size", Type: "number"}},
+		}, map[string]any{"size": "5"})
+		require.NoError(t, err)
+		assert.Equal(t, []map[string]any{{"size": int64(5), "user": "u1"}}, rows)
+	})
+
+	t.Run("upstream errors", func(t *testing.T) {
+		_, err := c.Query(ctx, model.Endpoint{Query: `{"url": "busy"}`}, nil)
+		assert.ErrorIs(t, err, gw_errors.ErrTooManyRequests)
+		assert.ErrorContains(t, err, "slow down")
+
+		unauthorized := newConnector(t, Config{BaseURL: srv.URL + "/api"})
+		_, err = unauthorized.Query(ctx, model.Endpoint{Query: `{"url": "orders"}`}, nil)
+		assert.ErrorIs(t, err, gw_errors.ErrNotAuthorized)
+	})
+
+	t.Run("discovery", func(t *testing.T) {
+		tables, err := c.Discovery(ctx, nil)
+		require.NoError(t, err)
+		require.Len(t, tables, 1)
+		assert.Equal(t, "orders", tables[0].Name)
+		assert.Equal(t, []model.ColumnSchema{
+			{Name: "id", Type: model.TypeInteger},
+			{Name: "status", Type: model.TypeString},
+			{Name: "user", Type: model.TypeString},
+		}, tables[0].Columns)
+
+		sample, err := c.Sample(ctx, model.Table{Name: "orders"})
+		require.NoError(t, err)
+		assert.Len(t, sample, 2)
+
+		columns, err := c.InferQuery(ctx, `{"url": "customers/42"}`)
+		require.NoError(t, err)
+		assert.Contains(t, columns, model.ColumnSchema{Name: "score", Type: model.TypeNumber})
+		assert.Contains(t, columns, model.ColumnSchema{Name: "tags", Type: model.TypeArray})
+	})
+}
+
+func TestHTTPReadonly(t *testing.T) {
+	srv := fakeAPI(t)
+	c := newConnector(t, Config{BaseURL: srv.URL + "/api", IsReadonly: true})
+	_, err := c.Query(context.Background(), model.Endpoint{Query: `{"method": "POST", "url": "search", "body": {}}`}, nil)
+	assert.ErrorContains(t, err, "read-only")
+}
@@ -0,0 +1,174 @@
+package httpapi
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// jsonPath is a compiled subset of JSONPath: $, .name, ['name'], [n], [*], .* and ..name
+type jsonPath []pathStep
+
+type pathStep struct {
+	key       string
+	index     int
+	indexed   bool
+	wildcard  bool
+	recursive bool
+}
+
+func parsePath(path string) (jsonPath, error) {
+	path = strings.TrimSpace(path)
+	if path == "" || path == "$" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "$") {
+		return nil, xerrors.Errorf("invalid JSONPath %q: must start with $", path)
+	}
+	var steps jsonPath
+	rest := path[1:]
+	for len(rest) > 0 {
+		var step pathStep
+		var err error
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			step.recursive = true
+			rest = rest[2:]
+			if strings.HasPrefix(rest, "[") {
+				err = parseBracket(&step, &rest, path)
+			} else {
+				err = parseName(&step, &rest, path)
+			}
+		case rest[0] == '.':
+			rest = rest[1:]
+			err = parseName(&step, &rest, path)
+		case rest[0] == '[':
+			err = parseBracket(&step, &rest, path)
+		default:
+			err = xerrors.Errorf("invalid JSONPath %q: unexpected %q", path, rest[0])
+		}
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func parseBracket(step *pathStep, rest *string, path string) error {
+	end := strings.IndexByte(*rest, ']')
+	if end < 0 {
+		return xerrors.Errorf("invalid JSONPath %q: unclosed bracket", path)
+	}
+	selector := strings.TrimSpace((*rest)[1:end])
+	*rest = (*rest)[end+1:]
+	switch {
+	case selector == "*":
+		step.wildcard = true
+	case len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0]:
+		step.key = selector[1 : len(selector)-1]
+	default:
+		index, err := strconv.Atoi(selector)
+		if err != nil {
+			return xerrors.Errorf("invalid JSONPath %q: unsupported selector [%s]", path, selector)
+		}
+		step.index = index
+		step.indexed = true
+	}
+	return nil
+}
+
+func parseName(step *pathStep, rest *string, path string) error {
+	end := strings.IndexAny(*rest, ".[")
+	if end < 0 {
+		end = len(*rest)
+	}
+	name := (*rest)[:end]
+	*rest = (*rest)[end:]
+	if name == "" {
+		return xerrors.Errorf("invalid JSONPath %q: empty name", path)
+	}
+	if name == "*" {
+		step.wildcard = true
+	} else {
+		step.key = name
+	}
+	return nil
+}
+
+// find returns all values matching the path, the document itself for an empty path
+func (p jsonPath) find(doc any) []any {
+	nodes := []any{doc}
+	for _, step := range p {
+		var next []any
+		for _, node := range nodes {
+			if step.recursive {
+				for _, child := range descendants(node) {
+					next = append(next, step.apply(child)...)
+				}
+				continue
+			}
+			next = append(next, step.apply(node)...)
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+func (s pathStep) apply(node any) []any {
+	switch v := node.(type) {
+	case map[string]any:
+		if s.wildcard {
+			res := make([]any, 0, len(v))
+			for _, key := range sortedKeys(v) {
+				res = append(res, v[key])
+			}
+			return res
+		}
+		if child, ok := v[s.key]; ok && !s.indexed {
+			return []any{child}
+		}
+	case []any:
+		if s.wildcard {
+			return v
+		}
+		if s.indexed {
+			index := s.index
+			if index < 0 {
+				index += len(v)
+			}
+			if index >= 0 && index < len(v) {
+				return []any{v[index]}
+			}
+		}
+	}
+	return nil
+}
+
+// descendants returns node and all values nested in it
+func descendants(node any) []any {
+	res := []any{node}
+	switch v := node.(type) {
+	case map[string]any:
+		for _, key := range sortedKeys(v) {
+			res = append(res, descendants(v[key])...)
+		}
+	case []any:
+		for _, child := range v {
+			res = append(res, descendants(child)...)
+		}
+	}
+	return res
+}
+
+// sortedKeys keeps matches of objects in a stable order
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
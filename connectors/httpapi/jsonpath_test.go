@@ -0,0 +1,53 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONPath(t *testing.T) {
+	var doc any
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"data": {"items": [{"id": 1, "tags": ["a"]}, {"id": 2, "tags": ["b", "c"]}]},
+		"meta": {"next cursor": "xyz"}
+	}`), &doc))
+
+	for _, tc := range []struct {
+		path string
+		want []any
+	}{
+		{path: "$", want: []any{doc}},
+		{path: "$.data.items[0].id", want: []any{1.0}},
+		{path: "$.data.items[-1].id", want: []any{2.0}},
+		{path: "$.data.items[*].id", want: []any{1.0, 2.0}},
+		{path: "$['meta']['next cursor']", want: []any{"xyz"}},
+		{path: "$..tags[0]", want: []any{"a", "b"}},
+		{path: "$..id", want: []any{1.0, 2.0}},
+		{path: "$.meta.*", want: []any{"xyz"}},
+		{path: "$.missing.id", want: nil},
+		{path: "$.data.items[5]", want: nil},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			path, err := parsePath(tc.path)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, path.find(doc))
+		})
+	}
+
+	for _, invalid := range []string{"data.items", "$.data[", "$.data[?(@.id)]", "$."} {
+		_, err := parsePath(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestSelectRows(t *testing.T) {
+	items, err := parsePath("$.items")
+	require.NoError(t, err)
+	doc := map[string]any{"items": []any{map[string]any{"id": int64(1)}, "plain", nil}}
+	assert.Equal(t, []map[string]any{{"id": int64(1)}, {"value": "plain"}}, selectRows(items, doc))
+	assert.Equal(t, []map[string]any{doc}, selectRows(nil, doc))
+	assert.Empty(t, selectRows(items, nil))
+}
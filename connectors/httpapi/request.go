@@ -0,0 +1,207 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// Request is the query of an endpoint, a template of the upstream request
+type Request struct {
+	Method     string            `json:"method,omitempty" yaml:"method,omitempty"`
+	URL        string            `json:"url" yaml:"url"`
+	Headers    map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body       any               `json:"body,omitempty" yaml:"body,omitempty"`
+	Rows       string            `json:"rows,omitempty" yaml:"rows,omitempty"` // JSONPath of result rows, the whole response when empty
+	Pagination *Pagination       `json:"pagination,omitempty" yaml:"pagination,omitempty"`
+}
+
+// Pagination tells how to get the next page, at most one way shall be set:
+// a next page URL in the response, a Link header, a cursor or a page number passed as a query parameter.
+type Pagination struct {
+	Next   string `json:"next,omitempty" yaml:"next,omitempty"`     // JSONPath of the next page URL
+	Link   bool   `json:"link,omitempty" yaml:"link,omitempty"`     // follow rel="next" of the Link header
+	Cursor string `json:"cursor,omitempty" yaml:"cursor,omitempty"` // JSONPath of the cursor of the next page
+	Param  string `json:"param,omitempty" yaml:"param,omitempty"`   // query parameter taking the cursor or page number
+	Page   int    `json:"page,omitempty" yaml:"page,omitempty"`     // number of the first page, 1 by default
+}
+
+// placeholder is {name} or {claims.name} in a template
+var placeholder = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_.]*)\}`)
+
+// lookup resolves a placeholder name to its value
+type lookup func(name string) (any, bool)
+
+func parseRequest(query string) (Request, error) {
+	var req Request
+	decoder := json.NewDecoder(strings.NewReader(query))
+	decoder.UseNumber()
+	if err := decoder.Decode(&req); err != nil {
+		return req, xerrors.Errorf("invalid request template, expected JSON object: %w", err)
+	}
+	return req, req.normalize()
+}
+
+// normalize validates the template and fills defaults
+func (r *Request) normalize() error {
+	if r.URL == "" {
+		return xerrors.New("request template has no url")
+	}
+	r.Method = strings.ToUpper(r.Method)
+	if r.Method == "" {
+		r.Method = http.MethodGet
+	}
+	if p := r.Pagination; p != nil {
+		if p.Cursor != "" && p.Param == "" {
+			return xerrors.New("cursor pagination requires param to pass the cursor in")
+		}
+		ways := 0
+		for _, set := range []bool{p.Next != "", p.Link, p.Param != ""} {
+			if set {
+				ways++
+			}
+		}
+		if ways > 1 {
+			return xerrors.New("pagination shall use one of next, link, cursor or page")
+		}
+		if p.Page == 0 {
+			p.Page = 1
+		}
+	}
+	return nil
+}
+
+// renderURL substitutes placeholders, escaping values for their place in the URL.
+// A query parameter which is just an unset placeholder is left out, a list value repeats the parameter.
+// Path segments rendered as . or .. are rejected, since resolving the URL would move to another upstream path.
+func renderURL(tpl string, values lookup) (string, error) {
+	path, query, hasQuery := strings.Cut(tpl, "?")
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if !placeholder.MatchString(segment) {
+			continue
+		}
+		segments[i] = placeholder.ReplaceAllStringFunc(segment, func(m string) string {
+			v, _ := values(m[1 : len(m)-1])
+			return url.PathEscape(format(v))
+		})
+		if segments[i] == "." || segments[i] == ".." {
+			return "", xerrors.Errorf("path segment %s is rendered as %q", segment, segments[i])
+		}
+	}
+	path = strings.Join(segments, "/")
+	if !hasQuery {
+		return path, nil
+	}
+	var parts []string
+	for _, part := range strings.Split(query, "&") {
+		key, value, _ := strings.Cut(part, "=")
+		if m := placeholder.FindStringSubmatch(value); m != nil && m[0] == value {
+			v, ok := values(m[1])
+			if !ok || v == nil || format(v) == "" {
+				continue
+			}
+			if list, ok := v.([]any); ok {
+				for _, item := range list {
+					parts = append(parts, key+"="+url.QueryEscape(format(item)))
+				}
+				continue
+			}
+		}
+		parts = append(parts, key+"="+renderText(value, values, url.QueryEscape))
+	}
+	if len(parts) == 0 {
+		return path, nil
+	}
+	return path + "?" + strings.Join(parts, "&"), nil
+}
+
+// renderText substitutes placeholders of a string
+func renderText(tpl string, values lookup, escape func(string) string) string {
+	return placeholder.ReplaceAllStringFunc(tpl, func(m string) string {
+		v, _ := values(m[1 : len(m)-1])
+		return escape(format(v))
+	})
+}
+
+// renderBody substitutes placeholders of a JSON body, a string that is just a placeholder takes the value as is
+func renderBody(node any, values lookup) any {
+	switch v := node.(type) {
+	case string:
+		if m := placeholder.FindStringSubmatch(v); m != nil && m[0] == v {
+			value, _ := values(m[1])
+			return value
+		}
+		return renderText(v, values, func(s string) string { return s })
+	case map[string]any:
+		res := make(map[string]any, len(v))
+		for key, child := range v {
+			res[key] = renderBody(child, values)
+		}
+		return res
+	case []any:
+		res := make([]any, len(v))
+		for i, child := range v {
+			res[i] = renderBody(child, values)
+		}
+		return res
+	default:
+		return node
+	}
+}
+
+// body encodes the rendered body, a string template is sent as is
+func (r Request) body(values lookup) (io.Reader, bool, error) {
+	switch v := r.Body.(type) {
+	case nil:
+		return nil, false, nil
+	case string:
+		return bytes.NewReader([]byte(renderText(v, values, func(s string) string { return s }))), false, nil
+	default:
+		raw, err := json.Marshal(renderBody(v, values))
+		if err != nil {
+			return nil, false, xerrors.Errorf("unable to encode request body: %w", err)
+		}
+		return bytes.NewReader(raw), true, nil
+	}
+}
+
+func format(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// linkNext returns the rel="next" URL of a Link header
+func linkNext(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, ok := strings.Cut(link, ";")
+		if !ok {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "rel") && strings.Trim(value, `"`) == "next" {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package httpapi
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderURL(t *testing.T) {
+	values := func(params map[string]any) lookup {
+		return func(name string) (any, bool) {
+			v, ok := params[name]
+			return v, ok
+		}
+	}
+	for _, tc := range []struct {
+		name   string
+		tpl    string
+		params map[string]any
+		want   string
+	}{
+		{
+			name:   "path and query values are escaped",
+			tpl:    "/users/{id}/orders?q={q}&limit=10",
+			params: map[string]any{"id": "a/b", "q": "x & y"},
+			want:   "/users/a%2Fb/orders?q=x+%26+y&limit=10",
+		},
+		{
+			name:   "unset query params are left out",
+			tpl:    "/orders?status={status}&from={from}",
+			params: map[string]any{"from": 1.5},
+			want:   "/orders?from=1.5",
+		},
+		{
+			name:   "lists repeat the param",
+			tpl:    "/orders?id={ids}",
+			params: map[string]any{"ids": []any{1, 2}},
+			want:   "/orders?id=1&id=2",
+		},
+		{
+			name:   "placeholders inside values",
+			tpl:    "/search?q=name:{name}",
+			params: map[string]any{"name": "a b"},
+			want:   "/search?q=name:a+b",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := renderURL(tc.tpl, values(tc.params))
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, res)
+		})
+	}
+
+	for _, id := range []string{".", ".."} {
+		_, err := renderURL("users/{id}/orders", values(map[string]any{"id": id}))
+		assert.Error(t, err, id)
+	}
+	res, err := renderURL("users/{id}/orders", values(map[string]any{"id": "..."}))
+	require.NoError(t, err)
+	assert.Equal(t, "users/.../orders", res)
+}
+
+func TestRequestBody(t *testing.T) {
+	req, err := parseRequest(`{"method": "post", "url": "/search", "body": {"query": "{q}", "size": "{size}", "filter": {"text": "name is {q}"}, "fixed": 10}}`)
+	require.NoError(t, err)
+	assert.Equal(t, "POST", req.Method)
+	body, isJSON, err := req.body(func(name string) (any, bool) {
+		return map[string]any{"q": "bob", "size": 5}[name], true
+	})
+	require.NoError(t, err)
+	assert.True(t, isJSON)
+	raw, err := io.ReadAll(body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"query": "bob", "size": 5, "filter": {"text": "name is bob"}, "fixed": 10}`, string(raw))
+}
+
+func TestParseRequestErrors(t *testing.T) {
+	for _, query := range []string{
+		`SELECT 1`,
+		`{"method": "GET"}`,
+		`{"url": "/a", "pagination": {"cursor": "$.next"}}`,
+		`{"url": "/a", "pagination": {"next": "$.next", "link": true}}`,
+	} {
+		_, err := parseRequest(query)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestLinkNext(t *testing.T) {
+	assert.Equal(t, "https://api/x?page=3", linkNext(`<https://api/x?page=1>; rel="prev", <https://api/x?page=3>; rel="next"`))
+	assert.Equal(t, "", linkNext(`<https://api/x?page=1>; rel="prev"`))
+}
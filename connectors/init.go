@@ -22,6 +22,14 @@ type Connector interface {
 	Sample(ctx context.Context, table model.Table) ([]map[string]any, error)
 	InferQuery(ctx context.Context, query string) ([]model.ColumnSchema, error)
 	Config() Config
+	// Close releases the connection pool, wrappers release their own resources and close the inner connector
+	Close() error
+}
+
+// Explainer is implemented by connectors that can estimate query cost without running it.
+// Cost units are database specific: planner cost, estimated rows or scanned bytes.
+type Explainer interface {
+	Explain(ctx context.Context, query string) (float64, error)
 }
 
 var interceptors = map[string]func(any) (Connector, error){}
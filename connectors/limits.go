@@ -0,0 +1,96 @@
+package connectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	gw_errors "github.com/centralmind/gateway/errors"
+	"github.com/centralmind/gateway/model"
+)
+
+// WithLimits enforces endpoint timeouts, row limits and concurrency caps around connector.
+// Endpoint limits take precedence over defaults. Connectors apply timeouts and row limits
+// natively where the engine supports it, the wrapper passes merged limits to them.
+func WithLimits(connector Connector, defaults model.QueryLimits) Connector {
+	l := &limited{
+		Connector: connector,
+		defaults:  defaults,
+		slots:     map[string]chan struct{}{},
+	}
+	if explainer, ok := connector.(Explainer); ok {
+		return &limitedExplainer{limited: l, explainer: explainer}
+	}
+	return l
+}
+
+type limited struct {
+	Connector
+	defaults model.QueryLimits
+
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+func (l *limited) Query(ctx context.Context, endpoint model.Endpoint, params map[string]any) ([]map[string]any, error) {
+	endpoint.QueryLimits = endpoint.QueryLimits.Merge(l.defaults)
+	if endpoint.MaxConcurrency > 0 {
+		slot := l.slot(endpoint)
+		select {
+		case slot <- struct{}{}:
+			defer func() { <-slot }()
+		default:
+			return nil, fmt.Errorf("%w: endpoint runs at most %d queries at a time, retry later", gw_errors.ErrTooManyRequests, endpoint.MaxConcurrency)
+		}
+	}
+	if endpoint.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, endpoint.Timeout)
+		defer cancel()
+	}
+	res, err := l.Connector.Query(ctx, endpoint, params)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !errors.Is(err, gw_errors.ErrTimeout) {
+			return nil, fmt.Errorf("%w: query exceeded %s: %v", gw_errors.ErrTimeout, endpoint.Timeout, err)
+		}
+		return nil, err
+	}
+	// connectors without native support still read everything, the limit is enforced here
+	if err := CheckRowLimit(endpoint, len(res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// slot returns the semaphore shared by all calls of the endpoint, raw queries share a single one
+func (l *limited) slot(endpoint model.Endpoint) chan struct{} {
+	key := endpoint.HTTPMethod + " " + endpoint.HTTPPath + " " + endpoint.MCPMethod
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	slot, ok := l.slots[key]
+	if !ok {
+		slot = make(chan struct{}, endpoint.MaxConcurrency)
+		l.slots[key] = slot
+	}
+	return slot
+}
+
+// limitedExplainer keeps cost estimation available on connectors that support it
+type limitedExplainer struct {
+	*limited
+	explainer Explainer
+}
+
+func (l *limitedExplainer) Explain(ctx context.Context, query string) (float64, error) {
+	return l.explainer.Explain(ctx, query)
+}
+
+// CheckRowLimit returns ErrTooManyRows once a result has more rows than the endpoint allows,
+// connectors call it while reading rows to stop early.
+func CheckRowLimit(endpoint model.Endpoint, rows int) error {
+	if endpoint.MaxRows > 0 && rows > endpoint.MaxRows {
+		return fmt.Errorf("%w: result has more than %d rows, narrow the query down", gw_errors.ErrTooManyRows, endpoint.MaxRows)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package connectors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	gw_errors "github.com/centralmind/gateway/errors"
+	"github.com/centralmind/gateway/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type slowConnector struct {
+	Connector
+	rows    int
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c *slowConnector) Query(ctx context.Context, endpoint model.Endpoint, params map[string]any) ([]map[string]any, error) {
+	if c.started != nil {
+		c.started <- struct{}{}
+	}
+	select {
+	case <-c.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return make([]map[string]any, c.rows), nil
+}
+
+func TestLimitsTimeout(t *testing.T) {
+	c := WithLimits(&slowConnector{release: make(chan struct{})}, model.QueryLimits{Timeout: 10 * time.Millisecond})
+	_, err := c.Query(context.Background(), model.Endpoint{}, nil)
+	assert.ErrorIs(t, err, gw_errors.ErrTimeout)
+}
+
+func TestLimitsRows(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	c := WithLimits(&slowConnector{rows: 3, release: release}, model.QueryLimits{MaxRows: 5})
+
+	res, err := c.Query(context.Background(), model.Endpoint{}, nil)
+	require.NoError(t, err)
+	assert.Len(t, res, 3)
+
+	_, err = c.Query(context.Background(), model.Endpoint{QueryLimits: model.QueryLimits{MaxRows: 2}}, nil)
+	assert.ErrorIs(t, err, gw_errors.ErrTooManyRows)
+}
+
+func TestLimitsConcurrency(t *testing.T) {
+	inner := &slowConnector{started: make(chan struct{}, 1), release: make(chan struct{})}
+	c := WithLimits(inner, model.QueryLimits{})
+	endpoint := model.Endpoint{HTTPPath: "/orders", QueryLimits: model.QueryLimits{MaxConcurrency: 1}}
+
+	done := make(chan error)
+	go func() {
+		_, err := c.Query(context.Background(), endpoint, nil)
+		done <- err
+	}()
+	<-inner.started
+
+	_, err := c.Query(context.Background(), endpoint, nil)
+	assert.ErrorIs(t, err, gw_errors.ErrTooManyRequests)
+
+	// other endpoints have their own slots
+	go func() { <-inner.started }()
+	close(inner.release)
+	_, err = c.Query(context.Background(), model.Endpoint{HTTPPath: "/users", QueryLimits: model.QueryLimits{MaxConcurrency: 1}}, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, <-done)
+}
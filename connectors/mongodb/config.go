@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/centralmind/gateway/connectors"
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"gopkg.in/yaml.v3"
@@ -23,6 +24,8 @@ type Config struct {
 	Password   string   `yaml:"password"`
 	IsReadonly bool     `yaml:"is_readonly"`
 	ConnString string   `yaml:"conn_string"`
+	// Pool uses max_open_conns and conn_max_idle_time, the driver manages idle connections itself
+	Pool connectors.PoolConfig `yaml:"pool"`
 }
 
 func (c Config) Readonly() bool {
@@ -33,7 +36,11 @@ func (c Config) ExtraPrompt() []string {
 	return []string{
 		"Database: MongoDB (NoSQL database).",
 		"Queries must use MongoDB Query Language.",
-		"Paginate with 'skip' and 'limit' instead of 'offset' and 'limit'.",
+		"A query is a JSON object: {\"collection\": \"name\", \"filter\": {...}, \"projection\": {...}, \"sort\": {...}, \"skip\": n, \"limit\": n}, all keys except collection are optional.",
+		"For grouping, joins or computed fields add \"pipeline\": [stages] with aggregation stages such as $match, $group, $lookup, $unwind, $project, $addFields; filter runs before the pipeline, sort, skip, limit and projection after it.",
+		"Do not use $out or $merge stages.",
+		"Use \"@param_name\" string values as parameter placeholders anywhere in filter or pipeline, e.g. {\"age\": {\"$gt\": \"@min_age\"}}.",
+		"Paginate with 'skip' and 'limit' instead of 'offset' and 'limit', e.g. \"skip\": \"@offset\", \"limit\": \"@limit\".",
 	}
 }
 
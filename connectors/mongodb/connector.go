@@ -2,12 +2,13 @@ package mongodb
 
 import (
 	"context"
-	"encoding/json"
 	"time"
 
 	"github.com/centralmind/gateway/castx"
 	"github.com/centralmind/gateway/connectors"
 	"github.com/centralmind/gateway/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/xerrors"
@@ -17,6 +18,12 @@ func init() {
 	connectors.Register(func(cfg Config) (connectors.Connector, error) {
 		// Create MongoDB client options
 		clientOptions := options.Client().ApplyURI(cfg.ConnectionString())
+		if cfg.Pool.MaxOpenConns > 0 {
+			clientOptions.SetMaxPoolSize(uint64(cfg.Pool.MaxOpenConns))
+		}
+		if cfg.Pool.ConnMaxIdleTime > 0 {
+			clientOptions.SetMaxConnIdleTime(cfg.Pool.ConnMaxIdleTime)
+		}
 
 		// Create MongoDB client
 		client, err := mongo.Connect(context.Background(), clientOptions)
@@ -54,35 +61,44 @@ func (c Connector) Ping(ctx context.Context) error {
 	return nil
 }
 
-func (c *Connector) Query(ctx context.Context, endpoint model.Endpoint, params map[string]any) ([]map[string]any, error) {
-	// Get the database
-	db := c.client.Database(c.config.Database)
-
-	// Parse the MongoDB query to get collection name and filter
-	var query struct {
-		Collection string      `json:"collection"`
-		Filter     interface{} `json:"filter"`
-	}
-	if err := json.Unmarshal([]byte(endpoint.Query), &query); err != nil {
-		return nil, xerrors.Errorf("invalid MongoDB query format: %w", err)
-	}
-
-	// Get collection
-	collection := db.Collection(query.Collection)
+func (c Connector) Close() error {
+	return c.client.Disconnect(context.Background())
+}
 
+func (c *Connector) Query(ctx context.Context, endpoint model.Endpoint, params map[string]any) ([]map[string]any, error) {
 	// Process parameters
 	processed, err := castx.ParamsE(endpoint, params)
 	if err != nil {
 		return nil, xerrors.Errorf("unable to process params: %w", err)
 	}
-
-	// Replace parameters in the filter
-	filter := replaceParams(query.Filter, processed)
-
-	// Execute the query
-	cursor, err := collection.Find(ctx, filter)
+	q, err := parseQuery(endpoint.Query, processed)
 	if err != nil {
-		return nil, xerrors.Errorf("unable to execute query: %w", err)
+		return nil, err
+	}
+	collection := c.client.Database(c.config.Database).Collection(q.Collection)
+
+	var cursor *mongo.Cursor
+	if len(q.Pipeline) > 0 {
+		if c.config.IsReadonly && q.writes() {
+			return nil, xerrors.New("$out and $merge stages are not allowed on a read-only connection")
+		}
+		stages, err := q.stages(endpoint.MaxRows)
+		if err != nil {
+			return nil, xerrors.Errorf("invalid pipeline: %w", err)
+		}
+		cursor, err = collection.Aggregate(ctx, stages, options.Aggregate().SetMaxTime(endpoint.Timeout))
+		if err != nil {
+			return nil, xerrors.Errorf("unable to execute pipeline: %w", err)
+		}
+	} else {
+		filter := q.Filter
+		if filter == nil {
+			filter = bson.D{}
+		}
+		cursor, err = collection.Find(ctx, filter, q.findOptions(endpoint.MaxRows).SetMaxTime(endpoint.Timeout))
+		if err != nil {
+			return nil, xerrors.Errorf("unable to execute query: %w", err)
+		}
 	}
 	defer cursor.Close(ctx)
 
@@ -91,31 +107,13 @@ func (c *Connector) Query(ctx context.Context, endpoint model.Endpoint, params m
 	if err := cursor.All(ctx, &results); err != nil {
 		return nil, xerrors.Errorf("unable to decode results: %w", err)
 	}
+	if err := connectors.CheckRowLimit(endpoint, len(results)); err != nil {
+		return nil, err
+	}
 
 	return results, nil
 }
 
-// replaceParams replaces parameter placeholders in the MongoDB query with actual values
-func replaceParams(filter interface{}, params map[string]any) interface{} {
-	switch v := filter.(type) {
-	case map[string]interface{}:
-		for key, value := range v {
-			if _, ok := value.(string); ok {
-				if paramValue, exists := params[key]; exists {
-					v[key] = paramValue
-				}
-			} else {
-				v[key] = replaceParams(value, params)
-			}
-		}
-	case []interface{}:
-		for i, value := range v {
-			v[i] = replaceParams(value, params)
-		}
-	}
-	return filter
-}
-
 func (c *Connector) Discovery(ctx context.Context, tablesList []string) ([]model.Table, error) {
 	// Get the database
 	db := c.client.Database(c.config.Database)
@@ -190,41 +188,44 @@ func getMongoType(value interface{}) string {
 		return "int"
 	case bool:
 		return "bool"
-	case time.Time:
+	case time.Time, primitive.DateTime, primitive.Timestamp:
 		return "date"
-	case map[string]interface{}:
+	case map[string]interface{}, bson.D, bson.M:
 		return "object"
-	case []interface{}:
+	case []interface{}, bson.A:
 		return "array"
 	default:
 		return "string"
 	}
 }
 
-func (c *Connector) InferQuery(ctx context.Context, query string) ([]model.ColumnSchema, error) {
-	// Parse the MongoDB query to get collection name
-	var queryStruct struct {
-		Collection string      `json:"collection"`
-		Filter     interface{} `json:"filter"`
-	}
-	if err := json.Unmarshal([]byte(query), &queryStruct); err != nil {
-		return nil, xerrors.Errorf("invalid MongoDB query format: %w", err)
-	}
-
-	// Get the database and collection
-	db := c.client.Database(c.config.Database)
-	collection := db.Collection(queryStruct.Collection)
+// inferSampleSize bounds documents a pipeline reads while inferring its result schema
+const inferSampleSize = 100
 
-	// Get a sample document from the collection
-	var sampleDoc map[string]interface{}
-	err := collection.FindOne(ctx, queryStruct.Filter).Decode(&sampleDoc)
-	if err != nil && err != mongo.ErrNoDocuments {
-		return nil, xerrors.Errorf("unable to get sample document: %w", err)
+func (c *Connector) InferQuery(ctx context.Context, query string) ([]model.ColumnSchema, error) {
+	q, err := parseQuery(query, nil)
+	if err != nil {
+		return nil, err
 	}
+	collection := c.client.Database(c.config.Database).Collection(q.Collection)
 
-	// If no document found, try to get any document from the collection
-	if err == mongo.ErrNoDocuments {
-		err = collection.FindOne(ctx, map[string]interface{}{}).Decode(&sampleDoc)
+	var sampleDoc bson.D
+	if len(q.Pipeline) > 0 {
+		sampleDoc, err = c.samplePipeline(ctx, collection, q)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		findOptions := options.FindOne()
+		if len(q.Projection) > 0 {
+			findOptions.SetProjection(q.Projection)
+		}
+		// without parameter values the filter would match nothing
+		filter := q.Filter
+		if filter == nil || hasPlaceholders(filter) {
+			filter = bson.D{}
+		}
+		err = collection.FindOne(ctx, filter, findOptions).Decode(&sampleDoc)
 		if err != nil && err != mongo.ErrNoDocuments {
 			return nil, xerrors.Errorf("unable to get sample document: %w", err)
 		}
@@ -232,18 +233,50 @@ func (c *Connector) InferQuery(ctx context.Context, query string) ([]model.Colum
 
 	// Create column schemas from the sample document
 	var columns []model.ColumnSchema
-	if sampleDoc != nil {
-		for fieldName, value := range sampleDoc {
-			columns = append(columns, model.ColumnSchema{
-				Name: fieldName,
-				Type: c.GuessColumnType(getMongoType(value)),
-			})
-		}
+	for _, field := range sampleDoc {
+		columns = append(columns, model.ColumnSchema{
+			Name: field.Key,
+			Type: c.GuessColumnType(getMongoType(field.Value)),
+		})
 	}
 
 	return columns, nil
 }
 
+// samplePipeline runs a pipeline on a sample of the collection and returns its first document.
+// Stages with unresolved parameters would match nothing without values, so they are skipped,
+// as well as stages that write.
+func (c *Connector) samplePipeline(ctx context.Context, collection *mongo.Collection, q *query) (bson.D, error) {
+	var stages []bson.D
+	if len(q.Filter) > 0 && !hasPlaceholders(q.Filter) {
+		stages = append(stages, bson.D{{Key: "$match", Value: q.Filter}})
+	}
+	stages = append(stages, bson.D{{Key: "$limit", Value: inferSampleSize}})
+	for _, stage := range q.Pipeline {
+		if len(stage) != 1 || writeStages[stage[0].Key] || hasPlaceholders(stage) {
+			continue
+		}
+		stages = append(stages, stage)
+	}
+	if len(q.Projection) > 0 {
+		stages = append(stages, bson.D{{Key: "$project", Value: q.Projection}})
+	}
+	stages = append(stages, bson.D{{Key: "$limit", Value: 1}})
+
+	cursor, err := collection.Aggregate(ctx, stages)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to run pipeline on a sample: %w", err)
+	}
+	defer cursor.Close(ctx)
+	var sampleDoc bson.D
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&sampleDoc); err != nil {
+			return nil, xerrors.Errorf("unable to decode sample document: %w", err)
+		}
+	}
+	return sampleDoc, cursor.Err()
+}
+
 func (c *Connector) GuessColumnType(mongoType string) model.ColumnType {
 	switch mongoType {
 	case "string":
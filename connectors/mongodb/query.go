@@ -0,0 +1,195 @@
+package mongodb
+
+import (
+	"strings"
+
+	"github.com/spf13/cast"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/xerrors"
+)
+
+// query is an endpoint query: a find with filter, projection, sort and pagination,
+// or an aggregation pipeline when pipeline is set
+type query struct {
+	Collection string   `bson:"collection"`
+	Filter     bson.D   `bson:"filter,omitempty"`
+	Pipeline   []bson.D `bson:"pipeline,omitempty"`
+	Projection bson.D   `bson:"projection,omitempty"`
+	Sort       bson.D   `bson:"sort,omitempty"`
+	Limit      any      `bson:"limit,omitempty"`
+	Skip       any      `bson:"skip,omitempty"`
+
+	limit int64
+	skip  int64
+}
+
+// writeStages store aggregation results and are rejected on read-only connections
+var writeStages = map[string]bool{
+	"$out":   true,
+	"$merge": true,
+}
+
+// parseQuery reads an extended JSON query and substitutes "@param" placeholders with params.
+// Key order is preserved, so sort and pipeline stages behave as written.
+func parseQuery(raw string, params map[string]any) (*query, error) {
+	var doc bson.D
+	if err := bson.UnmarshalExtJSON([]byte(raw), false, &doc); err != nil {
+		return nil, xerrors.Errorf("invalid MongoDB query format: %w", err)
+	}
+	doc = replaceParams(doc, params).(bson.D)
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to encode query: %w", err)
+	}
+	var q query
+	if err := bson.Unmarshal(data, &q); err != nil {
+		return nil, xerrors.Errorf("invalid MongoDB query format: %w", err)
+	}
+	if q.Collection == "" {
+		return nil, xerrors.New("invalid MongoDB query format: collection is required")
+	}
+	// limit and skip may come from string params
+	if q.limit, err = toCount(q.Limit); err != nil {
+		return nil, xerrors.Errorf("invalid limit: %w", err)
+	}
+	if q.skip, err = toCount(q.Skip); err != nil {
+		return nil, xerrors.Errorf("invalid skip: %w", err)
+	}
+	return &q, nil
+}
+
+func toCount(value any) (int64, error) {
+	if value == nil {
+		return 0, nil
+	}
+	res, err := cast.ToInt64E(value)
+	if err != nil {
+		return 0, err
+	}
+	if res < 0 {
+		return 0, xerrors.Errorf("%d is negative", res)
+	}
+	return res, nil
+}
+
+// replaceParams substitutes string values of the form "@name" with params anywhere in the query
+func replaceParams(value any, params map[string]any) any {
+	switch v := value.(type) {
+	case bson.D:
+		for i := range v {
+			v[i].Value = replaceParams(v[i].Value, params)
+		}
+	case bson.M:
+		for key, item := range v {
+			v[key] = replaceParams(item, params)
+		}
+	case bson.A:
+		for i, item := range v {
+			v[i] = replaceParams(item, params)
+		}
+	case map[string]any:
+		for key, item := range v {
+			v[key] = replaceParams(item, params)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = replaceParams(item, params)
+		}
+	case string:
+		if name, ok := strings.CutPrefix(v, "@"); ok {
+			if param, exists := params[name]; exists {
+				return param
+			}
+		}
+	}
+	return value
+}
+
+// hasPlaceholders reports whether value still contains "@name" strings
+func hasPlaceholders(value any) bool {
+	switch v := value.(type) {
+	case bson.D:
+		for _, e := range v {
+			if hasPlaceholders(e.Value) {
+				return true
+			}
+		}
+	case bson.A:
+		for _, item := range v {
+			if hasPlaceholders(item) {
+				return true
+			}
+		}
+	case string:
+		return strings.HasPrefix(v, "@")
+	}
+	return false
+}
+
+// stages builds the aggregation pipeline: filter first, then the query pipeline, sort, pagination and projection.
+// maxRows caps the result, one extra document tells that the result is over the limit.
+func (q *query) stages(maxRows int) ([]bson.D, error) {
+	var res []bson.D
+	if len(q.Filter) > 0 {
+		res = append(res, bson.D{{Key: "$match", Value: q.Filter}})
+	}
+	for _, stage := range q.Pipeline {
+		if len(stage) != 1 {
+			return nil, xerrors.Errorf("pipeline stage must have exactly one operator, got %d", len(stage))
+		}
+		res = append(res, stage)
+	}
+	if len(q.Sort) > 0 {
+		res = append(res, bson.D{{Key: "$sort", Value: q.Sort}})
+	}
+	if q.skip > 0 {
+		res = append(res, bson.D{{Key: "$skip", Value: q.skip}})
+	}
+	if limit := q.cap(maxRows); limit > 0 {
+		res = append(res, bson.D{{Key: "$limit", Value: limit}})
+	}
+	if len(q.Projection) > 0 {
+		res = append(res, bson.D{{Key: "$project", Value: q.Projection}})
+	}
+	return res, nil
+}
+
+// findOptions applies projection, sort and pagination to a find
+func (q *query) findOptions(maxRows int) *options.FindOptions {
+	opts := options.Find()
+	if len(q.Projection) > 0 {
+		opts.SetProjection(q.Projection)
+	}
+	if len(q.Sort) > 0 {
+		opts.SetSort(q.Sort)
+	}
+	if q.skip > 0 {
+		opts.SetSkip(q.skip)
+	}
+	if limit := q.cap(maxRows); limit > 0 {
+		opts.SetLimit(limit)
+	}
+	return opts
+}
+
+// cap returns the query limit bounded by maxRows+1, 0 means unlimited
+func (q *query) cap(maxRows int) int64 {
+	limit := q.limit
+	if maxRows > 0 && (limit == 0 || limit > int64(maxRows)) {
+		limit = int64(maxRows) + 1
+	}
+	return limit
+}
+
+// writes reports whether the pipeline stores results into a collection
+func (q *query) writes() bool {
+	for _, stage := range q.Pipeline {
+		for _, op := range stage {
+			if writeStages[op.Key] {
+				return true
+			}
+		}
+	}
+	return false
+}
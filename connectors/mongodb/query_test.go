@@ -0,0 +1,82 @@
+package mongodb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestParseQueryParams(t *testing.T) {
+	q, err := parseQuery(`{
+		"collection": "users",
+		"filter": {"age": {"$gt": "@min_age"}, "status": "@status", "tags": {"$in": ["@tag", "fixed"]}},
+		"sort": {"age": -1, "name": 1},
+		"skip": "@offset",
+		"limit": 10
+	}`, map[string]any{"min_age": 18, "status": "active", "tag": "vip", "offset": "20"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "users", q.Collection)
+	assert.Equal(t, bson.D{
+		{Key: "age", Value: bson.D{{Key: "$gt", Value: int32(18)}}},
+		{Key: "status", Value: "active"},
+		{Key: "tags", Value: bson.D{{Key: "$in", Value: bson.A{"vip", "fixed"}}}},
+	}, q.Filter)
+	assert.Equal(t, bson.D{{Key: "age", Value: int32(-1)}, {Key: "name", Value: int32(1)}}, q.Sort, "sort keeps key order")
+	assert.Equal(t, int64(20), q.skip)
+	assert.Equal(t, int64(10), q.limit)
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, query := range []string{
+		`not json`,
+		`{"filter": {}}`,
+		`{"collection": "users", "limit": -1}`,
+		`{"collection": "users", "skip": "many"}`,
+	} {
+		_, err := parseQuery(query, nil)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestPipelineStages(t *testing.T) {
+	q, err := parseQuery(`{
+		"collection": "orders",
+		"filter": {"status": "@status"},
+		"pipeline": [
+			{"$lookup": {"from": "users", "localField": "user_id", "foreignField": "_id", "as": "user"}},
+			{"$group": {"_id": "$user_id", "total": {"$sum": "$amount"}}}
+		],
+		"sort": {"total": -1},
+		"projection": {"total": 1},
+		"limit": 50
+	}`, map[string]any{"status": "paid"})
+	require.NoError(t, err)
+
+	stages, err := q.stages(10)
+	require.NoError(t, err)
+	var ops []string
+	for _, stage := range stages {
+		ops = append(ops, stage[0].Key)
+	}
+	assert.Equal(t, []string{"$match", "$lookup", "$group", "$sort", "$limit", "$project"}, ops)
+	assert.Equal(t, bson.D{{Key: "status", Value: "paid"}}, stages[0][0].Value)
+	assert.Equal(t, int64(11), stages[4][0].Value, "max rows caps the query limit")
+	assert.False(t, q.writes())
+
+	q, err = parseQuery(`{"collection": "orders", "pipeline": [{"$match": {}}, {"$out": "copy"}]}`, nil)
+	require.NoError(t, err)
+	assert.True(t, q.writes())
+
+	q, err = parseQuery(`{"collection": "orders", "pipeline": [{"$match": {}, "$limit": 1}]}`, nil)
+	require.NoError(t, err)
+	_, err = q.stages(0)
+	assert.Error(t, err)
+}
+
+func TestHasPlaceholders(t *testing.T) {
+	assert.True(t, hasPlaceholders(bson.D{{Key: "$match", Value: bson.D{{Key: "a", Value: bson.A{"@x"}}}}}))
+	assert.False(t, hasPlaceholders(bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$user_id"}}}}))
+}
@@ -4,6 +4,7 @@ import (
 	_ "embed"
 	"fmt"
 
+	"github.com/centralmind/gateway/connectors"
 	"gopkg.in/yaml.v3"
 )
 
@@ -12,14 +13,15 @@ var docString string
 
 // Config represents the configuration for a Microsoft SQL Server connection
 type Config struct {
-	Hosts      []string `json:"hosts" yaml:"hosts"`             // List of server addresses
-	User       string   `json:"user" yaml:"user"`               // Username for authentication
-	Password   string   `json:"password" yaml:"password"`       // Password for authentication
-	Database   string   `json:"database" yaml:"database"`       // Database name
-	Port       int      `json:"port" yaml:"port"`               // Port number (default 1433)
-	Schema     string   `json:"schema" yaml:"schema"`           // Schema name (default "dbo")
-	ConnString string   `json:"conn_string" yaml:"conn_string"` // Direct connection string
-	IsReadonly bool     `json:"is_readonly" yaml:"is_readonly"`
+	Hosts      []string              `json:"hosts" yaml:"hosts"`             // List of server addresses
+	User       string                `json:"user" yaml:"user"`               // Username for authentication
+	Password   string                `json:"password" yaml:"password"`       // Password for authentication
+	Database   string                `json:"database" yaml:"database"`       // Database name
+	Port       int                   `json:"port" yaml:"port"`               // Port number (default 1433)
+	Schema     string                `json:"schema" yaml:"schema"`           // Schema name (default "dbo")
+	ConnString string                `json:"conn_string" yaml:"conn_string"` // Direct connection string
+	IsReadonly bool                  `json:"is_readonly" yaml:"is_readonly"`
+	Pool       connectors.PoolConfig `json:"pool" yaml:"pool"` // Connection pool settings
 }
 
 func (c Config) Readonly() bool {
@@ -23,6 +23,7 @@ func init() {
 		if err != nil {
 			return nil, fmt.Errorf("unable to connect to mssql: %v", err)
 		}
+		cfg.Pool.Apply(db.DB)
 		return &Connector{
 			config: cfg,
 			db:     db,
@@ -212,6 +213,10 @@ func (c Connector) Ping(ctx context.Context) error {
 	return nil
 }
 
+func (c Connector) Close() error {
+	return c.db.Close()
+}
+
 func (c Connector) Query(ctx context.Context, endpoint model.Endpoint, params map[string]any) ([]map[string]any, error) {
 	processed, err := castx.ParamsE(endpoint, params)
 	if err != nil {
@@ -250,7 +255,7 @@ func (c Connector) Query(ctx context.Context, endpoint model.Endpoint, params ma
 		}
 	}
 
-	rows, err := c.db.NamedQuery(endpoint.Query, processed)
+	rows, err := sqlx.NamedQueryContext(ctx, c.db, endpoint.Query, processed)
 	if err != nil {
 		return nil, xerrors.Errorf("unable to query db: %w", err)
 	}
@@ -258,12 +263,18 @@ func (c Connector) Query(ctx context.Context, endpoint model.Endpoint, params ma
 
 	res := make([]map[string]any, 0)
 	for rows.Next() {
+		if err := connectors.CheckRowLimit(endpoint, len(res)+1); err != nil {
+			return nil, err
+		}
 		row := map[string]any{}
 		if err := rows.MapScan(row); err != nil {
 			return nil, xerrors.Errorf("unable to scan row: %w", err)
 		}
 		res = append(res, row)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, xerrors.Errorf("unable to read rows: %w", err)
+	}
 	return res, nil
 }
 
@@ -4,12 +4,17 @@ import (
 	"context"
 	"database/sql"
 	_ "embed"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/centralmind/gateway/connectors"
 
 	"github.com/centralmind/gateway/castx"
+	gw_errors "github.com/centralmind/gateway/errors"
 	"github.com/centralmind/gateway/model"
 	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
@@ -30,10 +35,21 @@ func init() {
 		if err != nil {
 			return nil, xerrors.Errorf("unable to open mysql db: %w", err)
 		}
+		cfg.Pool.Apply(db.DB)
+		var replicas []*sqlx.DB
+		for _, dsn := range cfg.Replicas {
+			replica, err := sqlx.Open("mysql", dsn)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to open mysql replica: %w", err)
+			}
+			cfg.Pool.Apply(replica.DB)
+			replicas = append(replicas, replica)
+		}
 		return &Connector{
-			config: cfg,
-			db:     db,
-			base:   &connectors.BaseConnector{DB: db},
+			config:   cfg,
+			db:       db,
+			base:     &connectors.BaseConnector{DB: db},
+			replicas: connectors.NewReplicaSet(db, replicas, cfg.ReplicaConfig, replicationLag),
 		}, nil
 	})
 }
@@ -45,8 +61,11 @@ type Config struct {
 	Password   string
 	Port       int
 	TLSConfig  string
-	ConnString string `yaml:"conn_string"`
-	IsReadonly bool   `yaml:"is_readonly"`
+	ConnString string                `yaml:"conn_string"`
+	IsReadonly bool                  `yaml:"is_readonly"`
+	Pool       connectors.PoolConfig `yaml:"pool"`
+
+	connectors.ReplicaConfig `yaml:",inline"`
 }
 
 func (c Config) Readonly() bool {
@@ -107,9 +126,37 @@ func (c Config) Doc() string {
 }
 
 type Connector struct {
-	config Config
-	db     *sqlx.DB
-	base   *connectors.BaseConnector
+	config   Config
+	db       *sqlx.DB
+	base     *connectors.BaseConnector
+	replicas *connectors.ReplicaSet
+}
+
+// replicationLag reads Seconds_Behind_Source of a replica, falling back to the pre 8.0.22 statement
+func replicationLag(ctx context.Context, db *sqlx.DB) (time.Duration, error) {
+	row := map[string]any{}
+	err := db.QueryRowxContext(ctx, "SHOW REPLICA STATUS").MapScan(row)
+	if err != nil {
+		err = db.QueryRowxContext(ctx, "SHOW SLAVE STATUS").MapScan(row)
+	}
+	if err != nil {
+		return 0, xerrors.Errorf("unable to read replica status: %w", err)
+	}
+	for _, key := range []string{"Seconds_Behind_Source", "Seconds_Behind_Master"} {
+		value, ok := row[key]
+		if !ok {
+			continue
+		}
+		if value == nil {
+			return 0, xerrors.New("replication is not running")
+		}
+		seconds, err := strconv.ParseFloat(fmt.Sprintf("%s", value), 64)
+		if err != nil {
+			return 0, xerrors.Errorf("unable to parse replication lag %v: %w", value, err)
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	return 0, xerrors.New("replica status has no lag column")
 }
 
 func (c Connector) Config() connectors.Config {
@@ -220,35 +267,61 @@ func (c Connector) Ping(ctx context.Context) error {
 	return c.db.PingContext(ctx)
 }
 
+func (c Connector) Close() error {
+	return c.replicas.Close()
+}
+
 func (c Connector) Query(ctx context.Context, endpoint model.Endpoint, params map[string]any) ([]map[string]any, error) {
 	processed, err := castx.ParamsE(endpoint, params)
 	if err != nil {
 		return nil, xerrors.Errorf("unable to process params: %w", err)
 	}
-	tx, err := c.base.DB.BeginTxx(ctx, &sql.TxOptions{
+	tx, err := c.replicas.Reader(endpoint).BeginTxx(ctx, &sql.TxOptions{
 		ReadOnly: c.Config().Readonly(),
 	})
 	if err != nil {
 		return nil, xerrors.Errorf("BeginTx failed with error: %w", err)
 	}
 	defer tx.Commit()
-	rows, err := tx.NamedQuery(endpoint.Query, processed)
+	if endpoint.Timeout > 0 {
+		// the session variable lives on the pooled connection, so it is reset before the connection is returned
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET SESSION max_execution_time = %d", endpoint.Timeout.Milliseconds())); err != nil {
+			return nil, xerrors.Errorf("unable to set execution timeout: %w", err)
+		}
+		defer tx.ExecContext(context.WithoutCancel(ctx), "SET SESSION max_execution_time = 0")
+	}
+	rows, err := sqlx.NamedQueryContext(ctx, tx, endpoint.Query, processed)
 	if err != nil {
-		return nil, xerrors.Errorf("unable to query db: %w", err)
+		return nil, queryError(err, endpoint)
 	}
 	defer rows.Close()
 
 	res := make([]map[string]any, 0)
 	for rows.Next() {
+		if err := connectors.CheckRowLimit(endpoint, len(res)+1); err != nil {
+			return nil, err
+		}
 		row := map[string]any{}
 		if err := rows.MapScan(row); err != nil {
 			return nil, xerrors.Errorf("unable to scan row: %w", err)
 		}
 		res = append(res, castx.Process(row))
 	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(err, endpoint)
+	}
 	return res, nil
 }
 
+// queryError reports interrupted statements (error 3024) as gw_errors.ErrTimeout
+func queryError(err error, endpoint model.Endpoint) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 3024 {
+		return fmt.Errorf("%w: query exceeded %s: %v", gw_errors.ErrTimeout, endpoint.Timeout, err)
+	}
+	return xerrors.Errorf("unable to query db: %w", err)
+}
+
 func (c Connector) LoadsColumns(ctx context.Context, tableName string) ([]model.ColumnSchema, error) {
 	tx, err := c.base.DB.BeginTxx(ctx, &sql.TxOptions{
 		ReadOnly: c.Config().Readonly(),
@@ -356,3 +429,26 @@ func (c *Connector) InferResultColumns(ctx context.Context, query string) ([]mod
 func (c *Connector) InferQuery(ctx context.Context, query string) ([]model.ColumnSchema, error) {
 	return c.base.InferResultColumns(ctx, query, c)
 }
+
+// Explain implements connectors.Explainer, cost is the optimizer query cost
+func (c *Connector) Explain(ctx context.Context, query string) (float64, error) {
+	var raw string
+	if err := c.db.QueryRowContext(ctx, "EXPLAIN FORMAT=JSON "+query).Scan(&raw); err != nil {
+		return 0, xerrors.Errorf("unable to explain query: %w", err)
+	}
+	var plan struct {
+		QueryBlock struct {
+			CostInfo struct {
+				QueryCost json.Number `json:"query_cost"`
+			} `json:"cost_info"`
+		} `json:"query_block"`
+	}
+	if err := json.Unmarshal([]byte(raw), &plan); err != nil {
+		return 0, xerrors.Errorf("unable to parse query plan: %w", err)
+	}
+	cost, err := plan.QueryBlock.CostInfo.QueryCost.Float64()
+	if err != nil {
+		return 0, xerrors.Errorf("unable to parse query cost: %w", err)
+	}
+	return cost, nil
+}
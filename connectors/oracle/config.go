@@ -4,20 +4,22 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/centralmind/gateway/connectors"
 	"gopkg.in/yaml.v3"
 )
 
 // Config holds the configuration for Oracle database connection
 type Config struct {
-	ConnType   string   `yaml:"type"`
-	Hosts      []string `yaml:"hosts"`
-	User       string   `yaml:"user"`
-	Password   string   `yaml:"password"`
-	Database   string   `yaml:"database"`
-	Schema     string   `yaml:"schema"`
-	Port       int      `yaml:"port"`
-	ConnString string   `yaml:"conn_string"`
-	IsReadonly bool     `yaml:"is_readonly"`
+	ConnType   string                `yaml:"type"`
+	Hosts      []string              `yaml:"hosts"`
+	User       string                `yaml:"user"`
+	Password   string                `yaml:"password"`
+	Database   string                `yaml:"database"`
+	Schema     string                `yaml:"schema"`
+	Port       int                   `yaml:"port"`
+	ConnString string                `yaml:"conn_string"`
+	IsReadonly bool                  `yaml:"is_readonly"`
+	Pool       connectors.PoolConfig `yaml:"pool"`
 }
 
 func (c Config) Readonly() bool {
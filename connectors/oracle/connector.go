@@ -22,6 +22,7 @@ func init() {
 		if err != nil {
 			return nil, fmt.Errorf("unable to connect to oracle: %v", err)
 		}
+		cfg.Pool.Apply(db.DB)
 		return &Connector{
 			config: cfg,
 			db:     db,
@@ -194,6 +195,10 @@ func (c Connector) Ping(ctx context.Context) error {
 	return nil
 }
 
+func (c Connector) Close() error {
+	return c.db.Close()
+}
+
 func (c Connector) Query(ctx context.Context, endpoint model.Endpoint, params map[string]any) ([]map[string]any, error) {
 	processed, err := castx.ParamsE(endpoint, params)
 	if err != nil {
@@ -247,7 +252,7 @@ func (c Connector) Query(ctx context.Context, endpoint model.Endpoint, params ma
 	}
 
 	// Execute query with numbered parameters
-	rows, err := c.db.Queryx(query, paramValues...)
+	rows, err := c.db.QueryxContext(ctx, query, paramValues...)
 	if err != nil {
 		return nil, xerrors.Errorf("unable to execute query: %w", err)
 	}
@@ -255,12 +260,18 @@ func (c Connector) Query(ctx context.Context, endpoint model.Endpoint, params ma
 
 	res := make([]map[string]any, 0)
 	for rows.Next() {
+		if err := connectors.CheckRowLimit(endpoint, len(res)+1); err != nil {
+			return nil, err
+		}
 		row := map[string]any{}
 		if err := rows.MapScan(row); err != nil {
 			return nil, xerrors.Errorf("unable to scan row: %w", err)
 		}
 		res = append(res, row)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, xerrors.Errorf("unable to read rows: %w", err)
+	}
 	return res, nil
 }
 
@@ -2,6 +2,8 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -10,6 +12,7 @@ import (
 	"database/sql"
 
 	"github.com/centralmind/gateway/castx"
+	gw_errors "github.com/centralmind/gateway/errors"
 	"github.com/centralmind/gateway/model"
 	"github.com/jackc/pgx/v4/stdlib"
 	"github.com/jmoiron/sqlx"
@@ -23,10 +26,22 @@ func init() {
 			return nil, xerrors.Errorf("unable to prepare pg config: %w", err)
 		}
 		db := sqlx.NewDb(stdlib.OpenDB(*c), "pgx")
+		cfg.Pool.Apply(db.DB)
+		var replicas []*sqlx.DB
+		for _, dsn := range cfg.Replicas {
+			rc, err := cfg.replicaConfig(dsn)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to prepare pg replica config: %w", err)
+			}
+			replica := sqlx.NewDb(stdlib.OpenDB(*rc), "pgx")
+			cfg.Pool.Apply(replica.DB)
+			replicas = append(replicas, replica)
+		}
 		return &Connector{
-			config: cfg,
-			db:     db,
-			base:   &connectors.BaseConnector{DB: db},
+			config:   cfg,
+			db:       db,
+			base:     &connectors.BaseConnector{DB: db},
+			replicas: connectors.NewReplicaSet(db, replicas, cfg.ReplicaConfig, connectors.LagQuery(lagQuery)),
 		}, nil
 	})
 	connectors.RegisterAlias("postgres", "postgresql")
@@ -34,11 +49,17 @@ func init() {
 
 // Connector implements the connectors.Connector interface for PostgreSQL
 type Connector struct {
-	config Config
-	db     *sqlx.DB
-	base   *connectors.BaseConnector
+	config   Config
+	db       *sqlx.DB
+	base     *connectors.BaseConnector
+	replicas *connectors.ReplicaSet
 }
 
+// lagQuery measures how far a replica is behind, it reports 0 on the primary
+const lagQuery = `SELECT CASE WHEN pg_is_in_recovery()
+	THEN COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+	ELSE 0 END`
+
 func (c Connector) Config() connectors.Config {
 	return c.config
 }
@@ -227,13 +248,17 @@ func (c Connector) Ping(ctx context.Context) error {
 	return nil
 }
 
+func (c Connector) Close() error {
+	return c.replicas.Close()
+}
+
 func (c Connector) Query(ctx context.Context, endpoint model.Endpoint, params map[string]any) ([]map[string]any, error) {
 	processed, err := castx.ParamsE(endpoint, params)
 	if err != nil {
 		return nil, xerrors.Errorf("unable to process params: %w", err)
 	}
 
-	tx, err := c.db.BeginTxx(ctx, &sql.TxOptions{
+	tx, err := c.replicas.Reader(endpoint).BeginTxx(ctx, &sql.TxOptions{
 		ReadOnly: c.Config().Readonly(),
 	})
 	if err != nil {
@@ -241,23 +266,45 @@ func (c Connector) Query(ctx context.Context, endpoint model.Endpoint, params ma
 	}
 	defer tx.Commit()
 
-	rows, err := tx.NamedQuery(endpoint.Query, processed)
+	if endpoint.Timeout > 0 {
+		// SET LOCAL scopes the timeout to this transaction
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", endpoint.Timeout.Milliseconds())); err != nil {
+			return nil, xerrors.Errorf("unable to set statement timeout: %w", err)
+		}
+	}
+
+	rows, err := sqlx.NamedQueryContext(ctx, tx, endpoint.Query, processed)
 	if err != nil {
-		return nil, xerrors.Errorf("unable to query db: %w", err)
+		return nil, queryError(err, endpoint)
 	}
 	defer rows.Close()
 
 	res := make([]map[string]any, 0)
 	for rows.Next() {
+		if err := connectors.CheckRowLimit(endpoint, len(res)+1); err != nil {
+			return nil, err
+		}
 		row := map[string]any{}
 		if err := rows.MapScan(row); err != nil {
 			return nil, xerrors.Errorf("unable to scan row: %w", err)
 		}
 		res = append(res, row)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(err, endpoint)
+	}
 	return res, nil
 }
 
+// queryError reports statement timeouts (SQLSTATE 57014) as gw_errors.ErrTimeout
+func queryError(err error, endpoint model.Endpoint) error {
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) && state.SQLState() == "57014" {
+		return fmt.Errorf("%w: query exceeded %s: %v", gw_errors.ErrTimeout, endpoint.Timeout, err)
+	}
+	return xerrors.Errorf("unable to query db: %w", err)
+}
+
 func (c Connector) LoadsColumns(ctx context.Context, tableName string) ([]model.ColumnSchema, error) {
 	tx, err := c.db.BeginTxx(ctx, &sql.TxOptions{
 		ReadOnly: true,
@@ -317,3 +364,20 @@ func (c Connector) LoadsColumns(ctx context.Context, tableName string) ([]model.
 func (c *Connector) InferQuery(ctx context.Context, query string) ([]model.ColumnSchema, error) {
 	return c.base.InferResultColumns(ctx, query, c)
 }
+
+// Explain implements connectors.Explainer, cost is the planner total cost of the query
+func (c *Connector) Explain(ctx context.Context, query string) (float64, error) {
+	var raw string
+	if err := c.db.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+query).Scan(&raw); err != nil {
+		return 0, xerrors.Errorf("unable to explain query: %w", err)
+	}
+	var plans []struct {
+		Plan struct {
+			TotalCost float64 `json:"Total Cost"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(raw), &plans); err != nil || len(plans) == 0 {
+		return 0, xerrors.Errorf("unable to parse query plan: %s", raw)
+	}
+	return plans[0].Plan.TotalCost, nil
+}
@@ -0,0 +1,220 @@
+package connectors
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/centralmind/gateway/model"
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/xerrors"
+)
+
+const (
+	defaultReplicaCheckInterval = 10 * time.Second
+	replicaCheckTimeout         = 5 * time.Second
+)
+
+// ReplicaConfig lists read replicas of a SQL database, connectors inline it into their config
+type ReplicaConfig struct {
+	Replicas             []string      `json:"replicas,omitempty" yaml:"replicas,omitempty"`                             // Replica DSNs in the conn_string format
+	ReplicaCheckInterval time.Duration `json:"replica_check_interval,omitempty" yaml:"replica_check_interval,omitempty"` // Health check period, 10s by default
+	ReplicaMaxLag        time.Duration `json:"replica_max_lag,omitempty" yaml:"replica_max_lag,omitempty"`               // Replicas lagging behind more are skipped, 0 disables lag checks
+	ReplicaLagQuery      string        `json:"replica_lag_query,omitempty" yaml:"replica_lag_query,omitempty"`           // Overrides the engine lag query, must return lag in seconds
+}
+
+// LagFunc measures replication lag of a replica
+type LagFunc func(ctx context.Context, db *sqlx.DB) (time.Duration, error)
+
+// LagQuery returns a LagFunc for a query that yields lag in seconds as a single value
+func LagQuery(query string) LagFunc {
+	return func(ctx context.Context, db *sqlx.DB) (time.Duration, error) {
+		var seconds float64
+		if err := db.QueryRowxContext(ctx, query).Scan(&seconds); err != nil {
+			return 0, xerrors.Errorf("unable to query replication lag: %w", err)
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+}
+
+// ReplicaSet routes read queries across healthy replicas, everything else goes to the primary.
+// Replicas are checked with Ping and, when max lag is configured, with a lag query.
+type ReplicaSet struct {
+	primary  *sqlx.DB
+	replicas []*replica
+	lag      LagFunc
+	maxLag   time.Duration
+	next     atomic.Uint64
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type replica struct {
+	db      *sqlx.DB
+	healthy atomic.Bool
+}
+
+// NewReplicaSet takes ownership of primary and replicas, lag is the engine default lag measure and may be nil.
+// Replicas are checked once before it returns, so reads don't hit a replica that is down at startup.
+func NewReplicaSet(primary *sqlx.DB, replicas []*sqlx.DB, cfg ReplicaConfig, lag LagFunc) *ReplicaSet {
+	r := &ReplicaSet{
+		primary: primary,
+		lag:     lag,
+		maxLag:  cfg.ReplicaMaxLag,
+	}
+	if cfg.ReplicaLagQuery != "" {
+		r.lag = LagQuery(cfg.ReplicaLagQuery)
+	}
+	for _, db := range replicas {
+		r.replicas = append(r.replicas, &replica{db: db})
+	}
+	if len(r.replicas) == 0 {
+		return r
+	}
+
+	interval := cfg.ReplicaCheckInterval
+	if interval <= 0 {
+		interval = defaultReplicaCheckInterval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	r.check(ctx)
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.check(ctx)
+			}
+		}
+	}()
+	return r
+}
+
+// Primary returns the primary database
+func (r *ReplicaSet) Primary() *sqlx.DB {
+	return r.primary
+}
+
+// Reader returns the database to run endpoint query on: a healthy replica for reads, otherwise the primary
+func (r *ReplicaSet) Reader(endpoint model.Endpoint) *sqlx.DB {
+	if len(r.replicas) == 0 || !ReadsOnly(endpoint) {
+		return r.primary
+	}
+	start := r.next.Add(1)
+	for i := range r.replicas {
+		replica := r.replicas[(start+uint64(i))%uint64(len(r.replicas))]
+		if replica.healthy.Load() {
+			return replica.db
+		}
+	}
+	return r.primary
+}
+
+// Close stops health checks and closes all databases
+func (r *ReplicaSet) Close() error {
+	if r.cancel != nil {
+		r.cancel()
+		<-r.done
+	}
+	errs := []error{r.primary.Close()}
+	for _, replica := range r.replicas {
+		errs = append(errs, replica.db.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (r *ReplicaSet) check(ctx context.Context) {
+	for _, replica := range r.replicas {
+		replica.healthy.Store(r.healthy(ctx, replica.db))
+	}
+}
+
+func (r *ReplicaSet) healthy(ctx context.Context, db *sqlx.DB) bool {
+	ctx, cancel := context.WithTimeout(ctx, replicaCheckTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		return false
+	}
+	if r.maxLag <= 0 || r.lag == nil {
+		return true
+	}
+	lag, err := r.lag(ctx, db)
+	return err == nil && lag <= r.maxLag
+}
+
+// readStatements are leading keywords of statements that read data
+var readStatements = map[string]bool{
+	"SELECT":   true,
+	"WITH":     true,
+	"SHOW":     true,
+	"DESCRIBE": true,
+	"DESC":     true,
+	"EXPLAIN":  true,
+	"VALUES":   true,
+	"TABLE":    true,
+}
+
+// writeMarkers appear in read statements that still need the primary: data modifying CTEs, SELECT INTO,
+// row locks and sequence calls. False positives only send a query to the primary.
+var writeMarkers = regexp.MustCompile(`(?i)\b(INSERT|UPDATE|DELETE|MERGE|UPSERT|REPLACE|CALL|INTO|LOCK|NEXTVAL|SETVAL|FOR\s+SHARE)\b`)
+
+// ReadsOnly reports whether an endpoint query may run on a read replica
+func ReadsOnly(endpoint model.Endpoint) bool {
+	if endpoint.Consistency == model.ConsistencyPrimary {
+		return false
+	}
+	if a := endpoint.Annotations; a != nil && a.ReadOnly != nil && !*a.ReadOnly {
+		return false
+	}
+	return ReadOnlyQuery(endpoint.Query)
+}
+
+// ReadOnlyQuery reports whether a query is a read statement without data modifying parts
+func ReadOnlyQuery(query string) bool {
+	return ReadStatement(query) && !writeMarkers.MatchString(query)
+}
+
+// ReadStatement reports whether a query starts with a keyword of a read statement such as SELECT or WITH,
+// the statement may still modify data, see ReadsOnly
+func ReadStatement(query string) bool {
+	return readStatements[LeadingKeyword(query)]
+}
+
+// LeadingKeyword returns the upper-cased first keyword of a query, skipping comments and parentheses
+func LeadingKeyword(query string) string {
+	query = strings.TrimSpace(query)
+	for {
+		switch {
+		case strings.HasPrefix(query, "--"):
+			idx := strings.Index(query, "\n")
+			if idx == -1 {
+				return ""
+			}
+			query = strings.TrimSpace(query[idx+1:])
+		case strings.HasPrefix(query, "/*"):
+			idx := strings.Index(query, "*/")
+			if idx == -1 {
+				return ""
+			}
+			query = strings.TrimSpace(query[idx+2:])
+		case strings.HasPrefix(query, "("):
+			query = strings.TrimSpace(query[1:])
+		default:
+			fields := strings.Fields(query)
+			if len(fields) == 0 {
+				return ""
+			}
+			return strings.ToUpper(strings.TrimRight(fields[0], "(;"))
+		}
+	}
+}
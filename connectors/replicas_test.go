@@ -0,0 +1,62 @@
+package connectors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/centralmind/gateway/model"
+	_ "github.com/glebarez/go-sqlite"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadsOnly(t *testing.T) {
+	for query, expected := range map[string]bool{
+		"SELECT * FROM users": true,
+		"-- report\n(SELECT id FROM a) UNION (SELECT id FROM b)":    true,
+		"WITH x AS (SELECT 1) SELECT * FROM x":                      true,
+		"SELECT updated_at FROM users":                              true,
+		"SELECT * FROM users FOR UPDATE":                            false,
+		"WITH d AS (DELETE FROM users RETURNING *) SELECT * FROM d": false,
+		"SELECT nextval('seq')":                                     false,
+		"INSERT INTO users VALUES (1)":                              false,
+		"{\"collection\": \"users\"}":                               false,
+	} {
+		assert.Equal(t, expected, ReadsOnly(model.Endpoint{Query: query}), query)
+	}
+	assert.False(t, ReadsOnly(model.Endpoint{Query: "SELECT 1", Consistency: model.ConsistencyPrimary}))
+	readOnly := false
+	assert.False(t, ReadsOnly(model.Endpoint{Query: "SELECT 1", Annotations: &model.Annotations{ReadOnly: &readOnly}}))
+}
+
+func TestReplicaSet(t *testing.T) {
+	open := func() *sqlx.DB {
+		db, err := sqlx.Open("sqlite", ":memory:")
+		require.NoError(t, err)
+		return db
+	}
+	primary, healthy, lagging, down := open(), open(), open(), open()
+	require.NoError(t, down.Close())
+
+	lag := func(ctx context.Context, db *sqlx.DB) (time.Duration, error) {
+		if db == lagging {
+			return time.Minute, nil
+		}
+		return 0, nil
+	}
+	set := NewReplicaSet(primary, []*sqlx.DB{lagging, down, healthy}, ReplicaConfig{ReplicaMaxLag: time.Second}, lag)
+	t.Cleanup(func() { _ = set.Close() })
+
+	read := model.Endpoint{Query: "SELECT 1"}
+	for i := 0; i < 5; i++ {
+		assert.Same(t, healthy, set.Reader(read))
+	}
+	assert.Same(t, primary, set.Reader(model.Endpoint{Query: "DELETE FROM users"}))
+	assert.Same(t, primary, set.Reader(model.Endpoint{Query: "SELECT 1", Consistency: model.ConsistencyPrimary}))
+
+	require.NoError(t, healthy.Close())
+	set.check(context.Background())
+	assert.Same(t, primary, set.Reader(read), "falls back to the primary without healthy replicas")
+}
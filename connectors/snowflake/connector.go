@@ -3,6 +3,7 @@ package snowflake
 import (
 	"context"
 	_ "embed"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -29,6 +30,7 @@ func init() {
 		if err != nil {
 			return nil, xerrors.Errorf("unable to open Snowflake db: %w", err)
 		}
+		cfg.Pool.Apply(db.DB)
 		return &Connector{
 			config: cfg,
 			db:     db,
@@ -45,8 +47,9 @@ type Config struct {
 	Warehouse  string
 	Schema     string
 	Role       string
-	ConnString string `yaml:"conn_string"`
-	IsReadonly bool   `yaml:"is_readonly"`
+	ConnString string                `yaml:"conn_string"`
+	IsReadonly bool                  `yaml:"is_readonly"`
+	Pool       connectors.PoolConfig `yaml:"pool"`
 }
 
 func (c Config) Readonly() bool {
@@ -208,13 +211,17 @@ func (c Connector) Ping(ctx context.Context) error {
 	return c.db.PingContext(ctx)
 }
 
+func (c Connector) Close() error {
+	return c.db.Close()
+}
+
 func (c Connector) Query(ctx context.Context, endpoint model.Endpoint, params map[string]any) ([]map[string]any, error) {
 	processed, err := castx.ParamsE(endpoint, params)
 	if err != nil {
 		return nil, xerrors.Errorf("unable to process params: %w", err)
 	}
 
-	rows, err := c.db.NamedQuery(endpoint.Query, processed)
+	rows, err := sqlx.NamedQueryContext(ctx, c.db, endpoint.Query, processed)
 	if err != nil {
 		return nil, xerrors.Errorf("unable to query db: %w", err)
 	}
@@ -222,12 +229,18 @@ func (c Connector) Query(ctx context.Context, endpoint model.Endpoint, params ma
 
 	res := make([]map[string]any, 0)
 	for rows.Next() {
+		if err := connectors.CheckRowLimit(endpoint, len(res)+1); err != nil {
+			return nil, err
+		}
 		row := map[string]any{}
 		if err := rows.MapScan(row); err != nil {
 			return nil, xerrors.Errorf("unable to scan row: %w", err)
 		}
 		res = append(res, row)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, xerrors.Errorf("unable to read rows: %w", err)
+	}
 	return res, nil
 }
 
@@ -328,3 +341,20 @@ func (c *Connector) InferResultColumns(ctx context.Context, query string) ([]mod
 func (c *Connector) InferQuery(ctx context.Context, query string) ([]model.ColumnSchema, error) {
 	return c.base.InferResultColumns(ctx, query, c)
 }
+
+// Explain implements connectors.Explainer, cost is the number of bytes assigned for scanning
+func (c *Connector) Explain(ctx context.Context, query string) (float64, error) {
+	var raw string
+	if err := c.db.QueryRowContext(ctx, "EXPLAIN USING JSON "+query).Scan(&raw); err != nil {
+		return 0, xerrors.Errorf("unable to explain query: %w", err)
+	}
+	var plan struct {
+		GlobalStats struct {
+			BytesAssigned float64 `json:"bytesAssigned"`
+		} `json:"GlobalStats"`
+	}
+	if err := json.Unmarshal([]byte(raw), &plan); err != nil {
+		return 0, xerrors.Errorf("unable to parse query plan: %w", err)
+	}
+	return plan.GlobalStats.BytesAssigned, nil
+}
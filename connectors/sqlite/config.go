@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"path/filepath"
 
+	"github.com/centralmind/gateway/connectors"
 	"gopkg.in/yaml.v3"
 )
 
@@ -13,11 +14,12 @@ var docString string
 
 // Config represents the configuration for a SQLite connection
 type Config struct {
-	Hosts      []string `json:"hosts" yaml:"hosts"`             // List of database file paths
-	Database   string   `json:"database" yaml:"database"`       // Database file name
-	ReadOnly   bool     `json:"read_only" yaml:"read_only"`     // Whether to open database in read-only mode
-	Memory     bool     `json:"memory" yaml:"memory"`           // Whether to create an in-memory database
-	ConnString string   `json:"conn_string" yaml:"conn_string"` // Direct connection string
+	Hosts      []string              `json:"hosts" yaml:"hosts"`             // List of database file paths
+	Database   string                `json:"database" yaml:"database"`       // Database file name
+	ReadOnly   bool                  `json:"read_only" yaml:"read_only"`     // Whether to open database in read-only mode
+	Memory     bool                  `json:"memory" yaml:"memory"`           // Whether to create an in-memory database
+	ConnString string                `json:"conn_string" yaml:"conn_string"` // Direct connection string
+	Pool       connectors.PoolConfig `json:"pool" yaml:"pool"`               // Connection pool settings
 }
 
 func (c Config) Readonly() bool {
@@ -33,6 +33,7 @@ func init() {
 		if err != nil {
 			return nil, fmt.Errorf("unable to connect to sqlite: %v", err)
 		}
+		cfg.Pool.Apply(db.DB)
 		return &Connector{
 			config: cfg,
 			db:     db,
@@ -207,6 +208,10 @@ func (c Connector) Ping(ctx context.Context) error {
 	return nil
 }
 
+func (c Connector) Close() error {
+	return c.db.Close()
+}
+
 func (c Connector) Query(ctx context.Context, endpoint model.Endpoint, params map[string]any) ([]map[string]any, error) {
 	processed, err := castx.ParamsE(endpoint, params)
 	if err != nil {
@@ -236,6 +241,9 @@ func (c Connector) Query(ctx context.Context, endpoint model.Endpoint, params ma
 
 		var result []map[string]any
 		for rows.Next() {
+			if err := connectors.CheckRowLimit(endpoint, len(result)+1); err != nil {
+				return nil, err
+			}
 			if err := rows.Scan(valuePtrs...); err != nil {
 				return nil, xerrors.Errorf("unable to scan row: %w", err)
 			}
@@ -246,6 +254,9 @@ func (c Connector) Query(ctx context.Context, endpoint model.Endpoint, params ma
 			}
 			result = append(result, row)
 		}
+		if err := rows.Err(); err != nil {
+			return nil, xerrors.Errorf("unable to read rows: %w", err)
+		}
 		return result, nil
 	}
 
@@ -258,7 +269,7 @@ func (c Connector) Query(ctx context.Context, endpoint model.Endpoint, params ma
 	}
 	defer tx.Commit()
 
-	rows, err := tx.NamedQuery(endpoint.Query, processed)
+	rows, err := sqlx.NamedQueryContext(ctx, tx, endpoint.Query, processed)
 	if err != nil {
 		return nil, xerrors.Errorf("unable to execute query: %w", err)
 	}
@@ -266,12 +277,18 @@ func (c Connector) Query(ctx context.Context, endpoint model.Endpoint, params ma
 
 	res := make([]map[string]any, 0)
 	for rows.Next() {
+		if err := connectors.CheckRowLimit(endpoint, len(res)+1); err != nil {
+			return nil, err
+		}
 		row := map[string]any{}
 		if err := rows.MapScan(row); err != nil {
 			return nil, xerrors.Errorf("unable to scan row: %w", err)
 		}
 		res = append(res, row)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, xerrors.Errorf("unable to read rows: %w", err)
+	}
 	return res, nil
 }
 
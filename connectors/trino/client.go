@@ -0,0 +1,230 @@
+package trino
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	gw_errors "github.com/centralmind/gateway/errors"
+	"golang.org/x/xerrors"
+)
+
+// client speaks the Trino client REST protocol: a statement is posted to /v1/statement,
+// then result pages are fetched from nextUri until the query finishes.
+// See https://trino.io/docs/current/develop/client-protocol.html
+type client struct {
+	config Config
+	http   *http.Client
+}
+
+// statement is a single query submission
+type statement struct {
+	query string
+	// prepared statements referenced by EXECUTE and DESCRIBE OUTPUT, by name
+	prepared map[string]string
+	// session properties added to the configured ones
+	session map[string]string
+}
+
+type column struct {
+	Name          string        `json:"name"`
+	Type          string        `json:"type"`
+	TypeSignature typeSignature `json:"typeSignature"`
+}
+
+type typeSignature struct {
+	RawType   string              `json:"rawType"`
+	Arguments []typeSignatureArgs `json:"arguments"`
+}
+
+type typeSignatureArgs struct {
+	Kind  string          `json:"kind"`
+	Value json.RawMessage `json:"value"`
+}
+
+type queryError struct {
+	Message   string `json:"message"`
+	ErrorCode int    `json:"errorCode"`
+	ErrorName string `json:"errorName"`
+	ErrorType string `json:"errorType"`
+}
+
+// queryResults is a page of the protocol response
+type queryResults struct {
+	ID      string      `json:"id"`
+	NextURI string      `json:"nextUri"`
+	Columns []column    `json:"columns"`
+	Data    [][]any     `json:"data"`
+	Error   *queryError `json:"error"`
+}
+
+// retryable statuses mean the coordinator is busy, the request shall be repeated
+var retryable = map[int]bool{
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+const maxRetries = 5
+
+// run executes the statement and calls onRow for every row, columns are known before the first row.
+// The query is cancelled on the coordinator when ctx is done or onRow fails.
+func (c *client) run(ctx context.Context, stmt statement, onRow func(columns []column, row []any) error) ([]column, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.baseURL()+"/v1/statement", strings.NewReader(stmt.query))
+	if err != nil {
+		return nil, xerrors.Errorf("unable to create request: %w", err)
+	}
+	c.setHeaders(req, stmt)
+	page, err := c.fetch(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []column
+	for {
+		if page.Error != nil {
+			return nil, page.Error.wrap()
+		}
+		if len(page.Columns) > 0 {
+			columns = page.Columns
+		}
+		for _, row := range page.Data {
+			if err := onRow(columns, row); err != nil {
+				c.cancel(page.NextURI)
+				return nil, err
+			}
+		}
+		if page.NextURI == "" {
+			return columns, nil
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, page.NextURI, nil)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to create request: %w", err)
+		}
+		c.setHeaders(req, statement{})
+		next, err := c.fetch(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				c.cancel(page.NextURI)
+			}
+			return nil, err
+		}
+		page = next
+	}
+}
+
+func (c *client) setHeaders(req *http.Request, stmt statement) {
+	req.Header.Set("X-Trino-User", c.config.User)
+	req.Header.Set("X-Trino-Source", c.config.Source)
+	if c.config.Catalog != "" {
+		req.Header.Set("X-Trino-Catalog", c.config.Catalog)
+	}
+	if c.config.Schema != "" {
+		req.Header.Set("X-Trino-Schema", c.config.Schema)
+	}
+	if c.config.Password != "" {
+		req.SetBasicAuth(c.config.User, c.config.Password)
+	}
+	session := map[string]string{}
+	for key, value := range c.config.SessionProperties {
+		session[key] = value
+	}
+	for key, value := range stmt.session {
+		session[key] = value
+	}
+	if header := encodeHeader(session); header != "" {
+		req.Header.Set("X-Trino-Session", header)
+	}
+	if header := encodeHeader(stmt.prepared); header != "" {
+		req.Header.Set("X-Trino-Prepared-Statement", header)
+	}
+}
+
+// encodeHeader formats key=value pairs with url encoded values, as Trino expects in session and prepared statement headers
+func encodeHeader(values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key+"="+url.QueryEscape(values[key]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// fetch sends req and decodes a result page, retrying while the coordinator is busy
+func (c *client) fetch(req *http.Request) (*queryResults, error) {
+	var body []byte
+	if req.GetBody != nil {
+		// keep the statement to resend it on retries
+		r, _ := req.GetBody()
+		body, _ = io.ReadAll(r)
+	}
+	for attempt := 0; ; attempt++ {
+		if body != nil {
+			req.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		res, err := c.http.Do(req)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to reach Trino: %w", err)
+		}
+		data, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read Trino response: %w", err)
+		}
+		if retryable[res.StatusCode] && attempt < maxRetries {
+			select {
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			case <-time.After(time.Duration(attempt+1) * 50 * time.Millisecond):
+			}
+			continue
+		}
+		if res.StatusCode != http.StatusOK {
+			return nil, xerrors.Errorf("Trino returned %s: %s", res.Status, bytes.TrimSpace(data))
+		}
+		var page queryResults
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		// bigint values don't fit float64
+		decoder.UseNumber()
+		if err := decoder.Decode(&page); err != nil {
+			return nil, xerrors.Errorf("unable to parse Trino response: %w", err)
+		}
+		return &page, nil
+	}
+}
+
+// cancel stops a running query, it doesn't depend on the query context that may be already done
+func (c *client) cancel(nextURI string) {
+	if nextURI == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, nextURI, nil)
+	if err != nil {
+		return
+	}
+	c.setHeaders(req, statement{})
+	if res, err := c.http.Do(req); err == nil {
+		res.Body.Close()
+	}
+}
+
+func (e *queryError) wrap() error {
+	if e.ErrorName == "EXCEEDED_TIME_LIMIT" {
+		return fmt.Errorf("%w: %s", gw_errors.ErrTimeout, e.Message)
+	}
+	return xerrors.Errorf("Trino query failed: %s: %s", e.ErrorName, e.Message)
+}
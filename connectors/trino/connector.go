@@ -0,0 +1,248 @@
+package trino
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/centralmind/gateway/castx"
+	"github.com/centralmind/gateway/connectors"
+	"github.com/centralmind/gateway/model"
+	"golang.org/x/xerrors"
+)
+
+func init() {
+	connectors.Register(func(cfg Config) (connectors.Connector, error) {
+		cfg, err := cfg.resolve()
+		if err != nil {
+			return nil, xerrors.Errorf("unable to prepare Trino config: %w", err)
+		}
+		transport := &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			MaxConnsPerHost:     cfg.Pool.MaxOpenConns,
+			MaxIdleConnsPerHost: cfg.Pool.MaxIdleConns,
+			IdleConnTimeout:     cfg.Pool.ConnMaxIdleTime,
+		}
+		if cfg.Secure {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		return &Connector{
+			config:    cfg,
+			transport: transport,
+			client: &client{
+				config: cfg,
+				http:   &http.Client{Transport: transport},
+			},
+		}, nil
+	})
+}
+
+var _ connectors.Connector = (*Connector)(nil)
+
+// Connector implements the connectors.Connector interface for Trino and Presto compatible engines
+type Connector struct {
+	config    Config
+	client    *client
+	transport *http.Transport
+}
+
+func (c *Connector) Config() connectors.Config {
+	return c.config
+}
+
+func (c *Connector) Ping(ctx context.Context) error {
+	if _, err := c.query(ctx, statement{query: "SELECT 1"}, model.Endpoint{}); err != nil {
+		return xerrors.Errorf("unable to ping Trino: %w", err)
+	}
+	return nil
+}
+
+// Close drops idle connections, queries hold no state between calls
+func (c *Connector) Close() error {
+	c.transport.CloseIdleConnections()
+	return nil
+}
+
+func (c *Connector) Query(ctx context.Context, endpoint model.Endpoint, params map[string]any) ([]map[string]any, error) {
+	processed, err := castx.ParamsE(endpoint, params)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to process params: %w", err)
+	}
+	query, names := bindParams(endpoint.Query)
+	stmt := statement{query: query}
+	if len(names) > 0 {
+		execute, err := executeStatement(names, processed)
+		if err != nil {
+			return nil, err
+		}
+		stmt = statement{
+			query:    execute,
+			prepared: map[string]string{preparedName: query},
+		}
+	}
+	if endpoint.Timeout > 0 {
+		// the coordinator stops the query too, not only the gateway
+		stmt.session = map[string]string{
+			"query_max_run_time": fmt.Sprintf("%ds", int(math.Ceil(endpoint.Timeout.Seconds()))),
+		}
+	}
+	return c.query(ctx, stmt, endpoint)
+}
+
+// query runs stmt and collects rows, stopping once there are more than the endpoint allows
+func (c *Connector) query(ctx context.Context, stmt statement, endpoint model.Endpoint) ([]map[string]any, error) {
+	res := make([]map[string]any, 0)
+	_, err := c.client.run(ctx, stmt, func(columns []column, values []any) error {
+		if err := connectors.CheckRowLimit(endpoint, len(res)+1); err != nil {
+			return err
+		}
+		row := make(map[string]any, len(columns))
+		for i, col := range columns {
+			if i < len(values) {
+				row[col.Name] = convertValue(col.TypeSignature, values[i])
+			}
+		}
+		res = append(res, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// InferQuery describes output columns of the prepared query, the query is not executed
+func (c *Connector) InferQuery(ctx context.Context, query string) ([]model.ColumnSchema, error) {
+	query, _ = bindParams(query)
+	rows, err := c.query(ctx, statement{
+		query:    "DESCRIBE OUTPUT " + preparedName,
+		prepared: map[string]string{preparedName: query},
+	}, model.Endpoint{})
+	if err != nil {
+		return nil, xerrors.Errorf("unable to describe query output: %w", err)
+	}
+	columns := make([]model.ColumnSchema, 0, len(rows))
+	for _, row := range rows {
+		name, _ := row["Column Name"].(string)
+		typ, _ := row["Type"].(string)
+		columns = append(columns, model.ColumnSchema{
+			Name: name,
+			Type: c.GuessColumnType(typ),
+		})
+	}
+	return columns, nil
+}
+
+func (c *Connector) Sample(ctx context.Context, table model.Table) ([]map[string]any, error) {
+	rows, err := c.query(ctx, statement{query: fmt.Sprintf("SELECT * FROM %s LIMIT 5", table.Name)}, model.Endpoint{})
+	if err != nil {
+		return nil, xerrors.Errorf("unable to sample %s: %w", table.Name, err)
+	}
+	return rows, nil
+}
+
+// Discovery lists tables of the configured catalog from information_schema. Tables of the configured
+// schema are named as is, with no schema configured all schemas are discovered and tables are named schema.table.
+func (c *Connector) Discovery(ctx context.Context, tablesList []string) ([]model.Table, error) {
+	tableSet := make(map[string]bool)
+	for _, table := range tablesList {
+		tableSet[table] = true
+	}
+
+	query := fmt.Sprintf(`SELECT c.table_schema, c.table_name, c.column_name, c.data_type
+FROM %[1]sinformation_schema.columns c
+JOIN %[1]sinformation_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+WHERE t.table_type = 'BASE TABLE' AND c.table_schema <> 'information_schema'`, c.catalogPrefix())
+	if c.config.Schema != "" {
+		query += " AND c.table_schema = " + quote(c.config.Schema)
+	}
+	query += "\nORDER BY c.table_schema, c.table_name, c.ordinal_position"
+
+	rows, err := c.query(ctx, statement{query: query}, model.Endpoint{})
+	if err != nil {
+		return nil, xerrors.Errorf("unable to query information_schema: %w", err)
+	}
+
+	var tables []model.Table
+	for _, row := range rows {
+		schema, _ := row["table_schema"].(string)
+		name, _ := row["table_name"].(string)
+		if c.config.Schema == "" {
+			name = schema + "." + name
+		}
+		if len(tablesList) > 0 && !tableSet[name] {
+			continue
+		}
+		if len(tables) == 0 || tables[len(tables)-1].Name != name {
+			tables = append(tables, model.Table{Name: name})
+		}
+		columnName, _ := row["column_name"].(string)
+		dataType, _ := row["data_type"].(string)
+		table := &tables[len(tables)-1]
+		table.Columns = append(table.Columns, model.ColumnSchema{
+			Name: columnName,
+			Type: c.GuessColumnType(dataType),
+		})
+	}
+
+	for i := range tables {
+		tables[i].RowCount = c.rowCount(ctx, tables[i].Name)
+	}
+	return tables, nil
+}
+
+// rowCount reads the row count estimate from table statistics, counting rows of lakehouse tables
+// may scan terabytes. Tables without statistics report 0.
+func (c *Connector) rowCount(ctx context.Context, table string) int {
+	rows, err := c.query(ctx, statement{query: "SHOW STATS FOR " + table}, model.Endpoint{})
+	if err != nil {
+		return 0
+	}
+	for _, row := range rows {
+		// the summary row has no column name
+		if row["column_name"] != nil {
+			continue
+		}
+		if count, ok := row["row_count"].(float64); ok {
+			return int(count)
+		}
+	}
+	return 0
+}
+
+func (c *Connector) catalogPrefix() string {
+	if c.config.Catalog == "" {
+		return ""
+	}
+	return `"` + strings.ReplaceAll(c.config.Catalog, `"`, `""`) + `".`
+}
+
+// GuessColumnType maps Trino types, including parametrized ones such as varchar(10),
+// decimal(10, 2), timestamp(3) with time zone, row(...), array(...) and map(...)
+func (c *Connector) GuessColumnType(sqlType string) model.ColumnType {
+	typ := strings.ToLower(strings.TrimSpace(sqlType))
+	if idx := strings.IndexByte(typ, '('); idx >= 0 {
+		typ = strings.TrimSpace(typ[:idx])
+	}
+	switch {
+	case typ == "varchar", typ == "char", typ == "varbinary", typ == "json", typ == "uuid", typ == "ipaddress":
+		return model.TypeString
+	case typ == "bigint", typ == "integer", typ == "int", typ == "smallint", typ == "tinyint":
+		return model.TypeInteger
+	case typ == "double", typ == "real", typ == "decimal":
+		return model.TypeNumber
+	case typ == "boolean":
+		return model.TypeBoolean
+	case typ == "date", strings.HasPrefix(typ, "time"):
+		return model.TypeDatetime
+	case typ == "row", typ == "map":
+		return model.TypeObject
+	case typ == "array":
+		return model.TypeArray
+	default:
+		return model.TypeString
+	}
+}
@@ -0,0 +1,225 @@
+package trino
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// preparedName is the prepared statement used for parameter binding and output description
+const preparedName = "gateway_query"
+
+// bindParams replaces :name placeholders outside of literals, quoted identifiers and comments
+// with positional ones and returns the parameter names in order of appearance.
+func bindParams(query string) (string, []string) {
+	var res strings.Builder
+	var names []string
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case ch == '\'' || ch == '"':
+			end := i + 1
+			for end < len(query) {
+				if query[end] == ch {
+					// doubled quote is an escaped one
+					if end+1 < len(query) && query[end+1] == ch {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			end = min(end+1, len(query))
+			res.WriteString(query[i:end])
+			i = end - 1
+		case ch == '-' && i+1 < len(query) && query[i+1] == '-':
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			res.WriteString(query[i : i+end])
+			i += end - 1
+		case ch == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i - 2
+			} else {
+				end += 2
+			}
+			res.WriteString(query[i : i+2+end])
+			i += 1 + end
+		case ch == ':' && i+1 < len(query) && isIdentStart(query[i+1]) && (i == 0 || query[i-1] != ':'):
+			end := i + 1
+			for end < len(query) && isIdentPart(query[end]) {
+				end++
+			}
+			names = append(names, query[i+1:end])
+			res.WriteByte('?')
+			i = end - 1
+		default:
+			res.WriteByte(ch)
+		}
+	}
+	return res.String(), names
+}
+
+func isIdentStart(ch byte) bool {
+	return ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+}
+
+func isIdentPart(ch byte) bool {
+	return isIdentStart(ch) || ch >= '0' && ch <= '9'
+}
+
+// executeStatement builds EXECUTE of the prepared query with params formatted as literals in placeholder order
+func executeStatement(names []string, params map[string]any) (string, error) {
+	if len(names) == 0 {
+		return "EXECUTE " + preparedName, nil
+	}
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		value, err := literal(params[name])
+		if err != nil {
+			return "", xerrors.Errorf("unable to bind param %s: %w", name, err)
+		}
+		values = append(values, value)
+	}
+	return "EXECUTE " + preparedName + " USING " + strings.Join(values, ", "), nil
+}
+
+// literal formats value as a Trino SQL literal
+func literal(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "NULL", nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", v), nil
+	case float32:
+		return literal(float64(v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", xerrors.Errorf("unsupported float value %v", v)
+		}
+		return "DOUBLE '" + strconv.FormatFloat(v, 'g', -1, 64) + "'", nil
+	case json.Number:
+		if _, err := v.Float64(); err != nil {
+			return "", xerrors.Errorf("invalid number %q", v)
+		}
+		return v.String(), nil
+	case string:
+		return quote(v), nil
+	case time.Time:
+		return "TIMESTAMP '" + v.Format("2006-01-02 15:04:05.000000 -07:00") + "'", nil
+	case fmt.Stringer:
+		return quote(v.String()), nil
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		items := make([]string, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			item, err := literal(rv.Index(i).Interface())
+			if err != nil {
+				return "", err
+			}
+			items = append(items, item)
+		}
+		return "ARRAY[" + strings.Join(items, ", ") + "]", nil
+	}
+	return "", xerrors.Errorf("unsupported param type %T", value)
+}
+
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// convertValue turns a JSON encoded Trino value into a Go value: integers stay exact,
+// rows become objects keyed by field names, maps and arrays are converted recursively.
+func convertValue(sig typeSignature, value any) any {
+	if value == nil {
+		return nil
+	}
+	switch strings.ToLower(sig.RawType) {
+	case "bigint", "integer", "smallint", "tinyint":
+		if n, ok := value.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				return i
+			}
+		}
+	case "double", "real":
+		if n, ok := value.(json.Number); ok {
+			if f, err := n.Float64(); err == nil {
+				return f
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok || len(sig.Arguments) != 1 {
+			break
+		}
+		elem := sig.Arguments[0].typeSignature()
+		res := make([]any, len(items))
+		for i, item := range items {
+			res[i] = convertValue(elem, item)
+		}
+		return res
+	case "map":
+		entries, ok := value.(map[string]any)
+		if !ok || len(sig.Arguments) != 2 {
+			break
+		}
+		elem := sig.Arguments[1].typeSignature()
+		res := make(map[string]any, len(entries))
+		for key, item := range entries {
+			res[key] = convertValue(elem, item)
+		}
+		return res
+	case "row":
+		fields, ok := value.([]any)
+		if !ok || len(fields) != len(sig.Arguments) {
+			break
+		}
+		res := make(map[string]any, len(fields))
+		for i, field := range fields {
+			name, fieldSig := sig.Arguments[i].namedType()
+			if name == "" {
+				name = fmt.Sprintf("field%d", i)
+			}
+			res[name] = convertValue(fieldSig, field)
+		}
+		return res
+	}
+	if n, ok := value.(json.Number); ok {
+		// numbers of other types, decimals are sent as strings
+		return n.String()
+	}
+	return value
+}
+
+func (a typeSignatureArgs) typeSignature() typeSignature {
+	var sig typeSignature
+	_ = json.Unmarshal(a.Value, &sig)
+	return sig
+}
+
+func (a typeSignatureArgs) namedType() (string, typeSignature) {
+	var named struct {
+		FieldName *struct {
+			Name string `json:"name"`
+		} `json:"fieldName"`
+		TypeSignature typeSignature `json:"typeSignature"`
+	}
+	_ = json.Unmarshal(a.Value, &named)
+	if named.FieldName == nil {
+		return "", named.TypeSignature
+	}
+	return named.FieldName.Name, named.TypeSignature
+}
@@ -4,4 +4,12 @@ import "golang.org/x/xerrors"
 
 var (
 	ErrNotAuthorized = xerrors.New("not authorized")
+	// ErrTimeout is returned when a query runs longer than its endpoint timeout
+	ErrTimeout = xerrors.New("query timed out")
+	// ErrTooManyRows is returned when a query result is larger than its endpoint max_rows
+	ErrTooManyRows = xerrors.New("too many rows")
+	// ErrTooManyRequests is returned when an endpoint already runs max_concurrency queries
+	ErrTooManyRequests = xerrors.New("too many concurrent requests")
+	// ErrTooExpensive is returned when a query would scan more bytes than its endpoint maximum_bytes_billed
+	ErrTooExpensive = xerrors.New("query exceeds bytes budget")
 )
@@ -9,6 +9,7 @@ import (
 	_ "github.com/centralmind/gateway/connectors/bigquery"
 	_ "github.com/centralmind/gateway/connectors/clickhouse"
 	_ "github.com/centralmind/gateway/connectors/elasticsearch"
+	_ "github.com/centralmind/gateway/connectors/httpapi"
 	_ "github.com/centralmind/gateway/connectors/mongodb"
 	_ "github.com/centralmind/gateway/connectors/mssql"
 	_ "github.com/centralmind/gateway/connectors/mysql"
@@ -16,12 +17,18 @@ import (
 	_ "github.com/centralmind/gateway/connectors/postgres"
 	_ "github.com/centralmind/gateway/connectors/snowflake"
 	_ "github.com/centralmind/gateway/connectors/sqlite"
+	_ "github.com/centralmind/gateway/connectors/trino"
 	_ "github.com/centralmind/gateway/plugins/api_keys"
+	_ "github.com/centralmind/gateway/plugins/column_masking"
+	_ "github.com/centralmind/gateway/plugins/jwt"
 	_ "github.com/centralmind/gateway/plugins/lru_cache"
 	_ "github.com/centralmind/gateway/plugins/lua_rls"
 	_ "github.com/centralmind/gateway/plugins/oauth"
 	_ "github.com/centralmind/gateway/plugins/otel"
+	_ "github.com/centralmind/gateway/plugins/pii_detector"
 	_ "github.com/centralmind/gateway/plugins/pii_remover"
+	_ "github.com/centralmind/gateway/plugins/sql_rls"
+	_ "github.com/centralmind/gateway/plugins/tokenization"
 	_ "github.com/centralmind/gateway/providers/anthropic"
 	_ "github.com/centralmind/gateway/providers/bedrock"
 	_ "github.com/centralmind/gateway/providers/openai"
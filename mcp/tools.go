@@ -75,19 +75,47 @@ type Tool struct {
 	InputSchema ToolInputSchema `json:"inputSchema"`
 	// Alternative to InputSchema - allows arbitrary JSON Schema to be provided
 	RawInputSchema json.RawMessage `json:"-"` // Hide this from JSON marshaling
+	// Optional hints describing tool behavior.
+	Annotations *ToolAnnotations `json:"annotations,omitempty"`
+}
+
+// ToolAnnotations are additional properties describing a Tool to clients.
+//
+// All properties are hints, they are not guaranteed to provide a faithful
+// description of tool behavior. Clients should never make tool use decisions
+// based on annotations received from untrusted servers.
+type ToolAnnotations struct {
+	// A human-readable title for the tool.
+	Title string `json:"title,omitempty"`
+	// If true, the tool does not modify its environment.
+	ReadOnlyHint *bool `json:"readOnlyHint,omitempty"`
+	// If true, the tool may perform destructive updates to its environment.
+	// If false, the tool performs only additive updates.
+	// Meaningful only when ReadOnlyHint is false.
+	DestructiveHint *bool `json:"destructiveHint,omitempty"`
+	// If true, calling the tool repeatedly with the same arguments will have
+	// no additional effect on its environment.
+	// Meaningful only when ReadOnlyHint is false.
+	IdempotentHint *bool `json:"idempotentHint,omitempty"`
+	// If true, this tool may interact with an "open world" of external
+	// entities. If false, the tool's domain of interaction is closed.
+	OpenWorldHint *bool `json:"openWorldHint,omitempty"`
 }
 
 // MarshalJSON implements the json.Marshaler interface for Tool.
 // It handles marshaling either InputSchema or RawInputSchema based on which is set.
 func (t Tool) MarshalJSON() ([]byte, error) {
 	// Create a map to build the JSON structure
-	m := make(map[string]interface{}, 3)
+	m := make(map[string]interface{}, 4)
 
 	// Add the name and description
 	m["name"] = t.Name
 	if t.Description != "" {
 		m["description"] = t.Description
 	}
+	if t.Annotations != nil {
+		m["annotations"] = t.Annotations
+	}
 
 	// Determine which schema to use
 	if t.RawInputSchema != nil {
@@ -166,6 +194,14 @@ func WithDescription(description string) ToolOption {
 	}
 }
 
+// WithAnnotations sets behavior hints of the Tool.
+// Hints let clients decide, for example, whether a tool can be auto-approved.
+func WithAnnotations(annotations ToolAnnotations) ToolOption {
+	return func(t *Tool) {
+		t.Annotations = &annotations
+	}
+}
+
 //
 // Common Property Options
 //
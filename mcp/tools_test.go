@@ -142,3 +142,21 @@ func TestUnmarshalToolWithoutRawSchema(t *testing.T) {
 	assert.Empty(t, toolUnmarshalled.InputSchema.Required)
 	assert.Empty(t, toolUnmarshalled.RawInputSchema)
 }
+
+func TestToolAnnotations(t *testing.T) {
+	readOnly := true
+	tool := NewTool("annotated-tool",
+		WithAnnotations(ToolAnnotations{ReadOnlyHint: &readOnly}),
+	)
+
+	data, err := json.Marshal(tool)
+	assert.NoError(t, err)
+
+	var raw map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &raw))
+	assert.Equal(t, map[string]interface{}{"readOnlyHint": true}, raw["annotations"])
+
+	data, err = json.Marshal(NewTool("plain-tool"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "annotations")
+}
@@ -534,6 +534,43 @@ func ParseCallToolResult(rawMessage *json.RawMessage) (*CallToolResult, error) {
 	return &result, nil
 }
 
+func ParseCreateMessageResult(rawMessage *json.RawMessage) (*CreateMessageResult, error) {
+	var jsonContent map[string]any
+	if err := json.Unmarshal(*rawMessage, &jsonContent); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	var result CreateMessageResult
+
+	meta, ok := jsonContent["_meta"]
+	if ok {
+		if metaMap, ok := meta.(map[string]any); ok {
+			result.Meta = metaMap
+		}
+	}
+
+	result.Model = ExtractString(jsonContent, "model")
+	result.StopReason = ExtractString(jsonContent, "stopReason")
+
+	roleStr := ExtractString(jsonContent, "role")
+	if roleStr != string(RoleAssistant) && roleStr != string(RoleUser) {
+		return nil, fmt.Errorf("unsupported role: %s", roleStr)
+	}
+	result.Role = Role(roleStr)
+
+	contentMap, ok := jsonContent["content"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("content is not an object")
+	}
+	content, err := ParseContent(contentMap)
+	if err != nil {
+		return nil, err
+	}
+	result.Content = content
+
+	return &result, nil
+}
+
 func ParseResourceContents(contentMap map[string]any) (ResourceContents, error) {
 	uri := ExtractString(contentMap, "uri")
 	if uri == "" {
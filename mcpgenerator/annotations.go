@@ -0,0 +1,90 @@
+package mcpgenerator
+
+import (
+	"strings"
+
+	"github.com/centralmind/gateway/connectors"
+	"github.com/centralmind/gateway/mcp"
+	"github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/sqlguard"
+)
+
+// destructiveStatements are leading query keywords of statements that may remove or overwrite data
+var destructiveStatements = map[string]bool{
+	"DELETE":   true,
+	"DROP":     true,
+	"TRUNCATE": true,
+	"UPDATE":   true,
+	"MERGE":    true,
+	"REPLACE":  true,
+	"ALTER":    true,
+}
+
+// writeStatements are leading query keywords of statements that modify data but are not assumed destructive
+var writeStatements = map[string]bool{
+	"INSERT": true,
+	"CREATE": true,
+	"UPSERT": true,
+	"COPY":   true,
+	"CALL":   true,
+}
+
+// EndpointAnnotations infers MCP tool hints from the endpoint HTTP method and query,
+// then applies overrides from the endpoint config.
+func EndpointAnnotations(endpoint model.Endpoint) mcp.ToolAnnotations {
+	statement := connectors.LeadingKeyword(endpoint.Query)
+	method := strings.ToUpper(endpoint.HTTPMethod)
+
+	// Read statements may hide writes, e.g. WITH x AS (DELETE ... RETURNING *), those count as the statement they hide
+	readStatement := connectors.ReadStatement(endpoint.Query)
+	hiddenWrite, hiddenDestructive := false, false
+	if readStatement {
+		writes, ok := sqlguard.Writes(endpoint.Query)
+		// a query that can't be lexed may hide anything
+		hiddenWrite, hiddenDestructive = !ok || len(writes) > 0, !ok
+		for _, keyword := range writes {
+			hiddenDestructive = hiddenDestructive || destructiveStatements[keyword]
+		}
+	}
+	isWrite := writeStatements[statement] || destructiveStatements[statement] || hiddenWrite
+	// Queries that are not SQL (e.g. MongoDB or Elasticsearch) fall back to the HTTP method
+	readOnly := (readStatement && !hiddenWrite) || (!isWrite && (method == "" || method == "GET"))
+	destructive := !readOnly && (destructiveStatements[statement] || hiddenDestructive || method == "DELETE")
+	idempotent := readOnly || method == "PUT" || method == "DELETE"
+	openWorld := false
+
+	if override := endpoint.Annotations; override != nil {
+		if override.ReadOnly != nil {
+			readOnly = *override.ReadOnly
+		}
+		if override.Destructive != nil {
+			destructive = *override.Destructive
+		}
+		if override.Idempotent != nil {
+			idempotent = *override.Idempotent
+		}
+		if override.OpenWorld != nil {
+			openWorld = *override.OpenWorld
+		}
+	}
+
+	return mcp.ToolAnnotations{
+		Title:           endpoint.Summary,
+		ReadOnlyHint:    &readOnly,
+		DestructiveHint: &destructive,
+		IdempotentHint:  &idempotent,
+		OpenWorldHint:   &openWorld,
+	}
+}
+
+// EndpointDescription builds a tool description from the endpoint summary and description
+func EndpointDescription(endpoint model.Endpoint) string {
+	switch {
+	case endpoint.Description == "":
+		return endpoint.Summary
+	case endpoint.Summary == "" || strings.Contains(endpoint.Description, endpoint.Summary):
+		return endpoint.Description
+	default:
+		return endpoint.Summary + "\n\n" + endpoint.Description
+	}
+}
@@ -0,0 +1,110 @@
+package mcpgenerator
+
+import (
+	"testing"
+
+	"github.com/centralmind/gateway/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		endpoint    model.Endpoint
+		readOnly    bool
+		destructive bool
+		idempotent  bool
+	}{
+		{
+			name:       "select via GET",
+			endpoint:   model.Endpoint{HTTPMethod: "GET", Query: "SELECT * FROM users WHERE id = :id"},
+			readOnly:   true,
+			idempotent: true,
+		},
+		{
+			name:       "CTE with leading comment",
+			endpoint:   model.Endpoint{HTTPMethod: "POST", Query: "-- report\nWITH t AS (SELECT 1) SELECT * FROM t"},
+			readOnly:   true,
+			idempotent: true,
+		},
+		{
+			name:     "insert",
+			endpoint: model.Endpoint{HTTPMethod: "POST", Query: "INSERT INTO users (name) VALUES (:name)"},
+		},
+		{
+			name:        "delete",
+			endpoint:    model.Endpoint{HTTPMethod: "DELETE", Query: "DELETE FROM users WHERE id = :id"},
+			destructive: true,
+			idempotent:  true,
+		},
+		{
+			name:        "data modifying CTE",
+			endpoint:    model.Endpoint{HTTPMethod: "GET", Query: "WITH x AS (DELETE FROM users WHERE id = :id RETURNING *) SELECT * FROM x"},
+			destructive: true,
+		},
+		{
+			name:     "CTE followed by insert",
+			endpoint: model.Endpoint{HTTPMethod: "POST", Query: "WITH t AS (SELECT :name AS name) INSERT INTO users (name) SELECT name FROM t"},
+		},
+		{
+			name:       "replace function",
+			endpoint:   model.Endpoint{HTTPMethod: "GET", Query: "SELECT replace(name, 'a', 'b') FROM users"},
+			readOnly:   true,
+			idempotent: true,
+		},
+		{
+			name:       "keyword in a string literal",
+			endpoint:   model.Endpoint{HTTPMethod: "GET", Query: "SELECT * FROM notes WHERE note LIKE '%update%' -- delete later"},
+			readOnly:   true,
+			idempotent: true,
+		},
+		{
+			name:       "column named lock",
+			endpoint:   model.Endpoint{HTTPMethod: "GET", Query: "SELECT id, lock FROM jobs FOR UPDATE"},
+			readOnly:   true,
+			idempotent: true,
+		},
+		{
+			name:     "select into",
+			endpoint: model.Endpoint{HTTPMethod: "POST", Query: "SELECT * INTO archive FROM users"},
+		},
+		{
+			name:       "non sql query falls back to http method",
+			endpoint:   model.Endpoint{HTTPMethod: "GET", Query: `{"collection": "users"}`},
+			readOnly:   true,
+			idempotent: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := EndpointAnnotations(tt.endpoint)
+			assert.Equal(t, tt.readOnly, *res.ReadOnlyHint)
+			assert.Equal(t, tt.destructive, *res.DestructiveHint)
+			assert.Equal(t, tt.idempotent, *res.IdempotentHint)
+			assert.False(t, *res.OpenWorldHint)
+		})
+	}
+}
+
+func TestEndpointAnnotationsOverride(t *testing.T) {
+	readOnly := false
+	openWorld := true
+	res := EndpointAnnotations(model.Endpoint{
+		HTTPMethod: "GET",
+		Query:      "SELECT refresh_cache()",
+		Annotations: &model.Annotations{
+			ReadOnly:  &readOnly,
+			OpenWorld: &openWorld,
+		},
+	})
+	assert.False(t, *res.ReadOnlyHint)
+	assert.True(t, *res.OpenWorldHint)
+}
+
+func TestEndpointDescription(t *testing.T) {
+	assert.Equal(t, "List users", EndpointDescription(model.Endpoint{Summary: "List users"}))
+	assert.Equal(t, "List users\n\nReturns active users only", EndpointDescription(model.Endpoint{
+		Summary:     "List users",
+		Description: "Returns active users only",
+	}))
+}
@@ -0,0 +1,177 @@
+package mcpgenerator
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/centralmind/gateway/mcp"
+	"github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/xcontext"
+	"golang.org/x/xerrors"
+)
+
+// cursorArgument is an extra tool argument used to page through truncated results
+const cursorArgument = "cursor"
+
+// SetResultBudget sets the default result budget applied to every tool.
+// Endpoints can override it with their own mcp_result settings.
+func (s *MCPServer) SetResultBudget(budget model.ResultBudget) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.budget = budget
+}
+
+// cursorOption adds the paging cursor argument to a tool when results may be truncated
+func cursorOption(budget model.ResultBudget) mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		if !budget.Limited() {
+			return
+		}
+		if _, ok := t.InputSchema.Properties[cursorArgument]; ok {
+			return
+		}
+		mcp.WithString(cursorArgument, mcp.Description("Cursor returned by a previous truncated call, used to fetch the next page of rows"))(t)
+	}
+}
+
+// popCursor extracts the paging cursor from tool arguments and decodes it into a row offset
+func popCursor(args map[string]any) (int, error) {
+	raw, ok := args[cursorArgument]
+	if !ok {
+		return 0, nil
+	}
+	delete(args, cursorArgument)
+	cursor, _ := raw.(string)
+	if cursor == "" {
+		return 0, nil
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, xerrors.Errorf("invalid cursor: %s", cursor)
+	}
+	offset, err := strconv.Atoi(string(decoded))
+	if err != nil || offset < 0 {
+		return 0, xerrors.Errorf("invalid cursor: %s", cursor)
+	}
+	return offset, nil
+}
+
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+// estimateTokens gives a rough token count, good enough to keep results within an agent context
+func estimateTokens(bytes int) int {
+	return (bytes + 3) / 4
+}
+
+// costNote tells agents how much data a query scans, so they learn to write cheaper queries
+func costNote(stats *xcontext.QueryStats) string {
+	if stats == nil || stats.EstimatedBytes <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" Query scans an estimated %s.", formatBytes(stats.EstimatedBytes))
+}
+
+// metaNote reports response metadata added by interceptors, e.g. how many values were masked
+func metaNote(stats *xcontext.QueryStats) string {
+	if stats == nil || len(stats.Meta) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(stats.Meta))
+	for key := range stats.Meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + stats.Meta[key]
+	}
+	return fmt.Sprintf(" Metadata: %s.", strings.Join(pairs, ", "))
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// renderResult converts rows into tool result content, starting from offset and
+// stopping once the budget is exhausted. At least one row is always returned so
+// that paging makes progress even if a single row is larger than the budget.
+func renderResult(
+	name string,
+	header string,
+	rows []map[string]any,
+	offset int,
+	budget model.ResultBudget,
+	encode func(row any) string,
+) *mcp.CallToolResult {
+	if offset > len(rows) {
+		offset = len(rows)
+	}
+	if budget.Format == model.ResultFormatJSON || budget.Format == model.ResultFormatResource {
+		encode = jsonify
+	}
+
+	var page []string
+	size := len(header)
+	for _, row := range rows[offset:] {
+		if budget.MaxRows > 0 && len(page) >= budget.MaxRows {
+			break
+		}
+		encoded := encode(row)
+		if len(page) > 0 {
+			if budget.MaxBytes > 0 && size+len(encoded) > budget.MaxBytes {
+				break
+			}
+			if budget.MaxTokens > 0 && estimateTokens(size+len(encoded)) > budget.MaxTokens {
+				break
+			}
+		}
+		size += len(encoded) + 1
+		page = append(page, encoded)
+	}
+
+	content := []mcp.Content{mcp.NewTextContent(header)}
+	switch budget.Format {
+	case model.ResultFormatJSON:
+		content = append(content, mcp.NewTextContent(jsonArray(page)))
+	case model.ResultFormatResource:
+		content = append(content, mcp.NewEmbeddedResource(mcp.TextResourceContents{
+			URI:      fmt.Sprintf("gateway://tools/%s/result", name),
+			MIMEType: "application/json",
+			Text:     jsonArray(page),
+		}))
+	default:
+		for _, row := range page {
+			content = append(content, mcp.NewTextContent(row))
+		}
+	}
+
+	next := offset + len(page)
+	if remaining := len(rows) - next; remaining > 0 {
+		content = append(content, mcp.NewTextContent(fmt.Sprintf(
+			"Result truncated: %v more row-(s), refine your query or use cursor %q to fetch the next page.",
+			remaining,
+			encodeCursor(next),
+		)))
+	}
+	return &mcp.CallToolResult{
+		Content: content,
+	}
+}
+
+func jsonArray(encodedRows []string) string {
+	return "[" + strings.Join(encodedRows, ",") + "]"
+}
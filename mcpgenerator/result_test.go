@@ -0,0 +1,96 @@
+package mcpgenerator
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/centralmind/gateway/mcp"
+	"github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/xcontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRows(n int) []map[string]any {
+	var rows []map[string]any
+	for i := 0; i < n; i++ {
+		rows = append(rows, map[string]any{"id": i})
+	}
+	return rows
+}
+
+func TestRenderResultUnlimited(t *testing.T) {
+	res := renderResult("tool", "header", testRows(3), 0, model.ResultBudget{}, jsonify)
+	require.Len(t, res.Content, 4)
+	assert.Equal(t, `{"id":2}`, res.Content[3].(mcp.TextContent).Text)
+}
+
+func TestRenderResultTruncatesAndPages(t *testing.T) {
+	budget := model.ResultBudget{MaxRows: 2}
+	rows := testRows(5)
+
+	res := renderResult("tool", "header", rows, 0, budget, jsonify)
+	require.Len(t, res.Content, 4)
+	footer := res.Content[3].(mcp.TextContent).Text
+	assert.Contains(t, footer, "3 more row-(s)")
+	assert.Contains(t, footer, encodeCursor(2))
+
+	offset, err := popCursor(map[string]any{cursorArgument: encodeCursor(2)})
+	require.NoError(t, err)
+	assert.Equal(t, 2, offset)
+
+	res = renderResult("tool", "header", rows, 4, budget, jsonify)
+	require.Len(t, res.Content, 2)
+	assert.Equal(t, `{"id":4}`, res.Content[1].(mcp.TextContent).Text)
+}
+
+func TestRenderResultByteBudget(t *testing.T) {
+	// every row is 8 bytes, so the budget fits the header and two rows
+	budget := model.ResultBudget{MaxBytes: len("header") + 18}
+	res := renderResult("tool", "header", testRows(5), 0, budget, jsonify)
+	require.Len(t, res.Content, 4)
+	assert.Contains(t, res.Content[3].(mcp.TextContent).Text, "3 more row-(s)")
+
+	// a single oversized row is still returned so paging can make progress
+	budget = model.ResultBudget{MaxBytes: 1}
+	res = renderResult("tool", "header", testRows(2), 0, budget, jsonify)
+	require.Len(t, res.Content, 3)
+}
+
+func TestRenderResultJSONFormat(t *testing.T) {
+	budget := model.ResultBudget{Format: model.ResultFormatJSON}
+	res := renderResult("tool", "header", testRows(3), 0, budget, jsonify)
+	require.Len(t, res.Content, 2)
+	var rows []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(res.Content[1].(mcp.TextContent).Text), &rows))
+	assert.Len(t, rows, 3)
+}
+
+func TestRenderResultResourceFormat(t *testing.T) {
+	budget := model.ResultBudget{Format: model.ResultFormatResource}
+	res := renderResult("tool", "header", testRows(3), 0, budget, jsonify)
+	require.Len(t, res.Content, 2)
+	resource := res.Content[1].(mcp.EmbeddedResource).Resource.(mcp.TextResourceContents)
+	assert.Equal(t, "application/json", resource.MIMEType)
+	assert.Equal(t, "gateway://tools/tool/result", resource.URI)
+}
+
+func TestPopCursorInvalid(t *testing.T) {
+	_, err := popCursor(map[string]any{cursorArgument: "not a cursor"})
+	assert.Error(t, err)
+}
+
+func TestCostNote(t *testing.T) {
+	assert.Empty(t, costNote(nil))
+	assert.Empty(t, costNote(&xcontext.QueryStats{}))
+	assert.Equal(t, " Query scans an estimated 512 B.", costNote(&xcontext.QueryStats{EstimatedBytes: 512}))
+	assert.Equal(t, " Query scans an estimated 1.5 GiB.", costNote(&xcontext.QueryStats{EstimatedBytes: 3 << 29}))
+}
+
+func TestMetaNote(t *testing.T) {
+	assert.Empty(t, metaNote(nil))
+	stats := &xcontext.QueryStats{}
+	stats.SetMeta("masked", "3")
+	stats.SetMeta("filtered", "1")
+	assert.Equal(t, " Metadata: filtered=1, masked=3.", metaNote(stats))
+}
@@ -1,11 +1,14 @@
 package mcpgenerator
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/centralmind/gateway/connectors"
+	"github.com/centralmind/gateway/mcp"
 	"github.com/centralmind/gateway/model"
 	"github.com/centralmind/gateway/plugins"
 	"github.com/centralmind/gateway/server"
+	"github.com/centralmind/gateway/sqlguard"
 	"golang.org/x/xerrors"
 	"sync"
 )
@@ -13,39 +16,65 @@ import (
 type MCPServer struct {
 	server       *server.MCPServer
 	connector    connectors.Connector
+	guard        *sqlguard.Guard
 	tools        []model.Endpoint
-	interceptors []plugins.Interceptor
+	scope        *plugins.Scope
+	interceptors []plugins.BatchInterceptor // global ones, for queries without an endpoint
+	budget       model.ResultBudget
+	asker        *asker
 
-	mu    sync.Mutex
-	plugs map[string]any
+	mu sync.Mutex
 }
 
 func New(
-	plugs map[string]any,
+	scope *plugins.Scope,
 ) (*MCPServer, error) {
 	srv := server.NewMCPServer("mcp-data-gateway", "0.0.1")
-	interceptors, err := plugins.Plugins[plugins.Interceptor](plugs)
+	interceptors, err := scope.Interceptors(model.Endpoint{})
 	if err != nil {
 		return nil, xerrors.Errorf("unable to init interceptors: %w", err)
 	}
-	return &MCPServer{
+	res := &MCPServer{
 		server:       srv,
 		connector:    nil,
-		plugs:        plugs,
+		scope:        scope,
 		interceptors: interceptors,
-	}, nil
+	}
+	srv.AddToolFilter(func(ctx context.Context, tool mcp.Tool) bool {
+		endpoint, ok := res.endpointByName(tool.Name)
+		if !ok {
+			return true
+		}
+		return scope.Visible(ctx, endpoint)
+	})
+	return res, nil
 }
 
-func (s *MCPServer) SetConnector(connector connectors.Connector) error {
+// endpointByName returns the endpoint a tool is generated from, only such tools
+// are subject to per-caller visibility
+func (s *MCPServer) endpointByName(name string) (model.Endpoint, bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	var err error
-	connector, err = plugins.Wrap(s.plugs, connector)
-	if err != nil {
-		return xerrors.Errorf("unable to init connector plugins: %w", err)
+	for _, endpoint := range s.tools {
+		if endpoint.MCPMethod == name {
+			return endpoint, true
+		}
 	}
+	return model.Endpoint{}, false
+}
+
+// SetConnector sets the connector used by tools, it shall be already wrapped by plugins
+func (s *MCPServer) SetConnector(connector connectors.Connector) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.connector = connector
-	return nil
+}
+
+// SetGuard configures guardrails for SQL sent by agents to raw and ask tools
+func (s *MCPServer) SetGuard(guard *sqlguard.Guard) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.guard = guard
 }
 
 func (s *MCPServer) ServeSSE(addr string, prefix string) *server.SSEServer {
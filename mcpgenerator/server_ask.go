@@ -0,0 +1,246 @@
+package mcpgenerator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/centralmind/gateway/connectors"
+	"github.com/centralmind/gateway/mcp"
+	"github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/plugins"
+	"github.com/centralmind/gateway/prompter"
+	"github.com/centralmind/gateway/providers"
+	"github.com/centralmind/gateway/xcontext"
+	"golang.org/x/xerrors"
+)
+
+const askSystemPrompt = `You translate questions about data into a single SQL query for %s database.
+!Important rules:
+	- Respond only with a single JSON object: {"sql": "<query>", "explanation": "<short explanation>"}.
+	- The query must be exactly one read-only SELECT statement (CTEs with WITH are allowed), no trailing semicolon.
+	- Use only tables and columns from the schema below, qualify table names exactly as they are listed.
+	- Prefer aggregations over returning raw rows, and add LIMIT when returning rows.
+
+Schema:
+%s`
+
+// askAnswer is the JSON structure the provider is asked to return
+type askAnswer struct {
+	SQL         string `json:"sql"`
+	Explanation string `json:"explanation"`
+}
+
+// asker turns natural-language questions into SQL using an AI provider
+type asker struct {
+	config   model.AskParams
+	provider providers.ModelProvider
+
+	mu         sync.Mutex
+	schema     string
+	schemaTime time.Time
+}
+
+// EnableAskTool registers the ask_database tool, which generates SQL for a question
+// with the configured AI provider, validates it and executes it in read-only mode.
+func (s *MCPServer) EnableAskTool(cfg model.AskParams) error {
+	provider, err := providers.NewModelProvider(providers.ModelProviderConfig{
+		Name:            cfg.Provider,
+		Endpoint:        cfg.Endpoint,
+		APIKey:          cfg.APIKey,
+		BedrockRegion:   cfg.BedrockRegion,
+		VertexAIRegion:  cfg.VertexAIRegion,
+		VertexAIProject: cfg.VertexAIProject,
+	})
+	if err != nil {
+		return xerrors.Errorf("unable to init ask provider %s: %w", cfg.Provider, err)
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.SchemaCacheTTL <= 0 {
+		cfg.SchemaCacheTTL = 10 * time.Minute
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.asker = &asker{config: cfg, provider: provider}
+	s.server.DeleteTools("ask_database")
+	s.server.AddTool(mcp.NewTool(
+		"ask_database",
+		mcp.WithDescription(fmt.Sprintf(`Answer a question about data in connected %s database.
+The gateway generates a read-only SQL query for the question, executes it and returns rows together with the SQL it used.
+Use it when you don't want to write SQL yourself, ask one focused question per call.
+`, s.connector.Config().Type())),
+		mcp.WithString("question", mcp.Required(), mcp.Description("Question about the data in natural language")),
+		cursorOption(s.budget),
+		mcp.WithAnnotations(mcp.ToolAnnotations{
+			Title:           "Ask database",
+			ReadOnlyHint:    ptr(true),
+			DestructiveHint: ptr(false),
+			IdempotentHint:  ptr(true),
+			OpenWorldHint:   ptr(true),
+		}),
+	), s.ask)
+	return nil
+}
+
+func (s *MCPServer) ask(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	offset, err := popCursor(request.Params.Arguments)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	question, _ := request.Params.Arguments["question"].(string)
+	if strings.TrimSpace(question) == "" {
+		return mcp.NewToolResultError("question is required"), nil
+	}
+
+	schema, err := s.askSchema(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to prepare schema context: %w", err)
+	}
+	answer, err := s.generateSQL(ctx, question, schema)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Unable to answer the question: %s", err)), nil
+	}
+
+	ctx, stats := xcontext.WithQueryStats(ctx)
+	resData, err := s.connector.Query(ctx, model.Endpoint{Query: answer.SQL}, make(map[string]any))
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Unable to query: %s\nSQL:\n%s", err, answer.SQL)), nil
+	}
+	res, err := plugins.Intercept(ctx, s.interceptors, plugins.Call{Endpoint: model.Endpoint{Query: answer.SQL}}, resData)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Unable to process result: %s\nSQL:\n%s", err, answer.SQL)), nil
+	}
+
+	result := renderResult(
+		"ask_database",
+		fmt.Sprintf("Found %v records-(s).%s%s", len(res), costNote(stats), metaNote(stats)),
+		res,
+		offset,
+		s.budget,
+		prompter.Yamlify,
+	)
+	result.Content = append([]mcp.Content{
+		mcp.NewTextContent(fmt.Sprintf("SQL used:\n```sql\n%s\n```\n%s", answer.SQL, answer.Explanation)),
+	}, result.Content...)
+	return result, nil
+}
+
+// generateSQL asks the provider for SQL and validates it, feeding validation errors back to the provider
+func (s *MCPServer) generateSQL(ctx context.Context, question string, schema string) (*askAnswer, error) {
+	messages := []providers.Message{{
+		Role:    providers.UserRole,
+		Content: []providers.ContentBlock{&providers.ContentBlockText{Value: question}},
+	}}
+	var lastErr error
+	for attempt := 0; attempt < s.asker.config.MaxAttempts; attempt++ {
+		resp, err := s.asker.provider.Chat(ctx, &providers.ConversationRequest{
+			ModelId:      s.asker.config.Model,
+			System:       fmt.Sprintf(askSystemPrompt, s.connector.Config().Type(), schema),
+			Messages:     messages,
+			MaxTokens:    s.asker.config.MaxTokens,
+			Temperature:  s.asker.config.Temperature,
+			JsonResponse: true,
+		})
+		if err != nil {
+			return nil, xerrors.Errorf("unable to call provider: %w", err)
+		}
+		var raw strings.Builder
+		for _, block := range resp.Content {
+			if text, ok := block.(*providers.ContentBlockText); ok {
+				raw.WriteString(text.Value)
+			}
+		}
+		messages = append(messages, providers.Message{
+			Role:    providers.AssistantRole,
+			Content: []providers.ContentBlock{&providers.ContentBlockText{Value: raw.String()}},
+		})
+
+		var answer askAnswer
+		if err := json.Unmarshal([]byte(providers.ExtractJSON(raw.String())), &answer); err != nil {
+			lastErr = xerrors.Errorf("unable to parse provider response: %w", err)
+		} else if sql, err := s.validateAskSQL(ctx, answer.SQL); err != nil {
+			lastErr = err
+		} else {
+			answer.SQL = sql
+			return &answer, nil
+		}
+		messages = append(messages, providers.Message{
+			Role: providers.UserRole,
+			Content: []providers.ContentBlock{&providers.ContentBlockText{
+				Value: fmt.Sprintf("The query is invalid: %s\nFix it and respond with the same JSON structure.", lastErr),
+			}},
+		})
+	}
+	return nil, xerrors.Errorf("no valid query after %v attempt-(s): %w", s.asker.config.MaxAttempts, lastErr)
+}
+
+// validateAskSQL makes sure generated SQL is a single read-only statement that passes the guard
+// and the database accepts, it returns the query to execute
+func (s *MCPServer) validateAskSQL(ctx context.Context, sql string) (string, error) {
+	sql = strings.TrimSpace(sql)
+	if sql == "" {
+		return "", xerrors.New("empty query")
+	}
+	if !connectors.ReadStatement(sql) {
+		return "", xerrors.Errorf("only read-only SELECT queries are allowed, got %s", connectors.LeadingKeyword(sql))
+	}
+	if strings.Contains(strings.TrimSuffix(sql, ";"), ";") {
+		return "", xerrors.New("only a single statement is allowed")
+	}
+	sql, err := s.guard.Check(ctx, sql)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.connector.InferQuery(ctx, sql); err != nil {
+		return "", xerrors.Errorf("unable to infer query: %w", err)
+	}
+	return sql, nil
+}
+
+// askSchema returns cached schema context with table structures and samples
+func (s *MCPServer) askSchema(ctx context.Context) (string, error) {
+	s.asker.mu.Lock()
+	defer s.asker.mu.Unlock()
+	if s.asker.schema != "" && time.Since(s.asker.schemaTime) < s.asker.config.SchemaCacheTTL {
+		return s.asker.schema, nil
+	}
+
+	tables, err := s.connector.Discovery(ctx, s.asker.config.Tables)
+	if err != nil {
+		return "", xerrors.Errorf("unable to discover data: %w", err)
+	}
+	tables = s.guard.Tables(tables)
+	// the schema is cached for all callers, so samples are intercepted as for an anonymous one
+	sampleCtx := xcontext.WithClaims(xcontext.WithHeader(ctx, map[string][]string{}), map[string]any{})
+	var tablesData []prompter.TableData
+	for _, table := range tables {
+		sample, err := s.connector.Sample(ctx, table)
+		if err != nil {
+			return "", xerrors.Errorf("unable to discover sample: %w", err)
+		}
+		// samples leave the gateway, so they pass through the same interceptors as results
+		filtered, err := plugins.Intercept(sampleCtx, s.interceptors, plugins.Call{}, s.guard.Sample(table, sample))
+		if err != nil {
+			return "", xerrors.Errorf("unable to process sample: %w", err)
+		}
+		tablesData = append(tablesData, prompter.TableData{
+			Columns:  table.Columns,
+			Name:     table.Name,
+			Sample:   filtered,
+			RowCount: table.RowCount,
+		})
+	}
+	s.asker.schema = prompter.TablesPrompt(tablesData, prompter.SchemaFromConfig(s.connector.Config()))
+	s.asker.schemaTime = time.Now()
+	return s.asker.schema, nil
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
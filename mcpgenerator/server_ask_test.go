@@ -0,0 +1,138 @@
+package mcpgenerator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/centralmind/gateway/mcp"
+	"github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testProvider replies with scripted answers in order and records the conversations it got
+type testProvider struct {
+	providers.ModelProvider
+	answers  []string
+	requests []*providers.ConversationRequest
+}
+
+func (p *testProvider) Chat(_ context.Context, req *providers.ConversationRequest) (*providers.ConversationResponse, error) {
+	p.requests = append(p.requests, req)
+	if len(p.requests) > len(p.answers) {
+		return nil, fmt.Errorf("no more answers")
+	}
+	return &providers.ConversationResponse{
+		Content: []providers.ContentBlock{&providers.ContentBlockText{Value: p.answers[len(p.requests)-1]}},
+	}, nil
+}
+
+func newAskServer(t *testing.T, raw model.RawParams, answers ...string) (*MCPServer, *testConnector, *testProvider) {
+	s, connector := newTestServer(t, raw)
+	provider := &testProvider{answers: answers}
+	s.asker = &asker{
+		config:   model.AskParams{MaxAttempts: 3, SchemaCacheTTL: time.Minute},
+		provider: provider,
+	}
+	return s, connector, provider
+}
+
+func TestValidateAskSQL(t *testing.T) {
+	s, _, _ := newAskServer(t, model.RawParams{DeniedTables: []string{"secrets"}, DefaultLimit: 10})
+
+	sql, err := s.validateAskSQL(context.Background(), "  SELECT id FROM users;  ")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id FROM users LIMIT 10", sql)
+
+	for query, msg := range map[string]string{
+		"":                                       "empty query",
+		"DELETE FROM users":                      "only read-only SELECT queries are allowed, got DELETE",
+		"SELECT 1; SELECT 2":                     "only a single statement is allowed",
+		"SELECT token FROM secrets":              "query rejected",
+		"SELECT missing FROM users":              "unable to infer query",
+		"WITH d AS (DELETE FROM users) SELECT 1": "DELETE is not allowed",
+	} {
+		_, err := s.validateAskSQL(context.Background(), query)
+		require.Error(t, err, query)
+		assert.Contains(t, err.Error(), msg, query)
+	}
+}
+
+func TestGenerateSQLRetries(t *testing.T) {
+	s, _, provider := newAskServer(t, model.RawParams{},
+		"not json",
+		`{"sql": "SELECT missing FROM users"}`,
+		"```json\n{\"sql\": \"SELECT id FROM users\", \"explanation\": \"all ids\"}\n```",
+	)
+
+	answer, err := s.generateSQL(context.Background(), "which users exist?", "schema")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id FROM users", answer.SQL)
+	assert.Equal(t, "all ids", answer.Explanation)
+
+	require.Len(t, provider.requests, 3)
+	assert.Contains(t, provider.requests[0].System, "postgres")
+	assert.Contains(t, provider.requests[0].System, "schema")
+	assert.True(t, provider.requests[0].JsonResponse)
+	// every retry carries the conversation so far with the validation error as feedback
+	last := provider.requests[2].Messages
+	require.Len(t, last, 5)
+	assert.Equal(t, "which users exist?", last[0].Content[0].(*providers.ContentBlockText).Value)
+	assert.Equal(t, providers.AssistantRole, last[1].Role)
+	assert.Contains(t, last[2].Content[0].(*providers.ContentBlockText).Value, "The query is invalid: unable to parse provider response")
+	assert.Contains(t, last[4].Content[0].(*providers.ContentBlockText).Value, `column "missing" does not exist`)
+}
+
+func TestGenerateSQLGivesUp(t *testing.T) {
+	s, _, provider := newAskServer(t, model.RawParams{},
+		`{"sql": "DELETE FROM users"}`,
+		`{"sql": "DELETE FROM users"}`,
+		`{"sql": "DELETE FROM users"}`,
+		`{"sql": "SELECT id FROM users"}`,
+	)
+
+	_, err := s.generateSQL(context.Background(), "remove users", "schema")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no valid query after 3 attempt-(s)")
+	assert.Contains(t, err.Error(), "got DELETE")
+	assert.Len(t, provider.requests, 3)
+}
+
+func TestAsk(t *testing.T) {
+	s, connector, provider := newAskServer(t, model.RawParams{DeniedTables: []string{"secrets"}},
+		`{"sql": "SELECT id FROM users", "explanation": "all ids"}`,
+	)
+
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]any{"question": "which users exist?"}
+	res, err := s.ask(context.Background(), request)
+	require.NoError(t, err)
+	require.False(t, res.IsError)
+
+	assert.Equal(t, []string{"SELECT id FROM users"}, connector.queries)
+	assert.Equal(t, "SQL used:\n```sql\nSELECT id FROM users\n```\nall ids", res.Content[0].(mcp.TextContent).Text)
+	assert.Contains(t, res.Content[1].(mcp.TextContent).Text, "Found 2 records-(s).")
+	// denied tables are not offered to the provider
+	assert.Contains(t, provider.requests[0].System, "<public.users")
+	assert.NotContains(t, provider.requests[0].System, "public.secrets")
+}
+
+func TestAskErrors(t *testing.T) {
+	s, connector, _ := newAskServer(t, model.RawParams{}, "not json", "not json", "not json")
+
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]any{"question": " "}
+	res, err := s.ask(context.Background(), request)
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+
+	request.Params.Arguments = map[string]any{"question": "which users exist?"}
+	res, err = s.ask(context.Background(), request)
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+	assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "Unable to answer the question")
+	assert.Empty(t, connector.queries)
+}
@@ -2,11 +2,14 @@ package mcpgenerator
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
+	gw_errors "github.com/centralmind/gateway/errors"
 	"github.com/centralmind/gateway/mcp"
 	"github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/plugins"
 	"github.com/centralmind/gateway/prompter"
 	"github.com/centralmind/gateway/xcontext"
 	"golang.org/x/xerrors"
@@ -41,56 +44,79 @@ This tool shall be executed before query, to examine output structure and verify
 		"query",
 		mcp.WithDescription(fmt.Sprintf("Query data structure for connected %s gateway", s.connector.Config().Type())),
 		mcp.WithString("query", mcp.Required()),
+		mcp.WithBoolean(summarizeArgument, mcp.Description("Ask your model to summarize the result set, useful for large results. Requires client sampling support.")),
+		cursorOption(s.budget),
 	), s.query)
 }
 
 func (s *MCPServer) query(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	offset, err := popCursor(request.Params.Arguments)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	summarize, _ := request.Params.Arguments[summarizeArgument].(bool)
+	delete(request.Params.Arguments, summarizeArgument)
+	query, err := s.guard.Check(ctx, request.Params.Arguments["query"].(string))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	ctx, stats := xcontext.WithQueryStats(ctx)
 	resData, err := s.connector.Query(
 		ctx,
-		model.Endpoint{Query: request.Params.Arguments["query"].(string)},
+		model.Endpoint{Query: query},
 		make(map[string]any),
 	)
 	if err != nil {
-		return nil, xerrors.Errorf("unable to infer query: %w", err)
+		if isLimitError(err) {
+			// the query is valid, rewriting it won't help
+			return mcp.NewToolResultError(err.Error() + "." + costNote(stats)), nil
+		}
+		if !s.server.ClientSupportsSampling(ctx) {
+			return nil, xerrors.Errorf("unable to infer query: %w", err)
+		}
+		msg := fmt.Sprintf("Unable to query: %s", err)
+		if fixed, fixErr := s.suggestQueryFix(ctx, query, err); fixErr == nil {
+			msg += fmt.Sprintf("\nSuggested fix, verify it before running:\n```sql\n%s\n```", fixed)
+		}
+		return mcp.NewToolResultError(msg), nil
 	}
 
-	var res []map[string]interface{}
-MAIN:
-	for _, row := range resData {
-		for _, interceptor := range s.interceptors {
-			r, skip := interceptor.Process(row, xcontext.Headers(ctx))
-			if skip {
-				continue MAIN
-			}
-			row = r
-		}
-		res = append(res, row)
+	res, err := plugins.Intercept(ctx, s.interceptors, plugins.Call{Endpoint: model.Endpoint{Query: query}}, resData)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Unable to process result: %s", err)), nil
 	}
-	var content []mcp.Content
-	content = append(content, mcp.TextContent{
-		Type: "text",
-		Text: fmt.Sprintf("Found %v records-(s).", len(res)),
-	})
-	for _, record := range res {
-		content = append(content, mcp.TextContent{
-			Type: "text",
-			Text: prompter.Yamlify(record),
-		})
+	result := renderResult(
+		"query",
+		fmt.Sprintf("Found %v records-(s).%s%s", len(res), costNote(stats), metaNote(stats)),
+		res,
+		offset,
+		s.budget,
+		prompter.Yamlify,
+	)
+	if summarize && offset == 0 && s.server.ClientSupportsSampling(ctx) {
+		summary, err := s.summarizeResult(ctx, query, res)
+		if err != nil {
+			summary = fmt.Sprintf("Summary is not available: %s", err)
+		}
+		result.Content = append([]mcp.Content{mcp.NewTextContent(summary)}, result.Content...)
 	}
-	return &mcp.CallToolResult{
-		Content: content,
-	}, nil
+	return result, nil
 }
 
 func (s *MCPServer) prepareQuery(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	resSchema, err := s.connector.InferQuery(ctx, request.Params.Arguments["query"].(string))
+	query, err := s.guard.Check(ctx, request.Params.Arguments["query"].(string))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	ctx, stats := xcontext.WithQueryStats(ctx)
+	resSchema, err := s.connector.InferQuery(ctx, query)
 	if err != nil {
 		return nil, xerrors.Errorf("unable to infer query: %w", err)
 	}
 	var content []mcp.Content
 	content = append(content, mcp.TextContent{
 		Type: "text",
-		Text: fmt.Sprintf("Query has a %v column-(s).", len(resSchema)),
+		Text: fmt.Sprintf("Query has a %v column-(s).%s", len(resSchema), costNote(stats)),
 	})
 	content = append(content, mcp.TextContent{
 		Type: "text",
@@ -106,6 +132,7 @@ func (s *MCPServer) discoverData(ctx context.Context, request mcp.CallToolReques
 	if err != nil {
 		return nil, xerrors.Errorf("unable to discover data: %w", err)
 	}
+	data = s.guard.Tables(data)
 	var content []mcp.Content
 	content = append(content, mcp.TextContent{
 		Type: "text",
@@ -115,6 +142,7 @@ func (s *MCPServer) discoverData(ctx context.Context, request mcp.CallToolReques
 	if err != nil {
 		return nil, xerrors.Errorf("unable to discover all tables: %w", err)
 	}
+	allTables = s.guard.Tables(allTables)
 
 	tablesList, _ := request.Params.Arguments["tables_list"].(string)
 
@@ -140,7 +168,7 @@ func (s *MCPServer) discoverData(ctx context.Context, request mcp.CallToolReques
 		tablesToGenerate = append(tablesToGenerate, prompter.TableData{
 			Columns:  table.Columns,
 			Name:     table.Name,
-			Sample:   sample,
+			Sample:   s.guard.Sample(table, sample),
 			RowCount: table.RowCount,
 		})
 	}
@@ -160,6 +188,7 @@ func (s *MCPServer) listTables(ctx context.Context, request mcp.CallToolRequest)
 	if err != nil {
 		return nil, xerrors.Errorf("unable to discover data: %w", err)
 	}
+	data = s.guard.Tables(data)
 
 	var content []mcp.Content
 	content = append(content, mcp.TextContent{
@@ -180,3 +209,11 @@ func (s *MCPServer) listTables(ctx context.Context, request mcp.CallToolRequest)
 		Content: content,
 	}, nil
 }
+
+// isLimitError reports whether a query was stopped by endpoint limits
+func isLimitError(err error) bool {
+	return errors.Is(err, gw_errors.ErrTimeout) ||
+		errors.Is(err, gw_errors.ErrTooManyRows) ||
+		errors.Is(err, gw_errors.ErrTooManyRequests) ||
+		errors.Is(err, gw_errors.ErrTooExpensive)
+}
@@ -0,0 +1,91 @@
+package mcpgenerator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/centralmind/gateway/mcp"
+	"github.com/centralmind/gateway/providers"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// samplingTimeout bounds how long a tool waits for the client's model, which may ask the user for approval
+	samplingTimeout = 2 * time.Minute
+	// samplingMaxBytes caps the amount of result data sent to the client's model
+	samplingMaxBytes = 64 * 1024
+	// summarizeArgument asks the query tool to summarize results with the client's model
+	summarizeArgument = "summarize"
+)
+
+// sample sends a single-turn prompt to the client's model through MCP sampling
+func (s *MCPServer) sample(ctx context.Context, system string, prompt string, maxTokens int) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, samplingTimeout)
+	defer cancel()
+
+	var request mcp.CreateMessageRequest
+	request.Params.SystemPrompt = system
+	request.Params.MaxTokens = maxTokens
+	request.Params.Messages = []mcp.SamplingMessage{{
+		Role:    mcp.RoleUser,
+		Content: mcp.NewTextContent(prompt),
+	}}
+	res, err := s.server.RequestSampling(ctx, request)
+	if err != nil {
+		return "", xerrors.Errorf("unable to sample client model: %w", err)
+	}
+	text, ok := res.Content.(mcp.TextContent)
+	if !ok {
+		return "", xerrors.Errorf("client model returned %T instead of text", res.Content)
+	}
+	return text.Text, nil
+}
+
+// summarizeResult asks the client's model to summarize a (possibly large) result set
+func (s *MCPServer) summarizeResult(ctx context.Context, query string, rows []map[string]any) (string, error) {
+	var data strings.Builder
+	included := 0
+	for _, row := range rows {
+		encoded := jsonify(row)
+		if data.Len()+len(encoded) > samplingMaxBytes {
+			break
+		}
+		data.WriteString(encoded)
+		data.WriteString("\n")
+		included++
+	}
+	return s.sample(
+		ctx,
+		"You are a data analyst. Summarize query results concisely: key figures, trends, outliers and anything notable. Do not repeat rows verbatim.",
+		fmt.Sprintf("Query:\n%s\n\nResult has %v row-(s), first %v included below as JSON lines:\n%s", query, len(rows), included, data.String()),
+		1024,
+	)
+}
+
+// suggestQueryFix asks the client's model to repair a failed query, the suggestion is validated before returning
+func (s *MCPServer) suggestQueryFix(ctx context.Context, query string, queryErr error) (string, error) {
+	answer, err := s.sample(
+		ctx,
+		fmt.Sprintf("You fix SQL queries for %s database. Respond only with a single JSON object: {\"sql\": \"<fixed query>\"}.", s.connector.Config().Type()),
+		fmt.Sprintf("Query:\n%s\n\nFailed with error:\n%s", query, queryErr),
+		1024,
+	)
+	if err != nil {
+		return "", err
+	}
+	var fixed askAnswer
+	if err := json.Unmarshal([]byte(providers.ExtractJSON(answer)), &fixed); err != nil {
+		return "", xerrors.Errorf("unable to parse suggested query: %w", err)
+	}
+	sql, err := s.guard.Check(ctx, fixed.SQL)
+	if err != nil {
+		return "", xerrors.Errorf("suggested query is not allowed: %w", err)
+	}
+	if _, err := s.connector.InferQuery(ctx, sql); err != nil {
+		return "", xerrors.Errorf("suggested query is invalid too: %w", err)
+	}
+	return sql, nil
+}
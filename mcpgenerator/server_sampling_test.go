@@ -0,0 +1,178 @@
+package mcpgenerator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centralmind/gateway/connectors"
+	"github.com/centralmind/gateway/mcp"
+	"github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/server"
+	"github.com/centralmind/gateway/sqlguard"
+	"github.com/centralmind/gateway/xcontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct{}
+
+func (testConfig) Type() string          { return "postgres" }
+func (testConfig) Doc() string           { return "" }
+func (testConfig) ExtraPrompt() []string { return nil }
+func (testConfig) Readonly() bool        { return true }
+
+// testConnector has users and secrets tables and rejects queries with a missing column
+type testConnector struct {
+	connectors.Connector
+	queries []string
+}
+
+func (c *testConnector) Config() connectors.Config { return testConfig{} }
+
+func (c *testConnector) InferQuery(_ context.Context, query string) ([]model.ColumnSchema, error) {
+	if strings.Contains(query, "missing") {
+		return nil, fmt.Errorf(`column "missing" does not exist`)
+	}
+	return []model.ColumnSchema{{Name: "id", Type: model.TypeInteger}}, nil
+}
+
+func (c *testConnector) Query(_ context.Context, endpoint model.Endpoint, _ map[string]any) ([]map[string]any, error) {
+	c.queries = append(c.queries, endpoint.Query)
+	return []map[string]any{{"id": 1}, {"id": 2}}, nil
+}
+
+func (c *testConnector) Discovery(context.Context, []string) ([]model.Table, error) {
+	return []model.Table{
+		{Name: "public.users", Columns: []model.ColumnSchema{{Name: "id", Type: model.TypeInteger}}},
+		{Name: "public.secrets", Columns: []model.ColumnSchema{{Name: "token", Type: model.TypeString}}},
+	}, nil
+}
+
+func (c *testConnector) Sample(context.Context, model.Table) ([]map[string]any, error) {
+	return []map[string]any{{"id": 1}}, nil
+}
+
+func newTestServer(t *testing.T, raw model.RawParams) (*MCPServer, *testConnector) {
+	connector := &testConnector{}
+	guard, err := sqlguard.New(raw, connector)
+	require.NoError(t, err)
+	return &MCPServer{
+		server:    server.NewMCPServer("test", "1.0.0"),
+		connector: connector,
+		guard:     guard,
+	}, connector
+}
+
+// samplingPrompt is what the fake client received in a sampling request
+type samplingPrompt struct {
+	SystemPrompt string `json:"systemPrompt"`
+	Messages     []struct {
+		Content mcp.TextContent `json:"content"`
+	} `json:"messages"`
+}
+
+// withSamplingClient connects a fake client that answers sampling requests with the given text
+// and remembers the prompts it received
+func withSamplingClient(t *testing.T, s *MCPServer, answer string) (context.Context, *[]samplingPrompt) {
+	ctx := xcontext.WithSession(context.Background(), "session-1")
+	var prompts []samplingPrompt
+	s.server.RegisterSession("session-1", func(message mcp.JSONRPCMessage) error {
+		request := message.(mcp.JSONRPCRequest)
+		assert.Equal(t, "sampling/createMessage", request.Method)
+		var prompt samplingPrompt
+		raw, _ := json.Marshal(request.Params)
+		assert.NoError(t, json.Unmarshal(raw, &prompt))
+		prompts = append(prompts, prompt)
+		go s.server.HandleMessage(ctx, []byte(fmt.Sprintf(`{
+          "jsonrpc": "2.0",
+          "id": %q,
+          "result": {"role": "assistant", "model": "test-model", "content": {"type": "text", "text": %q}}
+        }`, request.ID, answer)))
+		return nil
+	})
+	s.server.HandleMessage(ctx, []byte(`{
+      "jsonrpc": "2.0",
+      "id": 1,
+      "method": "initialize",
+      "params": {"capabilities": {"sampling": {}}}
+    }`))
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	t.Cleanup(cancel)
+	return ctx, &prompts
+}
+
+func TestSummarizeResult(t *testing.T) {
+	s, _ := newTestServer(t, model.RawParams{})
+	ctx, prompts := withSamplingClient(t, s, "two users")
+
+	summary, err := s.summarizeResult(ctx, "SELECT id FROM users", testRows(3))
+	require.NoError(t, err)
+	assert.Equal(t, "two users", summary)
+
+	require.Len(t, *prompts, 1)
+	prompt := (*prompts)[0].Messages[0].Content.Text
+	assert.Contains(t, prompt, "SELECT id FROM users")
+	assert.Contains(t, prompt, "Result has 3 row-(s), first 3 included")
+	assert.Contains(t, prompt, `{"id":2}`)
+}
+
+func TestSummarizeResultTruncates(t *testing.T) {
+	s, _ := newTestServer(t, model.RawParams{})
+	ctx, prompts := withSamplingClient(t, s, "many rows")
+
+	rows := testRows(samplingMaxBytes)
+	_, err := s.summarizeResult(ctx, "SELECT id FROM users", rows)
+	require.NoError(t, err)
+
+	prompt := (*prompts)[0].Messages[0].Content.Text
+	assert.Contains(t, prompt, fmt.Sprintf("Result has %v row-(s)", len(rows)))
+	assert.Less(t, len(prompt), samplingMaxBytes+1024)
+}
+
+func TestSummarizeResultWithoutSampling(t *testing.T) {
+	s, _ := newTestServer(t, model.RawParams{})
+	ctx := xcontext.WithSession(context.Background(), "session-1")
+
+	_, err := s.summarizeResult(ctx, "SELECT id FROM users", testRows(1))
+	require.Error(t, err)
+}
+
+func TestSuggestQueryFix(t *testing.T) {
+	s, _ := newTestServer(t, model.RawParams{DefaultLimit: 10})
+	ctx, prompts := withSamplingClient(t, s, "```json\n{\"sql\": \"SELECT id FROM users\"}\n```")
+
+	sql, err := s.suggestQueryFix(ctx, "SELECT missing FROM users", fmt.Errorf(`column "missing" does not exist`))
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id FROM users LIMIT 10", sql)
+
+	require.Len(t, *prompts, 1)
+	assert.Contains(t, (*prompts)[0].SystemPrompt, "postgres")
+	prompt := (*prompts)[0].Messages[0].Content.Text
+	assert.Contains(t, prompt, "SELECT missing FROM users")
+	assert.Contains(t, prompt, `column "missing" does not exist`)
+}
+
+func TestSuggestQueryFixRejected(t *testing.T) {
+	for name, tc := range map[string]struct {
+		answer string
+		err    string
+	}{
+		"not json":      {answer: "SELECT id FROM users", err: "unable to parse suggested query"},
+		"write":         {answer: `{"sql": "DELETE FROM users"}`, err: "suggested query is not allowed"},
+		"denied table":  {answer: `{"sql": "SELECT token FROM secrets"}`, err: "suggested query is not allowed"},
+		"still invalid": {answer: `{"sql": "SELECT missing FROM users"}`, err: "suggested query is invalid too"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s, _ := newTestServer(t, model.RawParams{DeniedTables: []string{"secrets"}})
+			ctx, _ := withSamplingClient(t, s, tc.answer)
+
+			_, err := s.suggestQueryFix(ctx, "SELECT missing FROM users", fmt.Errorf("failed"))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/centralmind/gateway/mcp"
 	"github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/plugins"
 	"github.com/centralmind/gateway/xcontext"
 )
 
@@ -29,6 +30,11 @@ func (s *MCPServer) SetTools(tools []model.Endpoint) {
 				opts = append(opts, ArgumentOption(col))
 			}
 		}
+		opts = append(opts, cursorOption(s.budget.Merge(endpoint.MCPResult)))
+		opts = append(opts, mcp.WithAnnotations(EndpointAnnotations(endpoint)))
+		if description := EndpointDescription(endpoint); description != "" {
+			opts = append(opts, mcp.WithDescription(description))
+		}
 
 		s.server.AddTool(mcp.NewTool(
 			endpoint.MCPMethod,
@@ -39,57 +45,68 @@ func (s *MCPServer) SetTools(tools []model.Endpoint) {
 }
 
 func (s *MCPServer) endpoint(endpoint model.Endpoint) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	budget := s.budget.Merge(endpoint.MCPResult)
+	hasCursorParam := false
+	for _, param := range endpoint.Params {
+		if param.Name == cursorArgument {
+			hasCursorParam = true
+		}
+	}
+	interceptors, interceptorsErr := s.scope.Interceptors(endpoint)
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if interceptorsErr != nil {
+			return mcp.NewToolResultError(interceptorsErr.Error()), nil
+		}
+		if request.Params.Arguments == nil {
+			request.Params.Arguments = map[string]any{}
+		}
 		arg := request.Params.Arguments
+		offset := 0
+		if budget.Limited() && !hasCursorParam {
+			var err error
+			offset, err = popCursor(arg)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+		}
 		for _, param := range endpoint.Params {
 			if _, ok := arg[param.Name]; !ok {
 				arg[param.Name] = nil
 			}
 		}
+		ctx, stats := xcontext.WithQueryStats(ctx)
 		resData, err := s.connector.Query(ctx, endpoint, request.Params.Arguments)
 		if err != nil {
 			return &mcp.CallToolResult{
 				Content: []mcp.Content{
 					mcp.TextContent{
 						Type: "text",
-						Text: fmt.Sprintf("Unable to query: %s", err),
+						Text: fmt.Sprintf("Unable to query: %s.%s", err, costNote(stats)),
 					},
 				},
 				IsError: true,
 			}, nil
 		}
-		var res []map[string]interface{}
-	MAIN:
-		for _, row := range resData {
-			for _, interceptor := range s.interceptors {
-				r, skip := interceptor.Process(row, xcontext.Headers(ctx))
-				if skip {
-					continue MAIN
-				}
-				row = r
-			}
-			res = append(res, row)
-		}
-		var content []mcp.Content
-		content = append(content, mcp.TextContent{
-			Type: "text",
-			Text: fmt.Sprintf("Found a %v row-(s) in %s.", len(res), endpoint.Group),
-		})
-		for _, row := range res {
-			content = append(content, mcp.TextContent{
-				Type: "text",
-				Text: jsonify(row),
-			})
+		res, err := plugins.Intercept(ctx, interceptors, plugins.Call{Endpoint: endpoint, Params: request.Params.Arguments}, resData)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Unable to process result: %s", err)), nil
 		}
-
-		return &mcp.CallToolResult{
-			Content: content,
-		}, nil
+		return renderResult(
+			endpoint.MCPMethod,
+			fmt.Sprintf("Found a %v row-(s) in %s.%s%s", len(res), endpoint.Group, costNote(stats), metaNote(stats)),
+			res,
+			offset,
+			budget,
+			jsonify,
+		), nil
 	}
 }
 
 func ArgumentOption(col model.EndpointParams, opts ...mcp.PropertyOption) mcp.ToolOption {
 	opts = append(opts, mcp.Title(fmt.Sprintf("Column %s", col.Name)))
+	if col.Description != "" {
+		opts = append(opts, mcp.Description(col.Description))
+	}
 	opts = append(opts, func(m map[string]interface{}) {
 		m["default"] = col.Default
 	})
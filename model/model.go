@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"strings"
+	"time"
 
 	"golang.org/x/xerrors"
 	"gopkg.in/yaml.v3"
@@ -77,6 +78,8 @@ func (ct *ColumnType) UnmarshalJSON(data []byte) error {
 type Config struct {
 	API      APIParams      `yaml:"api" json:"api"`
 	Database Database       `yaml:"database" json:"database"`
+	MCP      MCPParams      `yaml:"mcp" json:"mcp,omitempty"`
+	Raw      RawParams      `yaml:"raw" json:"raw,omitempty"`
 	Plugins  map[string]any `yaml:"plugins" json:"plugins"`
 }
 
@@ -139,6 +142,16 @@ func expandEnvInConfig(cfg *Config) {
 	for k, v := range cfg.Plugins {
 		cfg.Plugins[k] = processAnyField(v)
 	}
+	for _, group := range cfg.Database.Groups {
+		for k, v := range group.Plugins {
+			group.Plugins[k] = processAnyField(v)
+		}
+	}
+	for _, endpoint := range cfg.Database.Endpoints {
+		for k, v := range endpoint.Plugins {
+			endpoint.Plugins[k] = processAnyField(v)
+		}
+	}
 }
 
 // processAnyField recursively processes any field, expanding environment variables in strings
@@ -196,10 +209,132 @@ type APIParams struct {
 	Version     string `yaml:"version" json:"version,omitempty"`
 }
 
+// RawParams holds guardrails for raw mode, where callers send their own SQL.
+// Table and column entries are case-insensitive and may be qualified, e.g. "users", "public.users" or "users.email".
+type RawParams struct {
+	AllowedTables  []string `yaml:"allowed_tables" json:"allowed_tables,omitempty"`
+	DeniedTables   []string `yaml:"denied_tables" json:"denied_tables,omitempty"`
+	AllowedColumns []string `yaml:"allowed_columns" json:"allowed_columns,omitempty"`
+	DeniedColumns  []string `yaml:"denied_columns" json:"denied_columns,omitempty"`
+	// DefaultLimit is added to queries without their own LIMIT, zero disables it
+	DefaultLimit int `yaml:"default_limit" json:"default_limit,omitempty"`
+	// MaxCost rejects queries whose EXPLAIN estimate exceeds it, units depend on the database
+	MaxCost float64 `yaml:"max_cost" json:"max_cost,omitempty"`
+}
+
+// MCPParams holds settings that only affect the MCP protocol
+type MCPParams struct {
+	// Result is the default result budget for every MCP tool, endpoints may override it
+	Result ResultBudget `yaml:"result" json:"result,omitempty"`
+	// Ask enables the natural-language ask_database tool when set
+	Ask *AskParams `yaml:"ask,omitempty" json:"ask,omitempty"`
+}
+
+// AskParams configures the AI provider used by the ask_database tool to turn questions into SQL
+type AskParams struct {
+	Provider        string  `yaml:"provider" json:"provider,omitempty"`
+	Model           string  `yaml:"model" json:"model,omitempty"`
+	Endpoint        string  `yaml:"endpoint" json:"endpoint,omitempty"`
+	APIKey          string  `yaml:"api_key" json:"api_key,omitempty"`
+	BedrockRegion   string  `yaml:"bedrock_region" json:"bedrock_region,omitempty"`
+	VertexAIRegion  string  `yaml:"vertexai_region" json:"vertexai_region,omitempty"`
+	VertexAIProject string  `yaml:"vertexai_project" json:"vertexai_project,omitempty"`
+	MaxTokens       int     `yaml:"max_tokens" json:"max_tokens,omitempty"`
+	Temperature     float32 `yaml:"temperature" json:"temperature,omitempty"`
+	// Tables limits the schema context sent to the provider, all tables are used if empty
+	Tables []string `yaml:"tables" json:"tables,omitempty"`
+	// MaxAttempts is how many times the provider may retry after the generated SQL fails validation
+	MaxAttempts int `yaml:"max_attempts" json:"max_attempts,omitempty"`
+	// SchemaCacheTTL controls how long discovered schema context is reused, e.g. "10m"
+	SchemaCacheTTL time.Duration `yaml:"schema_cache_ttl" json:"schema_cache_ttl,omitempty"`
+}
+
+// ResultFormat controls how rows are rendered into MCP tool result content
+type ResultFormat string
+
+const (
+	// ResultFormatRows renders one text content block per row
+	ResultFormatRows ResultFormat = "rows"
+	// ResultFormatJSON renders all rows as a single JSON array text block
+	ResultFormatJSON ResultFormat = "json"
+	// ResultFormatResource renders all rows as a single embedded JSON resource
+	ResultFormatResource ResultFormat = "resource"
+)
+
+// ResultBudget limits how much data a single MCP tool call returns to the agent.
+// Zero values mean no limit.
+type ResultBudget struct {
+	MaxRows   int          `yaml:"max_rows" json:"max_rows,omitempty"`
+	MaxBytes  int          `yaml:"max_bytes" json:"max_bytes,omitempty"`
+	MaxTokens int          `yaml:"max_tokens" json:"max_tokens,omitempty"` // estimated as bytes / 4
+	Format    ResultFormat `yaml:"format" json:"format,omitempty"`
+}
+
+// Limited reports whether the budget truncates results at all
+func (b ResultBudget) Limited() bool {
+	return b.MaxRows > 0 || b.MaxBytes > 0 || b.MaxTokens > 0
+}
+
+// Merge returns a copy of the budget with all non-zero fields of override applied
+func (b ResultBudget) Merge(override *ResultBudget) ResultBudget {
+	if override == nil {
+		return b
+	}
+	if override.MaxRows > 0 {
+		b.MaxRows = override.MaxRows
+	}
+	if override.MaxBytes > 0 {
+		b.MaxBytes = override.MaxBytes
+	}
+	if override.MaxTokens > 0 {
+		b.MaxTokens = override.MaxTokens
+	}
+	if override.Format != "" {
+		b.Format = override.Format
+	}
+	return b
+}
+
 type Database struct {
 	Type       string     `yaml:"type" json:"type,omitempty"`
 	Connection any        `yaml:"connection" json:"connection,omitempty"`
 	Endpoints  []Endpoint `yaml:"endpoints" json:"endpoints,omitempty"`
+	// Limits are defaults for every query, including raw ones, endpoints may override them
+	Limits QueryLimits `yaml:"limits" json:"limits,omitempty"`
+	// Groups configure endpoints sharing the group name
+	Groups map[string]EndpointGroup `yaml:"groups,omitempty" json:"groups,omitempty"`
+}
+
+// EndpointGroup holds settings shared by endpoints of a group
+type EndpointGroup struct {
+	// Plugins override global plugins for endpoints of the group
+	Plugins map[string]any `yaml:"plugins,omitempty" json:"plugins,omitempty"`
+}
+
+// QueryLimits bound a single query execution, zero values mean no limit
+type QueryLimits struct {
+	Timeout        time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	MaxRows        int           `yaml:"max_rows,omitempty" json:"max_rows,omitempty"`
+	MaxConcurrency int           `yaml:"max_concurrency,omitempty" json:"max_concurrency,omitempty"`
+	// MaxBytesBilled caps bytes scanned by connectors that bill per byte, such as BigQuery
+	MaxBytesBilled int64 `yaml:"maximum_bytes_billed,omitempty" json:"maximum_bytes_billed,omitempty"`
+}
+
+// Merge returns a copy of limits with zero fields taken from defaults
+func (l QueryLimits) Merge(defaults QueryLimits) QueryLimits {
+	if l.Timeout == 0 {
+		l.Timeout = defaults.Timeout
+	}
+	if l.MaxRows == 0 {
+		l.MaxRows = defaults.MaxRows
+	}
+	if l.MaxConcurrency == 0 {
+		l.MaxConcurrency = defaults.MaxConcurrency
+	}
+	if l.MaxBytesBilled == 0 {
+		l.MaxBytesBilled = defaults.MaxBytesBilled
+	}
+	return l
 }
 
 type Table struct {
@@ -225,15 +360,44 @@ type Endpoint struct {
 	Query         string           `yaml:"query" json:"query,omitempty"`
 	IsArrayResult bool             `yaml:"is_array_result" json:"is_array_result,omitempty"`
 	Params        []EndpointParams `yaml:"params" json:"params,omitempty"`
+	MCPResult     *ResultBudget    `yaml:"mcp_result,omitempty" json:"mcp_result,omitempty"`
+	Annotations   *Annotations     `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+	Consistency   string           `yaml:"consistency,omitempty" json:"consistency,omitempty"` // "primary" keeps the endpoint off read replicas
+	Search        *SearchResult    `yaml:"search,omitempty" json:"search,omitempty"`
+	// Plugins override global and group plugins for the endpoint, false disables a plugin
+	Plugins     map[string]any `yaml:"plugins,omitempty" json:"plugins,omitempty"`
+	QueryLimits `yaml:",inline"`
+}
+
+// SearchResult shapes rows of search engine queries, by default rows are the matched documents
+type SearchResult struct {
+	// Aggregation returns buckets of the named aggregation as rows instead of hits,
+	// nested aggregations are addressed as "parent>child"
+	Aggregation string `yaml:"aggregation,omitempty" json:"aggregation,omitempty"`
+	// Metadata adds _id, _index, _score, _highlight and _total fields to every hit
+	Metadata bool `yaml:"metadata,omitempty" json:"metadata,omitempty"`
+}
+
+// ConsistencyPrimary routes endpoint queries to the primary database even when they only read
+const ConsistencyPrimary = "primary"
+
+// Annotations overrides MCP tool behavior hints that are otherwise inferred
+// from the endpoint HTTP method and query. Nil values keep the inferred hint.
+type Annotations struct {
+	ReadOnly    *bool `yaml:"read_only,omitempty" json:"read_only,omitempty"`
+	Destructive *bool `yaml:"destructive,omitempty" json:"destructive,omitempty"`
+	Idempotent  *bool `yaml:"idempotent,omitempty" json:"idempotent,omitempty"`
+	OpenWorld   *bool `yaml:"open_world,omitempty" json:"open_world,omitempty"`
 }
 
 type EndpointParams struct {
-	Name     string      `yaml:"name" json:"name,omitempty"`
-	Type     string      `yaml:"type" json:"type,omitempty"`
-	Location string      `yaml:"location" json:"location,omitempty"`
-	Required bool        `yaml:"required" json:"required,omitempty"`
-	Format   string      `yaml:"format,omitempty" json:"format,omitempty"`
-	Default  interface{} `yaml:"default,omitempty" json:"default,omitempty"`
+	Name        string      `yaml:"name" json:"name,omitempty"`
+	Type        string      `yaml:"type" json:"type,omitempty"`
+	Location    string      `yaml:"location" json:"location,omitempty"`
+	Required    bool        `yaml:"required" json:"required,omitempty"`
+	Format      string      `yaml:"format,omitempty" json:"format,omitempty"`
+	Default     interface{} `yaml:"default,omitempty" json:"default,omitempty"`
+	Description string      `yaml:"description,omitempty" json:"description,omitempty"`
 }
 
 func FromDSN(dsn string) (*Config, error) {
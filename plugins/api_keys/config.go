@@ -1,5 +1,7 @@
 package api_keys
 
+import "github.com/centralmind/gateway/plugins"
+
 // Config represents API key authentication plugin configuration
 type Config struct {
 	// Name specifies the header or query parameter name for the API key
@@ -20,6 +22,10 @@ func (c Config) Tag() string {
 	return "api_keys"
 }
 
+func (c Config) Order() int {
+	return plugins.OrderAuth
+}
+
 func (c Config) Doc() string {
 	return docString
 }
@@ -32,6 +38,20 @@ type Key struct {
 	// AllowedMethods specifies which HTTP methods this key can use
 	// If empty, all methods are allowed
 	AllowedMethods []string `yaml:"allowed_methods"`
+
+	// Roles are passed to plugins running after authentication as the roles claim,
+	// e.g. to condition column masking
+	Roles []string `yaml:"roles"`
+}
+
+// FindKey returns the key configuration matching the given token
+func (c Config) FindKey(token string) (*Key, bool) {
+	for i := range c.Keys {
+		if c.Keys[i].Key == token {
+			return &c.Keys[i], true
+		}
+	}
+	return nil, false
 }
 
 func (t *Key) Allowed(method string) bool {
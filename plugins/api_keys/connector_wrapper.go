@@ -20,18 +20,29 @@ func (c Connector) Query(ctx context.Context, endpoint model.Endpoint, params ma
 	if authToken == "" {
 		return nil, xerrors.Errorf("empty token: %w", errors.ErrNotAuthorized)
 	}
-	found := false
-	for _, token := range c.config.Keys {
-		if token.Key == authToken {
-			found = true
-			if !token.Allowed(endpoint.MCPMethod) {
-				return nil, xerrors.Errorf("method: %s is not authorized for this token: %w", endpoint.MCPMethod, errors.ErrNotAuthorized)
-			}
-			break
-		}
-	}
+	token, found := c.config.FindKey(authToken)
 	if !found {
 		return nil, xerrors.Errorf("unknown token: %w", errors.ErrNotAuthorized)
 	}
+	if !token.Allowed(endpoint.MCPMethod) {
+		return nil, xerrors.Errorf("method: %s is not authorized for this token: %w", endpoint.MCPMethod, errors.ErrNotAuthorized)
+	}
+	if len(token.Roles) > 0 {
+		ctx = xcontext.WithClaims(ctx, withRoles(xcontext.Claims(ctx), token.Roles))
+	}
 	return c.Connector.Query(ctx, endpoint, params)
 }
+
+// withRoles returns a copy of claims with the roles claim set
+func withRoles(claims map[string]any, roles []string) map[string]any {
+	res := make(map[string]any, len(claims)+1)
+	for k, v := range claims {
+		res[k] = v
+	}
+	list := make([]any, len(roles))
+	for i, role := range roles {
+		list[i] = role
+	}
+	res["roles"] = list
+	return res
+}
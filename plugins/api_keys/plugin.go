@@ -1,7 +1,9 @@
 package api_keys
 
 import (
+	"context"
 	_ "embed"
+	"github.com/centralmind/gateway/xcontext"
 	"github.com/danielgtaylor/huma/v2"
 
 	"github.com/centralmind/gateway/connectors"
@@ -18,6 +20,7 @@ func init() {
 type PluginBundle interface {
 	plugins.Wrapper
 	plugins.Swaggerer
+	plugins.Visibility
 }
 
 func New(cfg Config) (PluginBundle, error) {
@@ -69,6 +72,20 @@ func (p Plugin) Wrap(connector connectors.Connector) (connectors.Connector, erro
 	}, nil
 }
 
+// Visible hides methods that the caller's API key is not allowed to call, and all of them for an unknown key.
+// Callers without a key see every method, so the spec fetched by Swagger UI still lists operations.
+func (p Plugin) Visible(ctx context.Context, method string) bool {
+	key := xcontext.Header(ctx, p.config.Name)
+	if key == "" {
+		return true
+	}
+	token, found := p.config.FindKey(key)
+	if !found {
+		return false
+	}
+	return token.Allowed(method)
+}
+
 func (p Plugin) Doc() string {
 	return docString
 }
@@ -0,0 +1,54 @@
+package columnmasking
+
+import (
+	"github.com/centralmind/gateway/claimrule"
+	"github.com/centralmind/gateway/plugins"
+)
+
+// Config represents column masking configuration
+type Config struct {
+	// Policies are checked in order, the first policy matching a column masks it
+	Policies []Policy `yaml:"policies"`
+}
+
+// Policy masks matching columns for callers not listed in UnmaskedFor
+type Policy struct {
+	// Columns are column name globs, e.g. "email" or "*_phone"
+	Columns []string `yaml:"columns"`
+
+	// Tables limit the policy to queries reading one of the tables, "users" matches "public.users"
+	Tables []string `yaml:"tables"`
+
+	// Endpoints limit the policy to endpoints with the MCP methods
+	Endpoints []string `yaml:"endpoints"`
+
+	// Strategy is one of redact, partial, hash, format, null or date
+	Strategy string `yaml:"strategy"`
+
+	// Replacement is the value of redacted columns, "[REDACTED]" by default
+	Replacement string `yaml:"replacement"`
+
+	// Keep is the number of trailing characters the partial strategy leaves visible, 4 by default
+	Keep int `yaml:"keep"`
+
+	// Salt keys hash and format strategies, so values can't be recovered by hashing guesses
+	Salt string `yaml:"salt"`
+
+	// Precision of the date strategy: year, month (default) or day
+	Precision string `yaml:"precision"`
+
+	// UnmaskedFor lists claim rules of callers who see raw values, a single matching rule is enough
+	UnmaskedFor []claimrule.Rule `yaml:"unmasked_for"`
+}
+
+func (c Config) Tag() string {
+	return "column_masking"
+}
+
+func (c Config) Order() int {
+	return plugins.OrderColumnMasking
+}
+
+func (c Config) Doc() string {
+	return docString
+}
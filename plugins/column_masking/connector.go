@@ -0,0 +1,151 @@
+package columnmasking
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/centralmind/gateway/claimrule"
+	"github.com/centralmind/gateway/connectors"
+	"github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/plugins"
+	"github.com/centralmind/gateway/sqlguard"
+	"github.com/centralmind/gateway/xcontext"
+)
+
+// Connector masks columns of rows returned by the wrapped connector
+type Connector struct {
+	connectors.Connector
+	policies []policy
+	typ      string
+}
+
+// Query masks columns of the result. Raw queries, which have no MCP method or HTTP path, could return
+// values under other names, so they may read masked columns only through a plain * in the select list.
+func (c *Connector) Query(ctx context.Context, endpoint model.Endpoint, params map[string]any) ([]map[string]any, error) {
+	tables, known := sqlguard.Tables(c.typ, endpoint.Query)
+	policies := c.active(ctx, endpoint.MCPMethod, tables, known)
+	if endpoint.MCPMethod == "" && endpoint.HTTPPath == "" {
+		if err := c.checkRaw(policies, endpoint.Query); err != nil {
+			return nil, err
+		}
+	}
+	rows, err := c.Connector.Query(ctx, endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+	return c.mask(ctx, policies, rows), nil
+}
+
+// checkRaw rejects a raw query referring to a column masked for the caller, or one that can't be analyzed
+func (c *Connector) checkRaw(policies []policy, query string) error {
+	masked := false
+	for _, p := range policies {
+		masked = masked || p.mask != nil
+	}
+	if !masked {
+		return nil
+	}
+	columns, renamed, ok := sqlguard.Columns(c.typ, query)
+	if !ok {
+		return fmt.Errorf("%w: query can't be checked for masked columns", sqlguard.ErrRejected)
+	}
+	if renamed {
+		return fmt.Errorf("%w: query may return masked columns under other names, select them with *", sqlguard.ErrRejected)
+	}
+	for _, column := range columns {
+		if p := find(policies, column); p != nil && p.mask != nil {
+			return fmt.Errorf("%w: column %s is masked, select it with *", sqlguard.ErrRejected, column)
+		}
+	}
+	return nil
+}
+
+// Sample is not tied to an endpoint, so only policies without endpoints apply
+func (c *Connector) Sample(ctx context.Context, table model.Table) ([]map[string]any, error) {
+	rows, err := c.Connector.Sample(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	return c.mask(ctx, c.active(ctx, "", []string{table.Name}, true), rows), nil
+}
+
+// active returns policies applying to the call, policies the caller is exempt from keep columns raw.
+// Table conditions hold when tables read by the query are unknown, so a query that can't be analyzed is masked rather than leaked.
+func (c *Connector) active(ctx context.Context, method string, tables []string, known bool) []policy {
+	claims := xcontext.Claims(ctx)
+	var res []policy
+	for _, p := range c.policies {
+		if len(p.Endpoints) > 0 && !contains(p.Endpoints, method) {
+			continue
+		}
+		if len(p.Tables) > 0 && known && !readsAny(tables, p.Tables) {
+			continue
+		}
+		if claimrule.MatchAny(p.UnmaskedFor, claims) {
+			p.mask = nil
+		}
+		res = append(res, p)
+	}
+	return res
+}
+
+// mask returns masked copies of rows and reports the number of masked values as Masked-Values metadata
+func (c *Connector) mask(ctx context.Context, policies []policy, rows []map[string]any) []map[string]any {
+	if len(policies) == 0 {
+		return rows
+	}
+	masked := 0
+	byColumn := map[string]*policy{}
+	res := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		copied := make(map[string]any, len(row))
+		for column, v := range row {
+			p, ok := byColumn[column]
+			if !ok {
+				p = find(policies, column)
+				byColumn[column] = p
+			}
+			if p != nil && p.mask != nil && v != nil {
+				v = p.mask(v)
+				masked++
+			}
+			copied[column] = v
+		}
+		res[i] = copied
+	}
+	if masked > 0 {
+		xcontext.Stats(ctx).SetMeta("Masked-Values", strconv.Itoa(masked))
+	}
+	return res
+}
+
+// find returns the first policy matching column
+func find(policies []policy, column string) *policy {
+	for i := range policies {
+		if plugins.MatchColumn(policies[i].Columns, column) {
+			return &policies[i]
+		}
+	}
+	return nil
+}
+
+func readsAny(tables, policyTables []string) bool {
+	for _, table := range tables {
+		for _, policyTable := range policyTables {
+			if sqlguard.MatchTable(policyTable, table) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
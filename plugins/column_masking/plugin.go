@@ -0,0 +1,61 @@
+package columnmasking
+
+import (
+	_ "embed"
+
+	"github.com/centralmind/gateway/connectors"
+	"github.com/centralmind/gateway/plugins"
+	"golang.org/x/xerrors"
+)
+
+//go:embed README.md
+var docString string
+
+func init() {
+	plugins.Register(func(cfg Config) (plugins.Wrapper, error) {
+		return New(cfg)
+	})
+}
+
+type Plugin struct {
+	policies []policy
+}
+
+// policy is a configured policy with its masking strategy
+type policy struct {
+	Policy
+	mask strategy
+}
+
+func New(config Config) (*Plugin, error) {
+	if len(config.Policies) == 0 {
+		return nil, xerrors.New("at least one policy is required")
+	}
+	p := &Plugin{}
+	for i, cfg := range config.Policies {
+		if len(cfg.Columns) == 0 {
+			return nil, xerrors.Errorf("policy %d: columns are required", i)
+		}
+		if err := plugins.ValidateColumns(cfg.Columns); err != nil {
+			return nil, xerrors.Errorf("policy %d: %w", i, err)
+		}
+		mask, err := newStrategy(cfg)
+		if err != nil {
+			return nil, xerrors.Errorf("policy %d: %w", i, err)
+		}
+		p.policies = append(p.policies, policy{Policy: cfg, mask: mask})
+	}
+	return p, nil
+}
+
+func (p *Plugin) Doc() string {
+	return docString
+}
+
+func (p *Plugin) Wrap(connector connectors.Connector) (connectors.Connector, error) {
+	return &Connector{
+		Connector: connector,
+		policies:  p.policies,
+		typ:       connector.Config().Type(),
+	}, nil
+}
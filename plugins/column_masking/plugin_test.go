@@ -0,0 +1,173 @@
+package columnmasking
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/centralmind/gateway/claimrule"
+	"github.com/centralmind/gateway/connectors"
+	"github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/sqlguard"
+	"github.com/centralmind/gateway/xcontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrategies(t *testing.T) {
+	date := time.Date(1984, 5, 17, 10, 30, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name   string
+		policy Policy
+		value  any
+		want   any
+	}{
+		{name: "redact", policy: Policy{Strategy: "redact"}, value: "secret", want: "[REDACTED]"},
+		{name: "redact with replacement", policy: Policy{Strategy: "redact", Replacement: "***"}, value: 42, want: "***"},
+		{name: "partial", policy: Policy{Strategy: "partial"}, value: "4111111111111234", want: "************1234"},
+		{name: "partial short", policy: Policy{Strategy: "partial", Keep: 2}, value: "ab", want: "**"},
+		{name: "null", policy: Policy{Strategy: "null"}, value: "secret", want: nil},
+		{name: "date year", policy: Policy{Strategy: "date", Precision: "year"}, value: date, want: "1984"},
+		{name: "date month from string", policy: Policy{Strategy: "date"}, value: "1984-05-17", want: "1984-05"},
+		{name: "date day from timestamp", policy: Policy{Strategy: "date", Precision: "day"}, value: "1984-05-17T10:30:00Z", want: "1984-05-17"},
+		{name: "not a date", policy: Policy{Strategy: "date"}, value: "soon", want: nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mask, err := newStrategy(tc.policy)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, mask(tc.value))
+		})
+	}
+
+	hash, err := newStrategy(Policy{Strategy: "hash", Salt: "s"})
+	require.NoError(t, err)
+	assert.Equal(t, hash("a@b.c"), hash("a@b.c"))
+	assert.NotEqual(t, hash("a@b.c"), hash("d@e.f"))
+	otherSalt, err := newStrategy(Policy{Strategy: "hash", Salt: "t"})
+	require.NoError(t, err)
+	assert.NotEqual(t, hash("a@b.c"), otherSalt("a@b.c"))
+
+	format, err := newStrategy(Policy{Strategy: "format", Salt: "s"})
+	require.NoError(t, err)
+	phone := format("+1-555-123-4567").(string)
+	assert.Regexp(t, `^\+\d-\d{3}-\d{3}-\d{4}$`, phone)
+	assert.NotEqual(t, "+1-555-123-4567", phone)
+	assert.Equal(t, phone, format("+1-555-123-4567"))
+	assert.Regexp(t, `^[A-Z][a-z]{3} [A-Z]\d{2}$`, format("John D42"))
+
+	for _, policy := range []Policy{{Strategy: "scramble"}, {Strategy: "date", Precision: "week"}} {
+		_, err := newStrategy(policy)
+		assert.Error(t, err)
+	}
+}
+
+type testConfig struct{}
+
+func (testConfig) Type() string          { return "postgres" }
+func (testConfig) Doc() string           { return "" }
+func (testConfig) ExtraPrompt() []string { return nil }
+func (testConfig) Readonly() bool        { return true }
+
+// rowsConnector returns the same rows for every call, like a cache does
+type rowsConnector struct {
+	connectors.Connector
+	rows []map[string]any
+}
+
+func (c rowsConnector) Config() connectors.Config { return testConfig{} }
+
+func (c rowsConnector) Query(context.Context, model.Endpoint, map[string]any) ([]map[string]any, error) {
+	return c.rows, nil
+}
+
+func (c rowsConnector) Sample(context.Context, model.Table) ([]map[string]any, error) {
+	return c.rows, nil
+}
+
+func TestConnector(t *testing.T) {
+	plugin, err := New(Config{Policies: []Policy{
+		{
+			Columns:     []string{"*email"},
+			Strategy:    "partial",
+			UnmaskedFor: []claimrule.Rule{{Claim: "roles", Operation: "contains", Value: "admin"}},
+		},
+		{Columns: []string{"ssn"}, Tables: []string{"customers"}, Strategy: "null"},
+		{Columns: []string{"phone"}, Endpoints: []string{"list_customers"}, Strategy: "redact"},
+		{Columns: []string{"email"}, Strategy: "null"},
+	}})
+	require.NoError(t, err)
+	raw := []map[string]any{{"id": 1, "email": "a@b.com", "ssn": "123-45-6789", "phone": "555", "backup_email": nil}}
+	connector, err := plugin.Wrap(rowsConnector{rows: raw})
+	require.NoError(t, err)
+
+	endpoint := model.Endpoint{MCPMethod: "list_customers", Query: "SELECT * FROM public.customers"}
+	ctx, stats := xcontext.WithQueryStats(context.Background())
+	ctx = xcontext.WithClaims(ctx, map[string]any{"roles": []any{"support"}})
+	res, err := connector.Query(ctx, endpoint, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"id": 1, "email": "***.com", "ssn": nil, "phone": "[REDACTED]", "backup_email": nil}}, res)
+	assert.Equal(t, map[string]string{"Masked-Values": "3"}, stats.Meta)
+	assert.Equal(t, "a@b.com", raw[0]["email"], "rows of the wrapped connector are not modified")
+
+	ctx = xcontext.WithClaims(context.Background(), map[string]any{"roles": []any{"admin"}})
+	res, err = connector.Query(ctx, model.Endpoint{MCPMethod: "orders", Query: "SELECT * FROM orders"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, raw, res, "admins see raw emails, other policies do not apply to the endpoint")
+
+	res, err = connector.Sample(context.Background(), model.Table{Name: "customers"})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"id": 1, "email": "***.com", "ssn": nil, "phone": "555", "backup_email": nil}}, res)
+}
+
+func TestRawQueries(t *testing.T) {
+	plugin, err := New(Config{Policies: []Policy{
+		{
+			Columns:     []string{"*email"},
+			Strategy:    "partial",
+			UnmaskedFor: []claimrule.Rule{{Claim: "roles", Operation: "contains", Value: "admin"}},
+		},
+		{Columns: []string{"ssn"}, Tables: []string{"customers"}, Strategy: "null"},
+	}})
+	require.NoError(t, err)
+	raw := []map[string]any{{"id": 1, "email": "a@b.com"}}
+	connector, err := plugin.Wrap(rowsConnector{rows: raw})
+	require.NoError(t, err)
+	support := xcontext.WithClaims(context.Background(), map[string]any{"roles": []any{"support"}})
+
+	res, err := connector.Query(support, model.Endpoint{Query: "SELECT * FROM users WHERE id = 1"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "***.com", res[0]["email"], "a star returns masked columns under their names")
+	_, err = connector.Query(support, model.Endpoint{Query: "SELECT ssn FROM orders"}, nil)
+	assert.NoError(t, err, "policies of other tables don't apply")
+
+	for _, query := range []string{
+		"SELECT email AS e FROM users",
+		"SELECT lower(email) FROM users",
+		"SELECT id FROM users WHERE backup_email LIKE 'a%'",
+		"SELECT row_to_json(u) FROM users u",
+		"SELECT * FROM (SELECT * FROM users) AS t(a, b)",
+		"SELECT ssn FROM customers",
+		"SELECT (",
+	} {
+		_, err := connector.Query(support, model.Endpoint{Query: query}, nil)
+		assert.ErrorIs(t, err, sqlguard.ErrRejected, query)
+	}
+
+	admin := xcontext.WithClaims(context.Background(), map[string]any{"roles": []any{"admin"}})
+	_, err = connector.Query(admin, model.Endpoint{Query: "SELECT email AS e FROM users"}, nil)
+	assert.NoError(t, err, "callers seeing raw values may query them")
+	_, err = connector.Query(support, model.Endpoint{MCPMethod: "list_users", Query: "SELECT email AS e FROM users"}, nil)
+	assert.NoError(t, err, "endpoint queries are written by the configuration author")
+}
+
+func TestConfigErrors(t *testing.T) {
+	for _, cfg := range []Config{
+		{},
+		{Policies: []Policy{{Strategy: "null"}}},
+		{Policies: []Policy{{Columns: []string{"[a"}, Strategy: "null"}}},
+		{Policies: []Policy{{Columns: []string{"a"}, Strategy: "blur"}}},
+	} {
+		_, err := New(cfg)
+		assert.Error(t, err, cfg)
+	}
+}
@@ -0,0 +1,118 @@
+package columnmasking
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/xerrors"
+)
+
+// strategy masks a single non-nil value
+type strategy func(v any) any
+
+func newStrategy(p Policy) (strategy, error) {
+	switch p.Strategy {
+	case "redact", "":
+		replacement := p.Replacement
+		if replacement == "" {
+			replacement = "[REDACTED]"
+		}
+		return func(any) any { return replacement }, nil
+	case "partial":
+		keep := p.Keep
+		if keep <= 0 {
+			keep = 4
+		}
+		return func(v any) any { return partial(fmt.Sprint(v), keep) }, nil
+	case "hash":
+		return func(v any) any { return hex.EncodeToString(digest(p.Salt, fmt.Sprint(v))) }, nil
+	case "format":
+		return func(v any) any { return preserveFormat(p.Salt, fmt.Sprint(v)) }, nil
+	case "null":
+		return func(any) any { return nil }, nil
+	case "date":
+		layout, ok := precisions[p.Precision]
+		if !ok {
+			return nil, xerrors.Errorf("unknown date precision %q, expected year, month or day", p.Precision)
+		}
+		return func(v any) any { return generalizeDate(v, layout) }, nil
+	default:
+		return nil, xerrors.Errorf("unknown strategy %q", p.Strategy)
+	}
+}
+
+// partial replaces all but keep trailing characters with *, short values are masked completely
+func partial(s string, keep int) string {
+	runes := []rune(s)
+	if len(runes) <= keep {
+		return strings.Repeat("*", len(runes))
+	}
+	return strings.Repeat("*", len(runes)-keep) + string(runes[len(runes)-keep:])
+}
+
+func digest(salt, s string) []byte {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(s))
+	return mac.Sum(nil)
+}
+
+// preserveFormat replaces digits with digits and letters with letters of the same case,
+// keeping length and separators. Replacements are derived from the value, so equal values stay equal.
+func preserveFormat(salt, s string) string {
+	stream := digest(salt, s)
+	next := func(i int) int {
+		if i%len(stream) == 0 && i > 0 {
+			stream = digest(salt, string(stream))
+		}
+		return int(stream[i%len(stream)])
+	}
+	var res strings.Builder
+	i := 0
+	for _, r := range s {
+		switch {
+		case unicode.IsDigit(r):
+			res.WriteRune(rune('0' + next(i)%10))
+			i++
+		case unicode.IsUpper(r):
+			res.WriteRune(rune('A' + next(i)%26))
+			i++
+		case unicode.IsLetter(r):
+			res.WriteRune(rune('a' + next(i)%26))
+			i++
+		default:
+			res.WriteRune(r)
+		}
+	}
+	return res.String()
+}
+
+// precisions map date precision to the layout generalized dates are formatted with
+var precisions = map[string]string{
+	"year":  "2006",
+	"month": "2006-01",
+	"":      "2006-01",
+	"day":   "2006-01-02",
+}
+
+// dateLayouts are tried in order to parse dates returned as strings
+var dateLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+// generalizeDate formats a date with layout dropping finer parts, values that are not dates are nulled out
+func generalizeDate(v any, layout string) any {
+	switch v := v.(type) {
+	case time.Time:
+		return v.Format(layout)
+	case string:
+		for _, l := range dateLayouts {
+			if t, err := time.Parse(l, v); err == nil {
+				return t.Format(layout)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package plugins
+
+import (
+	"path"
+
+	"golang.org/x/xerrors"
+)
+
+// ValidateColumns checks that column globs are valid path.Match patterns
+func ValidateColumns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return xerrors.Errorf("invalid column pattern %s: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// MatchColumn reports whether column matches one of the globs. Matching is case sensitive,
+// the same as pii_remover fields, so one list of columns selects the same values in every plugin.
+func MatchColumn(patterns []string, column string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, column); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,19 @@
+package plugins
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchColumn(t *testing.T) {
+	patterns := []string{"email", "*_phone"}
+	assert.True(t, MatchColumn(patterns, "email"))
+	assert.True(t, MatchColumn(patterns, "home_phone"))
+	assert.False(t, MatchColumn(patterns, "Email"), "matching is case sensitive")
+	assert.False(t, MatchColumn(patterns, "phone"))
+	assert.False(t, MatchColumn(nil, "email"))
+
+	assert.NoError(t, ValidateColumns(patterns))
+	assert.Error(t, ValidateColumns([]string{"[a"}))
+}
@@ -1,6 +1,8 @@
 package plugins
 
 import (
+	"context"
+
 	"github.com/centralmind/gateway/server"
 	"github.com/danielgtaylor/huma/v2"
 	"net/http"
@@ -23,7 +25,9 @@ type Plugin interface {
 	Doc() string
 }
 
-// Interceptor represents a plugin that can process and modify data before it reaches the connector
+// Interceptor represents a plugin that can process and modify data before it reaches the connector.
+// It sees a single row and caller headers, new plugins should implement BatchInterceptor,
+// row interceptors are adapted to it.
 type Interceptor interface {
 	Plugin
 	// Process handles the data transformation and returns processed data and a skip flag
@@ -47,7 +51,8 @@ type MCPToolEnricher interface {
 	EnrichMCP(tooler MCPTooler)
 }
 
-// Wrapper represents a plugin that can wrap and enhance a connector's functionality
+// Wrapper represents a plugin that can wrap and enhance a connector's functionality.
+// Wrappers that change rows return copies, connectors below (e.g. lru_cache) may share rows between calls.
 type Wrapper interface {
 	Plugin
 	// Wrap takes a connector and returns an enhanced version of it
@@ -55,6 +60,14 @@ type Wrapper interface {
 	Wrap(connector connectors.Connector) (connectors.Connector, error)
 }
 
+// Visibility represents a plugin that can hide methods from callers who are not allowed to use them
+type Visibility interface {
+	Plugin
+	// Visible reports whether the endpoint with given MCP method is usable by the caller
+	// described by ctx (headers, session, claims)
+	Visible(ctx context.Context, method string) bool
+}
+
 // Swaggerer represents a plugin that can modify OpenAPI documentation
 type Swaggerer interface {
 	Plugin
@@ -90,15 +103,17 @@ func New(tag string, config any) (Plugin, error) {
 	return f(config)
 }
 
+// Plugins constructs configured plugins implementing TPlugin in chain order
 func Plugins[TPlugin Plugin](pluginsCfg map[string]any) ([]TPlugin, error) {
+	links, err := chain(pluginsCfg)
+	if err != nil {
+		return nil, err
+	}
 	var res []TPlugin
-	for k, v := range pluginsCfg {
-		if _, ok := plugins[k]; !ok {
-			continue
-		}
-		plugin, err := plugins[k](v)
+	for _, l := range links {
+		plugin, err := plugins[l.tag](l.config)
 		if err != nil {
-			return nil, xerrors.Errorf("unable to construct: %s: %w", k, err)
+			return nil, xerrors.Errorf("unable to construct: %s: %w", l.tag, err)
 		}
 		p, ok := plugin.(TPlugin)
 		if !ok {
@@ -132,15 +147,17 @@ func Enrich(pluginsCfg map[string]any, schema *huma.OpenAPI) (*huma.OpenAPI, err
 	return schema, nil
 }
 
+// Wrap applies wrapper plugins so that the first one in the chain is the outermost
+// and sees a query first.
 func Wrap(pluginsCfg map[string]any, connector connectors.Connector) (connectors.Connector, error) {
 	plugs, err := Plugins[Wrapper](pluginsCfg)
 	if err != nil {
 		return nil, err
 	}
-	for _, wrapper := range plugs {
-		connector, err = wrapper.Wrap(connector)
+	for i := len(plugs) - 1; i >= 0; i-- {
+		connector, err = plugs[i].Wrap(connector)
 		if err != nil {
-			return nil, xerrors.Errorf("unable to wrap: %T: %w", wrapper, err)
+			return nil, xerrors.Errorf("unable to wrap: %T: %w", plugs[i], err)
 		}
 	}
 	return connector, nil
@@ -0,0 +1,80 @@
+package plugins
+
+import (
+	"context"
+
+	"github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/xcontext"
+	"golang.org/x/xerrors"
+)
+
+// Call describes the request whose result is intercepted
+type Call struct {
+	// Endpoint is the called endpoint, raw queries get an endpoint with only the query set
+	Endpoint model.Endpoint
+	// Params are the call parameters passed to the connector
+	Params map[string]any
+}
+
+// BatchInterceptor represents a plugin that processes the whole result of a call before it is returned.
+// Caller headers, session and claims are available from ctx through xcontext, response metadata
+// is added with xcontext.Stats(ctx).SetMeta.
+type BatchInterceptor interface {
+	Plugin
+	// Intercept returns rows to send to the caller, rows may be modified, removed or added.
+	// An error fails the call.
+	Intercept(ctx context.Context, call Call, rows []map[string]any) ([]map[string]any, error)
+}
+
+// Interceptors constructs configured interceptors in chain order,
+// row interceptors are adapted to BatchInterceptor
+func Interceptors(pluginsCfg map[string]any) ([]BatchInterceptor, error) {
+	links, err := chain(pluginsCfg)
+	if err != nil {
+		return nil, err
+	}
+	var res []BatchInterceptor
+	for _, l := range links {
+		plugin, err := plugins[l.tag](l.config)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to construct: %s: %w", l.tag, err)
+		}
+		switch p := plugin.(type) {
+		case BatchInterceptor:
+			res = append(res, p)
+		case Interceptor:
+			res = append(res, rowInterceptor{Interceptor: p})
+		}
+	}
+	return res, nil
+}
+
+// Intercept passes rows through interceptors in chain order
+func Intercept(ctx context.Context, interceptors []BatchInterceptor, call Call, rows []map[string]any) ([]map[string]any, error) {
+	for _, interceptor := range interceptors {
+		var err error
+		rows, err = interceptor.Intercept(ctx, call, rows)
+		if err != nil {
+			return nil, xerrors.Errorf("interceptor %T: %w", interceptor, err)
+		}
+	}
+	return rows, nil
+}
+
+// rowInterceptor adapts a row interceptor, it sees caller headers only
+type rowInterceptor struct {
+	Interceptor
+}
+
+func (r rowInterceptor) Intercept(ctx context.Context, _ Call, rows []map[string]any) ([]map[string]any, error) {
+	headers := xcontext.Headers(ctx)
+	res := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		processed, skipped := r.Process(row, headers)
+		if skipped {
+			continue
+		}
+		res = append(res, processed)
+	}
+	return res, nil
+}
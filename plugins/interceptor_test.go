@@ -0,0 +1,56 @@
+package plugins
+
+import (
+	"context"
+	"testing"
+
+	"github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/xcontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+// headerFilter is a row interceptor skipping rows whose owner differs from the X-Owner header
+type headerFilter struct{}
+
+func (headerFilter) Doc() string { return "" }
+
+func (headerFilter) Process(row map[string]any, headers map[string][]string) (map[string]any, bool) {
+	return row, row["owner"] != headers["X-Owner"][0]
+}
+
+// failing is a batch interceptor rejecting calls of the blocked endpoint
+type failing struct{}
+
+func (failing) Doc() string { return "" }
+
+func (failing) Intercept(ctx context.Context, call Call, rows []map[string]any) ([]map[string]any, error) {
+	if call.Endpoint.MCPMethod == "blocked" {
+		return nil, xerrors.New("blocked")
+	}
+	xcontext.Stats(ctx).SetMeta("Rows", "seen")
+	return rows, nil
+}
+
+func TestIntercept(t *testing.T) {
+	interceptors := []BatchInterceptor{rowInterceptor{Interceptor: headerFilter{}}, failing{}}
+	ctx := xcontext.WithHeader(context.Background(), map[string][]string{"X-Owner": {"alice"}})
+	ctx, stats := xcontext.WithQueryStats(ctx)
+	rows := []map[string]any{{"owner": "alice"}, {"owner": "bob"}}
+
+	res, err := Intercept(ctx, interceptors, Call{}, rows)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"owner": "alice"}}, res)
+	assert.Equal(t, map[string]string{"Rows": "seen"}, stats.Meta)
+
+	_, err = Intercept(ctx, interceptors, Call{Endpoint: model.Endpoint{MCPMethod: "blocked"}}, rows)
+	assert.Error(t, err)
+}
+
+func TestInterceptorsAdaptRowInterceptors(t *testing.T) {
+	interceptors, err := Interceptors(map[string]any{"test_mask": nil, "test_recorder": map[string]any{}})
+	require.NoError(t, err)
+	require.Len(t, interceptors, 1)
+	assert.IsType(t, rowInterceptor{}, interceptors[0])
+}
@@ -0,0 +1,71 @@
+package jwt
+
+import (
+	"time"
+
+	"github.com/centralmind/gateway/claimrule"
+	"github.com/centralmind/gateway/plugins"
+)
+
+// Config represents JWT authentication plugin configuration
+type Config struct {
+	// JWKSURL is the JSON Web Key Set URL of the identity provider, e.g. https://idp.example.com/.well-known/jwks.json
+	JWKSURL string `yaml:"jwks_url"`
+
+	// JWKSRefresh is how often the key set is fetched again (default: 1h),
+	// tokens signed by an unknown key also trigger a refresh, at most once a minute
+	JWKSRefresh time.Duration `yaml:"jwks_refresh"`
+
+	// Keys are static verification keys, used with or instead of the key set
+	Keys []Key `yaml:"keys"`
+
+	// Issuer is the expected iss claim, not checked if empty
+	Issuer string `yaml:"issuer"`
+
+	// Audience lists accepted aud claims, a token must have one of them, not checked if empty
+	Audience []string `yaml:"audience"`
+
+	// Algorithms lists accepted signing algorithms,
+	// asymmetric ones by default and HS256, HS384, HS512 when secrets are configured
+	Algorithms []string `yaml:"algorithms"`
+
+	// Leeway is the allowed clock skew for exp, nbf and iat checks
+	Leeway time.Duration `yaml:"leeway"`
+
+	// Header carries the token, with or without the Bearer prefix (default: Authorization)
+	Header string `yaml:"header"`
+
+	// TokenEnv is the environment variable holding the token of the stdio MCP server,
+	// that has no request headers
+	TokenEnv string `yaml:"token_env"`
+
+	// AuthorizationRules defines authorization rules for methods, same as in the oauth plugin
+	AuthorizationRules []claimrule.AuthorizationRule `yaml:"authorization_rules"`
+}
+
+// Key is a static verification key: a PEM encoded public key or an HMAC secret
+type Key struct {
+	// ID matches the kid header of tokens, keys without it are tried for every token
+	ID string `yaml:"kid"`
+
+	// PublicKey is a PEM encoded RSA, ECDSA or Ed25519 public key
+	PublicKey string `yaml:"public_key"`
+
+	// PublicKeyFile is a path to a PEM encoded public key
+	PublicKeyFile string `yaml:"public_key_file"`
+
+	// Secret is an HMAC secret for HS256, HS384 and HS512 tokens
+	Secret string `yaml:"secret"`
+}
+
+func (c Config) Tag() string {
+	return "jwt"
+}
+
+func (c Config) Order() int {
+	return plugins.OrderAuth
+}
+
+func (c Config) Doc() string {
+	return docString
+}
@@ -0,0 +1,28 @@
+package jwt
+
+import (
+	"context"
+
+	"github.com/centralmind/gateway/claimrule"
+	"github.com/centralmind/gateway/connectors"
+	"github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/xcontext"
+	"golang.org/x/xerrors"
+)
+
+// Connector authenticates every query by the caller's JWT and passes its claims on
+type Connector struct {
+	connectors.Connector
+	plugin *Plugin
+}
+
+func (c *Connector) Query(ctx context.Context, endpoint model.Endpoint, params map[string]any) ([]map[string]any, error) {
+	claims, err := c.plugin.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := claimrule.Authorize(c.plugin.config.AuthorizationRules, endpoint.MCPMethod, claims, params); err != nil {
+		return nil, xerrors.Errorf("unable to authorize: %w", err)
+	}
+	return c.Connector.Query(xcontext.WithClaims(ctx, claims), endpoint, params)
+}
@@ -0,0 +1,270 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+)
+
+const (
+	defaultJWKSRefresh = time.Hour
+	// minJWKSRefresh limits refreshes triggered by tokens with unknown key ids
+	minJWKSRefresh = time.Minute
+	jwksTimeout    = 10 * time.Second
+)
+
+// key is a verification key with its id, empty for keys matching any token
+type key struct {
+	id  string
+	key any
+}
+
+// keySet holds static keys and keys fetched from a JWKS URL. The key set is fetched on first use
+// and again once refresh passes or a token names an unknown key, failed fetches keep the previous keys.
+type keySet struct {
+	static  []key
+	url     string
+	refresh time.Duration
+	client  *http.Client
+
+	mu      sync.Mutex
+	remote  []key
+	fetched time.Time
+}
+
+func newKeySet(config Config) (*keySet, error) {
+	s := &keySet{
+		url:     config.JWKSURL,
+		refresh: config.JWKSRefresh,
+		client:  &http.Client{Timeout: jwksTimeout},
+	}
+	if s.refresh <= 0 {
+		s.refresh = defaultJWKSRefresh
+	}
+	for i, k := range config.Keys {
+		parsed, err := staticKey(k)
+		if err != nil {
+			return nil, xerrors.Errorf("key %d: %w", i, err)
+		}
+		s.static = append(s.static, key{id: k.ID, key: parsed})
+	}
+	return s, nil
+}
+
+func staticKey(k Key) (any, error) {
+	pem := k.PublicKey
+	if k.PublicKeyFile != "" {
+		raw, err := os.ReadFile(k.PublicKeyFile)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read public key file: %w", err)
+		}
+		pem = string(raw)
+	}
+	switch {
+	case pem != "" && k.Secret != "":
+		return nil, xerrors.New("public key and secret are mutually exclusive")
+	case k.Secret != "":
+		return []byte(k.Secret), nil
+	case pem == "":
+		return nil, xerrors.New("public key or secret is required")
+	}
+	if rsaKey, err := gojwt.ParseRSAPublicKeyFromPEM([]byte(pem)); err == nil {
+		return rsaKey, nil
+	}
+	if ecKey, err := gojwt.ParseECPublicKeyFromPEM([]byte(pem)); err == nil {
+		return ecKey, nil
+	}
+	if edKey, err := gojwt.ParseEdPublicKeyFromPEM([]byte(pem)); err == nil {
+		return edKey, nil
+	}
+	return nil, xerrors.New("unable to parse public key, expected a PEM encoded RSA, ECDSA or Ed25519 key")
+}
+
+// hasSecrets reports whether HMAC secrets are configured
+func (s *keySet) hasSecrets() bool {
+	for _, k := range s.static {
+		if _, ok := k.key.([]byte); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// keyfunc returns keys that may have signed the token: keys with its kid and keys without an id
+func (s *keySet) keyfunc(ctx context.Context) gojwt.Keyfunc {
+	return func(token *gojwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		keys := s.static
+		if s.url != "" {
+			remote, err := s.remoteKeys(ctx, kid)
+			if err != nil && len(keys) == 0 {
+				return nil, err
+			}
+			keys = append(append([]key{}, keys...), remote...)
+		}
+		var res gojwt.VerificationKeySet
+		for _, k := range keys {
+			if k.id == "" || kid == "" || k.id == kid {
+				res.Keys = append(res.Keys, k.key)
+			}
+		}
+		if len(res.Keys) == 0 {
+			return nil, xerrors.Errorf("no key for kid %q", kid)
+		}
+		return res, nil
+	}
+}
+
+func (s *keySet) remoteKeys(ctx context.Context, kid string) ([]key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	since := time.Since(s.fetched)
+	if s.fetched.IsZero() || since > s.refresh || !hasKey(s.remote, kid) && since > minJWKSRefresh {
+		keys, err := s.fetch(ctx)
+		s.fetched = time.Now()
+		switch {
+		case err != nil && len(s.remote) == 0:
+			return nil, err
+		case err != nil:
+			logrus.Warnf("jwt: unable to refresh JWKS, keeping previous keys: %v", err)
+		default:
+			s.remote = keys
+		}
+	}
+	return s.remote, nil
+}
+
+func hasKey(keys []key, kid string) bool {
+	if kid == "" {
+		return true
+	}
+	for _, k := range keys {
+		if k.id == kid {
+			return true
+		}
+	}
+	return false
+}
+
+// jwk is a JSON Web Key of the key set
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (s *keySet) fetch(ctx context.Context) ([]key, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to create JWKS request: %w", err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, xerrors.Errorf("JWKS request failed: %d", resp.StatusCode)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, xerrors.Errorf("unable to decode JWKS: %w", err)
+	}
+	var res []key
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		parsed, err := k.publicKey()
+		if err != nil {
+			// a key of an unsupported type doesn't make the rest of the set unusable
+			logrus.Debugf("jwt: skipping JWKS key %q: %v", k.Kid, err)
+			continue
+		}
+		res = append(res, key{id: k.Kid, key: parsed})
+	}
+	if len(res) == 0 {
+		return nil, xerrors.New("JWKS has no usable signing keys")
+	}
+	return res, nil
+}
+
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() {
+			return nil, xerrors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, xerrors.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, xerrors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, xerrors.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, xerrors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, xerrors.Errorf("unsupported key type %s", k.Kty)
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(raw) == 0 {
+		return nil, xerrors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
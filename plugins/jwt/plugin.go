@@ -0,0 +1,200 @@
+package jwt
+
+import (
+	"context"
+	_ "embed"
+	"os"
+	"strings"
+
+	"github.com/centralmind/gateway/claimrule"
+	"github.com/centralmind/gateway/connectors"
+	"github.com/centralmind/gateway/errors"
+	"github.com/centralmind/gateway/mcp"
+	"github.com/centralmind/gateway/plugins"
+	"github.com/centralmind/gateway/server"
+	"github.com/centralmind/gateway/xcontext"
+	"github.com/danielgtaylor/huma/v2"
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"golang.org/x/xerrors"
+)
+
+//go:embed README.md
+var docString string
+
+func init() {
+	plugins.Register(New)
+}
+
+const (
+	defaultHeader = "Authorization"
+	// stdioSession is the session of the stdio MCP server, the only one taking the token from the environment
+	stdioSession = "stdio"
+)
+
+var (
+	asymmetricAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+	hmacAlgorithms       = []string{"HS256", "HS384", "HS512"}
+)
+
+type PluginBundle interface {
+	plugins.Wrapper
+	plugins.Swaggerer
+	plugins.MCPToolEnricher
+	plugins.Visibility
+}
+
+type Plugin struct {
+	config Config
+	keys   *keySet
+	parser *gojwt.Parser
+}
+
+func New(cfg Config) (PluginBundle, error) {
+	if cfg.JWKSURL == "" && len(cfg.Keys) == 0 {
+		return nil, xerrors.New("jwks_url or keys are required")
+	}
+	if cfg.Header == "" {
+		cfg.Header = defaultHeader
+	}
+	keys, err := newKeySet(cfg)
+	if err != nil {
+		return nil, err
+	}
+	algorithms := cfg.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = asymmetricAlgorithms
+		if keys.hasSecrets() {
+			algorithms = append(append([]string{}, algorithms...), hmacAlgorithms...)
+		}
+	}
+	for _, alg := range algorithms {
+		if gojwt.GetSigningMethod(alg) == nil || alg == "none" {
+			return nil, xerrors.Errorf("unsupported algorithm %s", alg)
+		}
+	}
+	options := []gojwt.ParserOption{
+		gojwt.WithValidMethods(algorithms),
+		gojwt.WithExpirationRequired(),
+		gojwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		options = append(options, gojwt.WithIssuer(cfg.Issuer))
+	}
+	return &Plugin{
+		config: cfg,
+		keys:   keys,
+		parser: gojwt.NewParser(options...),
+	}, nil
+}
+
+func (p *Plugin) Doc() string {
+	return docString
+}
+
+func (p *Plugin) Wrap(connector connectors.Connector) (connectors.Connector, error) {
+	return &Connector{
+		Connector: connector,
+		plugin:    p,
+	}, nil
+}
+
+// EnrichMCP authenticates MCP tool calls, so claims are available to all plugins, interceptors included
+func (p *Plugin) EnrichMCP(tooler plugins.MCPTooler) {
+	tooler.Server().AddToolMiddleware(func(ctx context.Context, tool server.ServerTool, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		claims, err := p.authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return tool.Handler(xcontext.WithClaims(ctx, claims), request)
+	})
+}
+
+// Visible hides methods the caller can't call: all of them with an invalid token,
+// and methods whose authorization rules the token claims don't pass.
+// Callers without a token see every method, so the spec fetched by Swagger UI still lists operations.
+func (p *Plugin) Visible(ctx context.Context, method string) bool {
+	if p.token(ctx) == "" {
+		return true
+	}
+	claims, err := p.authenticate(ctx)
+	if err != nil {
+		return false
+	}
+	return claimrule.AuthorizeVisible(p.config.AuthorizationRules, method, claims) == nil
+}
+
+func (p *Plugin) Enrich(swag *huma.OpenAPI) *huma.OpenAPI {
+	securityName := "BearerAuth"
+	if swag.Components.SecuritySchemes == nil {
+		swag.Components.SecuritySchemes = map[string]*huma.SecurityScheme{}
+	}
+	scheme := &huma.SecurityScheme{
+		Type:         "http",
+		Scheme:       "bearer",
+		BearerFormat: "JWT",
+	}
+	if !strings.EqualFold(p.config.Header, defaultHeader) {
+		scheme = &huma.SecurityScheme{
+			Type: "apiKey",
+			In:   "header",
+			Name: p.config.Header,
+		}
+	}
+	swag.Components.SecuritySchemes[securityName] = scheme
+	for _, v := range swag.Paths {
+		for _, op := range []*huma.Operation{v.Get, v.Delete, v.Post, v.Put, v.Patch} {
+			if op != nil {
+				op.Security = []map[string][]string{{securityName: {}}}
+			}
+		}
+	}
+	return swag
+}
+
+// authenticate validates the token of the caller and returns its claims
+func (p *Plugin) authenticate(ctx context.Context) (map[string]any, error) {
+	token := p.token(ctx)
+	if token == "" {
+		return nil, xerrors.Errorf("empty token: %w", errors.ErrNotAuthorized)
+	}
+	return p.validate(ctx, token)
+}
+
+// token returns the token from the request header, the stdio server takes it from the environment
+func (p *Plugin) token(ctx context.Context) string {
+	raw := xcontext.Header(ctx, p.config.Header)
+	if raw == "" && p.config.TokenEnv != "" && xcontext.Session(ctx) == stdioSession {
+		raw = os.Getenv(p.config.TokenEnv)
+	}
+	raw = strings.TrimSpace(raw)
+	if len(raw) > len("Bearer ") && strings.EqualFold(raw[:len("Bearer ")], "Bearer ") {
+		raw = strings.TrimSpace(raw[len("Bearer "):])
+	}
+	return raw
+}
+
+// validate checks the signature, exp, nbf, iss and aud of a token
+func (p *Plugin) validate(ctx context.Context, token string) (map[string]any, error) {
+	claims := gojwt.MapClaims{}
+	if _, err := p.parser.ParseWithClaims(token, claims, p.keys.keyfunc(ctx)); err != nil {
+		return nil, xerrors.Errorf("invalid token: %v: %w", err, errors.ErrNotAuthorized)
+	}
+	if len(p.config.Audience) > 0 {
+		aud, err := claims.GetAudience()
+		if err != nil || !intersects(aud, p.config.Audience) {
+			return nil, xerrors.Errorf("invalid token audience: %w", errors.ErrNotAuthorized)
+		}
+	}
+	return claims, nil
+}
+
+func intersects(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/centralmind/gateway/claimrule"
+	"github.com/centralmind/gateway/connectors"
+	"github.com/centralmind/gateway/errors"
+	"github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/xcontext"
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jwksServer serves RSA keys by kid and counts requests
+type jwksServer struct {
+	mu       sync.Mutex
+	keys     map[string]*rsa.PrivateKey
+	requests atomic.Int32
+}
+
+func (s *jwksServer) add(t *testing.T, kid string) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[kid] = key
+	return key
+}
+
+func (s *jwksServer) start(t *testing.T) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		var set struct {
+			Keys []jwk `json:"keys"`
+		}
+		for kid, key := range s.keys {
+			set.Keys = append(set.Keys, jwk{
+				Kty: "RSA",
+				Kid: kid,
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func sign(t *testing.T, method gojwt.SigningMethod, key any, kid string, claims gojwt.MapClaims) string {
+	token := gojwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	res, err := token.SignedString(key)
+	require.NoError(t, err)
+	return res
+}
+
+func validClaims() gojwt.MapClaims {
+	return gojwt.MapClaims{
+		"sub":   "user-1",
+		"iss":   "https://idp.example.com/",
+		"aud":   []string{"gateway", "other"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{"support"},
+	}
+}
+
+func withToken(token string) context.Context {
+	return xcontext.WithHeader(context.Background(), map[string][]string{"Authorization": {"Bearer " + token}})
+}
+
+func TestValidate(t *testing.T) {
+	jwks := &jwksServer{keys: map[string]*rsa.PrivateKey{}}
+	key := jwks.add(t, "k1")
+	bundle, err := New(Config{
+		JWKSURL:  jwks.start(t),
+		Issuer:   "https://idp.example.com/",
+		Audience: []string{"gateway"},
+	})
+	require.NoError(t, err)
+	plugin := bundle.(*Plugin)
+
+	claims, err := plugin.authenticate(withToken(sign(t, gojwt.SigningMethodRS256, key, "k1", validClaims())))
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims["sub"])
+
+	for name, mutate := range map[string]func(gojwt.MapClaims){
+		"expired":     func(c gojwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() },
+		"no exp":      func(c gojwt.MapClaims) { delete(c, "exp") },
+		"not yet":     func(c gojwt.MapClaims) { c["nbf"] = time.Now().Add(time.Hour).Unix() },
+		"issuer":      func(c gojwt.MapClaims) { c["iss"] = "https://evil.example.com/" },
+		"audience":    func(c gojwt.MapClaims) { c["aud"] = "other" },
+		"missing aud": func(c gojwt.MapClaims) { delete(c, "aud") },
+		"missing iss": func(c gojwt.MapClaims) { delete(c, "iss") },
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := validClaims()
+			mutate(c)
+			_, err := plugin.authenticate(withToken(sign(t, gojwt.SigningMethodRS256, key, "k1", c)))
+			assert.ErrorIs(t, err, errors.ErrNotAuthorized)
+		})
+	}
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, err = plugin.authenticate(withToken(sign(t, gojwt.SigningMethodRS256, other, "k1", validClaims())))
+	assert.ErrorIs(t, err, errors.ErrNotAuthorized, "signed by another key")
+	_, err = plugin.authenticate(withToken(sign(t, gojwt.SigningMethodHS256, []byte("secret"), "k1", validClaims())))
+	assert.ErrorIs(t, err, errors.ErrNotAuthorized, "HMAC is not accepted without secrets")
+	_, err = plugin.authenticate(context.Background())
+	assert.ErrorIs(t, err, errors.ErrNotAuthorized)
+	assert.Equal(t, int32(1), jwks.requests.Load(), "the key set is cached")
+}
+
+func TestJWKSRotation(t *testing.T) {
+	jwks := &jwksServer{keys: map[string]*rsa.PrivateKey{}}
+	key := jwks.add(t, "k1")
+	bundle, err := New(Config{JWKSURL: jwks.start(t)})
+	require.NoError(t, err)
+	plugin := bundle.(*Plugin)
+
+	_, err = plugin.authenticate(withToken(sign(t, gojwt.SigningMethodRS256, key, "k1", validClaims())))
+	require.NoError(t, err)
+
+	// a new key is picked up once the minimal refresh interval passes
+	rotated := jwks.add(t, "k2")
+	token := sign(t, gojwt.SigningMethodRS256, rotated, "k2", validClaims())
+	_, err = plugin.authenticate(withToken(token))
+	assert.Error(t, err)
+	plugin.keys.fetched = time.Now().Add(-2 * minJWKSRefresh)
+	_, err = plugin.authenticate(withToken(token))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), jwks.requests.Load())
+}
+
+func TestStaticKeysAndStdio(t *testing.T) {
+	bundle, err := New(Config{
+		Keys:     []Key{{Secret: "secret"}},
+		TokenEnv: "TEST_GATEWAY_JWT",
+		Header:   "X-Token",
+	})
+	require.NoError(t, err)
+	plugin := bundle.(*Plugin)
+	token := sign(t, gojwt.SigningMethodHS256, []byte("secret"), "", validClaims())
+
+	ctx := xcontext.WithHeader(context.Background(), map[string][]string{"X-Token": {token}})
+	_, err = plugin.authenticate(ctx)
+	require.NoError(t, err)
+
+	t.Setenv("TEST_GATEWAY_JWT", token)
+	_, err = plugin.authenticate(xcontext.WithSession(context.Background(), "stdio"))
+	require.NoError(t, err)
+	_, err = plugin.authenticate(xcontext.WithSession(context.Background(), "sse-session"))
+	assert.ErrorIs(t, err, errors.ErrNotAuthorized, "HTTP callers don't get the environment token")
+}
+
+type testConnector struct {
+	connectors.Connector
+	claims map[string]any
+}
+
+func (c *testConnector) Query(ctx context.Context, _ model.Endpoint, _ map[string]any) ([]map[string]any, error) {
+	c.claims = xcontext.Claims(ctx)
+	return nil, nil
+}
+
+func TestConnectorAuthorization(t *testing.T) {
+	bundle, err := New(Config{
+		Keys: []Key{{Secret: "secret"}},
+		AuthorizationRules: []claimrule.AuthorizationRule{
+			{Methods: []string{"*"}, ClaimRules: []claimrule.Rule{{Claim: "sub", Operation: "exists"}}},
+			{Methods: []string{"delete_user"}, ClaimRules: []claimrule.Rule{{Claim: "roles", Operation: "contains", Value: "admin"}}},
+			{Methods: []string{"get_org"}, ClaimRules: []claimrule.Rule{{Claim: "org", Operation: "eq", Value: "{{.org}}"}}},
+		},
+	})
+	require.NoError(t, err)
+	inner := &testConnector{}
+	connector, err := bundle.Wrap(inner)
+	require.NoError(t, err)
+
+	claims := validClaims()
+	claims["org"] = "acme"
+	ctx := withToken(sign(t, gojwt.SigningMethodHS256, []byte("secret"), "", claims))
+
+	_, err = connector.Query(ctx, model.Endpoint{MCPMethod: "list_users"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", inner.claims["sub"])
+
+	_, err = connector.Query(ctx, model.Endpoint{MCPMethod: "delete_user"}, nil)
+	assert.ErrorIs(t, err, errors.ErrNotAuthorized)
+	_, err = connector.Query(ctx, model.Endpoint{MCPMethod: "get_org"}, map[string]any{"org": "acme"})
+	require.NoError(t, err)
+	_, err = connector.Query(ctx, model.Endpoint{MCPMethod: "get_org"}, map[string]any{"org": "other"})
+	assert.ErrorIs(t, err, errors.ErrNotAuthorized)
+
+	assert.True(t, bundle.Visible(ctx, "list_users"))
+	assert.True(t, bundle.Visible(ctx, "get_org"))
+	assert.False(t, bundle.Visible(ctx, "delete_user"))
+	assert.False(t, bundle.Visible(withToken("not a token"), "list_users"))
+	// anonymous spec readers like Swagger UI see every method
+	assert.True(t, bundle.Visible(context.Background(), "delete_user"))
+}
+
+func TestConfigErrors(t *testing.T) {
+	for _, cfg := range []Config{
+		{},
+		{Keys: []Key{{}}},
+		{Keys: []Key{{Secret: "s", PublicKey: "pem"}}},
+		{Keys: []Key{{PublicKey: "not a pem"}}},
+		{Keys: []Key{{PublicKeyFile: "missing.pem"}}},
+		{Keys: []Key{{Secret: "s"}}, Algorithms: []string{"none"}},
+		{Keys: []Key{{Secret: "s"}}, Algorithms: []string{"XX256"}},
+	} {
+		_, err := New(cfg)
+		assert.Error(t, err, cfg)
+	}
+}
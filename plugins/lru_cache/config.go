@@ -1,6 +1,10 @@
 package lrucache
 
-import "time"
+import (
+	"time"
+
+	"github.com/centralmind/gateway/plugins"
+)
 
 // Config represents LRU cache configuration
 type Config struct {
@@ -16,6 +20,10 @@ func (c Config) Tag() string {
 	return "lru_cache"
 }
 
+func (c Config) Order() int {
+	return plugins.OrderCache
+}
+
 func (c Config) Doc() string {
 	return docString
 }
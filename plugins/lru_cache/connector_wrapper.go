@@ -31,6 +31,12 @@ func (c Connector) Query(ctx context.Context, endpoint model.Endpoint, params ma
 	return v, nil
 }
 
+// Close drops cached results before closing the inner connector
+func (c Connector) Close() error {
+	c.lru.Purge()
+	return c.Connector.Close()
+}
+
 func keyify(endpoint model.Endpoint, params map[string]any) string {
 	var keys []string
 	for k := range params {
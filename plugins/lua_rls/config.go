@@ -1,5 +1,7 @@
 package luarls
 
+import "github.com/centralmind/gateway/plugins"
+
 // Config represents Lua Row-Level Security configuration
 type Config struct {
 	// Script is the Lua script content for RLS logic
@@ -11,7 +13,7 @@ type Config struct {
 	// Variables defines global variables available to Lua script
 	Variables map[string]interface{} `yaml:"variables"`
 
-	// CacheSize is the size of the Lua VM cache
+	// CacheSize is the number of idle Lua states kept in the pool
 	CacheSize int `yaml:"cache_size"`
 }
 
@@ -19,6 +21,10 @@ func (c Config) Tag() string {
 	return "lua_rls"
 }
 
+func (c Config) Order() int {
+	return plugins.OrderRowFilter
+}
+
 func (c Config) Doc() string {
 	return docString
 }
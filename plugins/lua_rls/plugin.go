@@ -1,80 +1,233 @@
 package luarls
 
 import (
+	"context"
 	_ "embed"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/centralmind/gateway/plugins"
+	"github.com/centralmind/gateway/xcontext"
 	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+	"golang.org/x/xerrors"
 )
 
 //go:embed README.md
 var docString string
 
 func init() {
-	plugins.Register(func(cfg Config) (plugins.Interceptor, error) {
+	plugins.Register(func(cfg Config) (plugins.BatchInterceptor, error) {
 		return New(cfg)
 	})
 }
 
+const (
+	// entryPoint returns true to keep a row with changes made to it, a table replacing the row, or false or nil to hide it
+	entryPoint = "filter_rows"
+	// legacyEntryPoint hides rows it returns true for
+	legacyEntryPoint = "check_visibility"
+
+	defaultPoolSize = 16
+)
+
+// Plugin runs a script compiled once in a pool of Lua states,
+// a state serves one call at a time and keeps globals of the script between calls
 type Plugin struct {
-	script string
+	proto     *lua.FunctionProto
+	variables map[string]any
+	pool      chan *lua.LState
+}
+
+func New(config Config) (*Plugin, error) {
+	script := config.Script
+	if config.ScriptFile != "" {
+		if script != "" {
+			return nil, xerrors.New("script and script_file are mutually exclusive")
+		}
+		raw, err := os.ReadFile(config.ScriptFile)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read script file: %w", err)
+		}
+		script = string(raw)
+	}
+	chunk, err := parse.Parse(strings.NewReader(script), "lua_rls")
+	if err != nil {
+		return nil, xerrors.Errorf("unable to parse script: %w", err)
+	}
+	proto, err := lua.Compile(chunk, "lua_rls")
+	if err != nil {
+		return nil, xerrors.Errorf("unable to compile script: %w", err)
+	}
+	size := config.CacheSize
+	if size <= 0 {
+		size = defaultPoolSize
+	}
+	p := &Plugin{
+		proto:     proto,
+		variables: config.Variables,
+		pool:      make(chan *lua.LState, size),
+	}
+	// the first state checks that the script runs and has an entry point
+	st, err := p.newState()
+	if err != nil {
+		return nil, err
+	}
+	p.put(st)
+	return p, nil
 }
 
-func (p Plugin) Doc() string {
+func (p *Plugin) Doc() string {
 	return docString
 }
 
-func (p Plugin) Process(row map[string]any, headers map[string][]string) (processed map[string]any, skipped bool) {
-	st := lua.NewState()
-	if err := st.DoString(p.script); err != nil {
-		return nil, false
+// Intercept runs the entry point of the script for every row. A script error fails the call,
+// so rows are never returned unchecked.
+func (p *Plugin) Intercept(ctx context.Context, call plugins.Call, rows []map[string]any) ([]map[string]any, error) {
+	st, err := p.get()
+	if err != nil {
+		return nil, err
 	}
-	fn := st.GetGlobal("check_visibility")
-	if fn == lua.LNil {
-		return nil, false
+	st.SetContext(ctx)
+	res, err := p.filter(ctx, st, call, rows)
+	st.RemoveContext()
+	if err != nil {
+		// a failed script may leave globals half updated
+		st.Close()
+		return nil, err
 	}
-	defer st.Close()
+	p.put(st)
+	return res, nil
+}
+
+func (p *Plugin) filter(ctx context.Context, st *lua.LState, call plugins.Call, rows []map[string]any) ([]map[string]any, error) {
+	fn, legacy := entry(st)
+	callContext := p.callContext(ctx, st, call)
+	res := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		rowTable := st.NewTable()
+		values := make(map[string]lua.LValue, len(row))
+		for k, v := range row {
+			if legacy {
+				// check_visibility scripts compare values as strings
+				values[k] = lua.LString(fmt.Sprintf("%v", v))
+			} else {
+				values[k] = toLua(st, v)
+			}
+			rowTable.RawSetString(k, values[k])
+		}
+		if err := st.CallByParam(lua.P{
+			Fn:      fn,
+			NRet:    1,
+			Protect: true,
+		}, rowTable, callContext); err != nil {
+			return nil, xerrors.Errorf("script failed: %w", err)
+		}
+		ret := st.Get(-1)
+		st.Pop(1)
 
-	rowTable := st.NewTable()
-	for k, v := range row {
-		st.SetTable(rowTable, lua.LString(k), lua.LString(fmt.Sprintf("%v", v)))
+		if legacy {
+			if !lua.LVAsBool(ret) {
+				res = append(res, row)
+			}
+			continue
+		}
+		switch ret := ret.(type) {
+		case *lua.LNilType:
+		case lua.LBool:
+			if ret {
+				res = append(res, fromRowTable(rowTable, row, values))
+			}
+		case *lua.LTable:
+			res = append(res, fromRowTable(ret, row, values))
+		default:
+			return nil, xerrors.Errorf("%s must return a boolean or a table, got %s", entryPoint, ret.Type())
+		}
 	}
+	return res, nil
+}
 
-	// Преобразуем headers в lua-таблицу
-	headersTable := st.NewTable()
-	for k, v := range headers {
+// callContext holds single-valued headers and claims of the caller as properties,
+// and also under headers and claims, with the endpoint name and call params
+func (p *Plugin) callContext(ctx context.Context, st *lua.LState, call plugins.Call) *lua.LTable {
+	res := st.NewTable()
+	headers := st.NewTable()
+	for k, v := range xcontext.Headers(ctx) {
 		if len(v) != 1 {
 			continue
 		}
-		st.SetTable(headersTable, lua.LString(k), lua.LString(v[0]))
+		headers.RawSetString(k, lua.LString(v[0]))
+		res.RawSetString(k, lua.LString(v[0]))
 	}
-
-	if err := st.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}, rowTable, headersTable); err != nil {
-		return nil, true
+	claims := st.NewTable()
+	for k, v := range xcontext.Claims(ctx) {
+		value := toLua(st, v)
+		claims.RawSetString(k, value)
+		res.RawSetString(k, value)
 	}
+	res.RawSetString("headers", headers)
+	res.RawSetString("claims", claims)
+	res.RawSetString("endpoint", lua.LString(call.Endpoint.MCPMethod))
+	res.RawSetString("params", toLua(st, call.Params))
+	return res
+}
 
-	ret := st.Get(-1)
-	st.Pop(1)
+// fromRowTable builds a row from a table returned by the script,
+// values the script did not change keep their original Go type
+func fromRowTable(table *lua.LTable, row map[string]any, values map[string]lua.LValue) map[string]any {
+	res := make(map[string]any, len(row))
+	table.ForEach(func(k, v lua.LValue) {
+		key := k.String()
+		if original, ok := values[key]; ok && original == v {
+			res[key] = row[key]
+			return
+		}
+		res[key] = fromLua(v)
+	})
+	return res
+}
 
-	return row, lua.LVAsBool(ret)
+func entry(st *lua.LState) (fn lua.LValue, legacy bool) {
+	if fn := st.GetGlobal(entryPoint); fn.Type() == lua.LTFunction {
+		return fn, false
+	}
+	if fn := st.GetGlobal(legacyEntryPoint); fn.Type() == lua.LTFunction {
+		return fn, true
+	}
+	return lua.LNil, false
 }
 
-func New(config Config) (plugins.Interceptor, error) {
+func (p *Plugin) newState() (*lua.LState, error) {
 	st := lua.NewState()
-	if err := st.DoString(config.Script); err != nil {
-		return nil, err
+	for k, v := range p.variables {
+		st.SetGlobal(k, toLua(st, v))
+	}
+	st.Push(st.NewFunctionFromProto(p.proto))
+	if err := st.PCall(0, lua.MultRet, nil); err != nil {
+		st.Close()
+		return nil, xerrors.Errorf("unable to run script: %w", err)
+	}
+	if fn, _ := entry(st); fn == lua.LNil {
+		st.Close()
+		return nil, xerrors.Errorf("entry point %s not found", entryPoint)
 	}
-	fn := st.GetGlobal("check_visibility")
-	if fn == lua.LNil {
-		return nil, fmt.Errorf("entry point check_visibility not found")
+	return st, nil
+}
+
+func (p *Plugin) get() (*lua.LState, error) {
+	select {
+	case st := <-p.pool:
+		return st, nil
+	default:
+		return p.newState()
+	}
+}
+
+func (p *Plugin) put(st *lua.LState) {
+	select {
+	case p.pool <- st:
+	default:
+		st.Close()
 	}
-	st.Close()
-	return &Plugin{
-		script: config.Script,
-	}, nil
 }
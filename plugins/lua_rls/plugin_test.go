@@ -0,0 +1,125 @@
+package luarls
+
+import (
+	"context"
+	"testing"
+
+	"github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/plugins"
+	"github.com/centralmind/gateway/xcontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterRows(t *testing.T) {
+	plugin, err := New(Config{
+		Script: `
+function filter_rows(row, context)
+  if context.role == "admin" then
+    return true
+  end
+  if row.tenant_id ~= context.claims.tenant_id or not row.active then
+    return false
+  end
+  row.email = mask
+  row.tags_count = #row.tags
+  row.internal = nil
+  return true
+end
+`,
+		Variables: map[string]any{"mask": "***"},
+	})
+	require.NoError(t, err)
+
+	rows := func() []map[string]any {
+		return []map[string]any{
+			{"id": int64(1), "tenant_id": int64(7), "active": true, "email": "a@b.c", "score": 1.5, "tags": []any{"x", "y"}, "meta": map[string]any{"k": "v"}, "internal": "secret"},
+			{"id": int64(2), "tenant_id": int64(7), "active": false, "tags": []any{}},
+			{"id": int64(3), "tenant_id": int64(8), "active": true, "tags": []any{}},
+		}
+	}
+
+	ctx := xcontext.WithClaims(context.Background(), map[string]any{"tenant_id": float64(7), "role": "support"})
+	res, err := plugin.Intercept(ctx, plugins.Call{}, rows())
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{
+		{"id": int64(1), "tenant_id": int64(7), "active": true, "email": "***", "score": 1.5, "tags": []any{"x", "y"}, "meta": map[string]any{"k": "v"}, "tags_count": int64(2)},
+	}, res)
+
+	ctx = xcontext.WithClaims(context.Background(), map[string]any{"role": "admin"})
+	res, err = plugin.Intercept(ctx, plugins.Call{}, rows())
+	require.NoError(t, err)
+	assert.Equal(t, rows(), res)
+}
+
+func TestFilterRowsReplacesRow(t *testing.T) {
+	plugin, err := New(Config{Script: `
+function filter_rows(row, context)
+  return {id = row.id, endpoint = context.endpoint, limit = context.params.limit, nothing = row.missing}
+end
+`})
+	require.NoError(t, err)
+
+	call := plugins.Call{Endpoint: model.Endpoint{MCPMethod: "list_orders"}, Params: map[string]any{"limit": 10}}
+	res, err := plugin.Intercept(context.Background(), call, []map[string]any{{"id": "a", "secret": "x"}})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"id": "a", "endpoint": "list_orders", "limit": int64(10)}}, res)
+}
+
+func TestCheckVisibility(t *testing.T) {
+	plugin, err := New(Config{Script: `
+function check_visibility(row, context)
+  return row.tenant_id ~= context["X-Tenant-Id"] or row.status == "1"
+end
+`})
+	require.NoError(t, err)
+
+	ctx := xcontext.WithHeader(context.Background(), map[string][]string{"X-Tenant-Id": {"1"}})
+	rows := []map[string]any{
+		{"id": 1, "tenant_id": 1, "status": 0},
+		{"id": 2, "tenant_id": 2, "status": 0},
+		{"id": 3, "tenant_id": 1, "status": 1},
+	}
+	res, err := plugin.Intercept(ctx, plugins.Call{}, rows)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"id": 1, "tenant_id": 1, "status": 0}}, res, "values are compared as strings")
+}
+
+func TestScriptErrorsFailClosed(t *testing.T) {
+	plugin, err := New(Config{Script: `
+function filter_rows(row, context)
+  if row.id == 2 then
+    error("boom")
+  end
+  return true
+end
+`})
+	require.NoError(t, err)
+
+	_, err = plugin.Intercept(context.Background(), plugins.Call{}, []map[string]any{{"id": 1}, {"id": 2}})
+	assert.ErrorContains(t, err, "boom")
+
+	// the failed state is dropped, the next call gets a working one
+	res, err := plugin.Intercept(context.Background(), plugins.Call{}, []map[string]any{{"id": 1}})
+	require.NoError(t, err)
+	assert.Len(t, res, 1)
+	assert.Len(t, plugin.pool, 1)
+
+	plugin, err = New(Config{Script: `function filter_rows(row, context) return "yes" end`})
+	require.NoError(t, err)
+	_, err = plugin.Intercept(context.Background(), plugins.Call{}, []map[string]any{{"id": 1}})
+	assert.Error(t, err)
+}
+
+func TestNewErrors(t *testing.T) {
+	for _, cfg := range []Config{
+		{Script: `function visible(row) return true end`},
+		{Script: `function filter_rows(row`},
+		{Script: `error("on load")`},
+		{Script: `function filter_rows() end`, ScriptFile: "rls.lua"},
+		{ScriptFile: "missing.lua"},
+	} {
+		_, err := New(cfg)
+		assert.Error(t, err, cfg)
+	}
+}
@@ -0,0 +1,132 @@
+package luarls
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// toLua converts a Go value to Lua keeping its type: numbers, bools, strings, nil,
+// maps and slices become tables, other values are passed as their string form
+func toLua(st *lua.LState, v any) lua.LValue {
+	switch v := v.(type) {
+	case nil:
+		return lua.LNil
+	case bool:
+		return lua.LBool(v)
+	case string:
+		return lua.LString(v)
+	case []byte:
+		return lua.LString(v)
+	case int:
+		return lua.LNumber(v)
+	case int8:
+		return lua.LNumber(v)
+	case int16:
+		return lua.LNumber(v)
+	case int32:
+		return lua.LNumber(v)
+	case int64:
+		return lua.LNumber(v)
+	case uint:
+		return lua.LNumber(v)
+	case uint8:
+		return lua.LNumber(v)
+	case uint16:
+		return lua.LNumber(v)
+	case uint32:
+		return lua.LNumber(v)
+	case uint64:
+		return lua.LNumber(v)
+	case float32:
+		return lua.LNumber(v)
+	case float64:
+		return lua.LNumber(v)
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return lua.LNumber(f)
+		}
+		return lua.LString(v)
+	case time.Time:
+		return lua.LString(v.Format(time.RFC3339Nano))
+	case map[string]any:
+		table := st.NewTable()
+		for k, item := range v {
+			table.RawSetString(k, toLua(st, item))
+		}
+		return table
+	case []any:
+		table := st.NewTable()
+		for _, item := range v {
+			table.Append(toLua(st, item))
+		}
+		return table
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return lua.LNil
+		}
+		return toLua(st, rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		table := st.NewTable()
+		for i := 0; i < rv.Len(); i++ {
+			table.Append(toLua(st, rv.Index(i).Interface()))
+		}
+		return table
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			table := st.NewTable()
+			iter := rv.MapRange()
+			for iter.Next() {
+				table.RawSetString(iter.Key().String(), toLua(st, iter.Value().Interface()))
+			}
+			return table
+		}
+	}
+	return lua.LString(fmt.Sprint(v))
+}
+
+// fromLua converts a Lua value back to Go: integral numbers become int64,
+// tables with only sequential integer keys become slices, other tables maps
+func fromLua(v lua.LValue) any {
+	switch v := v.(type) {
+	case *lua.LNilType:
+		return nil
+	case lua.LBool:
+		return bool(v)
+	case lua.LString:
+		return string(v)
+	case lua.LNumber:
+		f := float64(v)
+		if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			return int64(f)
+		}
+		return f
+	case *lua.LTable:
+		if n := v.MaxN(); n > 0 && n == tableLen(v) {
+			res := make([]any, 0, n)
+			for i := 1; i <= n; i++ {
+				res = append(res, fromLua(v.RawGetInt(i)))
+			}
+			return res
+		}
+		res := map[string]any{}
+		v.ForEach(func(k, item lua.LValue) {
+			res[k.String()] = fromLua(item)
+		})
+		return res
+	}
+	return v.String()
+}
+
+func tableLen(t *lua.LTable) int {
+	n := 0
+	t.ForEach(func(lua.LValue, lua.LValue) { n++ })
+	return n
+}
@@ -404,3 +404,43 @@ func TestBerlinResidencyAuthorization(t *testing.T) {
 		})
 	}
 }
+
+func TestVisibility(t *testing.T) {
+	rules := []AuthorizationRule{
+		{
+			Methods:     []string{"GetHealth"},
+			AllowPublic: true,
+		},
+		{
+			Methods: []string{"AdminGetUsers"},
+			ClaimRules: []ClaimRule{
+				{
+					Claim:     "site_admin",
+					Operation: "eq",
+					Value:     "true",
+				},
+			},
+		},
+		{
+			// Depends on request parameters, can't be decided before the call
+			Methods: []string{"GetOrganizationData"},
+			ClaimRules: []ClaimRule{
+				{
+					Claim:     "org",
+					Operation: "eq",
+					Value:     "{{.OrganizationName}}",
+				},
+			},
+		},
+	}
+	user := map[string]interface{}{
+		"site_admin": false,
+		"org":        "Double.Cloud",
+	}
+
+	require.NoError(t, checkVisibility(rules, "GetHealth", user))
+	require.ErrorIs(t, checkVisibility(rules, "AdminGetUsers", user), errors.ErrNotAuthorized)
+	require.NoError(t, checkVisibility(rules, "AdminGetUsers", map[string]interface{}{"site_admin": true}))
+	require.NoError(t, checkVisibility(rules, "GetOrganizationData", user))
+	require.ErrorIs(t, checkVisibility(rules, "UnknownMethod", user), errors.ErrNotAuthorized)
+}
@@ -1,34 +1,16 @@
 package oauth
 
-import "golang.org/x/oauth2"
+import (
+	"github.com/centralmind/gateway/claimrule"
+	"github.com/centralmind/gateway/plugins"
+	"golang.org/x/oauth2"
+)
 
 // ClaimRule represents a rule for checking a claim value
-type ClaimRule struct {
-	// Claim defines the path to the value in JWT or user data (e.g., "email", "groups[0]", "org.name")
-	Claim string `yaml:"claim"`
-
-	// Operation defines the comparison operation ("eq", "ne", "contains", "regex", "exists")
-	Operation string `yaml:"operation"`
-
-	// Value is the expected value for comparison
-	Value string `yaml:"value"`
-}
+type ClaimRule = claimrule.Rule
 
 // AuthorizationRule defines an authorization rule for a method or group of methods
-type AuthorizationRule struct {
-	// Methods defines the list of methods to which the rule applies
-	Methods []string `yaml:"methods"`
-
-	// AllowPublic allows public access without a token
-	AllowPublic bool `yaml:"allow_public"`
-
-	// RequireAllClaims determines if all ClaimRules must be true (AND)
-	// If false, one true rule is sufficient (OR)
-	RequireAllClaims bool `yaml:"require_all_claims"`
-
-	// ClaimRules list of claim validation rules
-	ClaimRules []ClaimRule `yaml:"claim_rules"`
-}
+type AuthorizationRule = claimrule.AuthorizationRule
 
 // Config represents OAuth plugin configuration
 type Config struct {
@@ -105,6 +87,10 @@ func (c Config) Tag() string {
 	return "oauth"
 }
 
+func (c Config) Order() int {
+	return plugins.OrderAuth
+}
+
 func (c Config) Doc() string {
 	return docString
 }
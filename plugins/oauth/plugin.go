@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/centralmind/gateway/prompter"
+	"github.com/hashicorp/golang-lru/v2/expirable"
 	"github.com/sirupsen/logrus"
 
 	gerrors "github.com/centralmind/gateway/errors"
@@ -38,6 +39,7 @@ type PluginBundle interface {
 	plugins.Swaggerer
 	plugins.HTTPServer
 	plugins.MCPToolEnricher
+	plugins.Visibility
 }
 
 func New(cfg Config) (PluginBundle, error) {
@@ -48,8 +50,9 @@ func New(cfg Config) (PluginBundle, error) {
 	}
 
 	plugin := &Plugin{
-		config:      cfg,
-		oauthConfig: oauthConfig,
+		config:       cfg,
+		oauthConfig:  oauthConfig,
+		visibleCache: expirable.NewLRU[string, map[string]any](1024, nil, time.Minute),
 	}
 
 	return plugin, nil
@@ -60,6 +63,8 @@ type Plugin struct {
 	oauthConfig             *oauth2.Config
 	tokenRateLimiter        *SimpleRateLimiter
 	registrationRateLimiter *SimpleRateLimiter
+	// visibleCache keeps validated claims per token, so listing tools doesn't hit IDP for every tool
+	visibleCache *expirable.LRU[string, map[string]any]
 }
 
 func (p *Plugin) EnrichMCP(tooler plugins.MCPTooler) {
@@ -140,6 +145,33 @@ Prompt to user a link as markdown link above.
 	})
 }
 
+// Visible hides methods that the authenticated caller can't pass authorization rules for.
+// Callers without a token see everything, since calling a tool is what starts the auth flow.
+func (p *Plugin) Visible(ctx context.Context, method string) bool {
+	if len(p.config.AuthorizationRules) == 0 {
+		return true
+	}
+	authHeader := xcontext.Header(ctx, p.config.TokenHeader)
+	if authHeader == "" {
+		ss, ok := authorizedSessions.Load(xcontext.Session(ctx))
+		if !ok {
+			return true
+		}
+		authHeader = ss.(string)
+	}
+	token := strings.Replace(authHeader, "Bearer ", "", 1)
+	claims, ok := p.visibleCache.Get(token)
+	if !ok {
+		var err error
+		claims, err = validateToken(ctx, p.config, token)
+		if err != nil {
+			return true
+		}
+		p.visibleCache.Add(token, claims)
+	}
+	return checkVisibility(p.config.AuthorizationRules, method, claims) == nil
+}
+
 func (p *Plugin) RegisterRoutes(mux *http.ServeMux) {
 	if p.config.AuthURL == "" || p.config.CallbackURL == "" {
 		return
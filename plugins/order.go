@@ -0,0 +1,113 @@
+package plugins
+
+import (
+	"sort"
+	"strconv"
+
+	"golang.org/x/xerrors"
+)
+
+// Default positions of plugin kinds in the chain, lower comes first on the request path:
+// telemetry sees every call, auth rejects callers before cache lookups, rows are filtered before masking.
+const (
+	OrderTelemetry = 100
+	OrderAuth      = 200
+	OrderRowFilter = 300
+	// OrderColumnMasking places wrappers masking rows per caller outside of the cache
+	OrderColumnMasking = 350
+	OrderCache         = 400
+	OrderMasking       = 500
+	OrderDefault       = 1000
+)
+
+// Orderer is implemented by plugin configs to place the plugin in the chain by default,
+// plugins without it get OrderDefault. The `order` key of a plugin config overrides it.
+type Orderer interface {
+	Order() int
+}
+
+// orderKey is the reserved plugin config key placing the plugin in the chain
+const orderKey = "order"
+
+// link is a configured plugin in the chain
+type link struct {
+	tag    string
+	order  int
+	config any
+}
+
+// chain returns registered plugins of the config sorted by order, then by tag.
+// Unknown plugins are left out.
+func chain(pluginsCfg map[string]any) ([]link, error) {
+	var res []link
+	for tag, raw := range pluginsCfg {
+		if _, ok := plugins[tag]; !ok {
+			continue
+		}
+		order := OrderDefault
+		if orderer, ok := configs[tag].(Orderer); ok {
+			order = orderer.Order()
+		}
+		config := raw
+		if m, ok := raw.(map[string]any); ok {
+			if v, ok := m[orderKey]; ok {
+				var err error
+				if order, err = parseOrder(v); err != nil {
+					return nil, xerrors.Errorf("plugin %s: %w", tag, err)
+				}
+				config = withoutKey(m, orderKey)
+			}
+		}
+		res = append(res, link{tag: tag, order: order, config: config})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].order != res[j].order {
+			return res[i].order < res[j].order
+		}
+		return res[i].tag < res[j].tag
+	})
+	return res, nil
+}
+
+// Chain returns tags of configured plugins in the order they are applied
+func Chain(pluginsCfg map[string]any) ([]string, error) {
+	links, err := chain(pluginsCfg)
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]string, len(links))
+	for i, l := range links {
+		tags[i] = l.tag
+	}
+	return tags, nil
+}
+
+func parseOrder(v any) (int, error) {
+	switch v := v.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case uint64:
+		return int(v), nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	case string:
+		if n, err := strconv.Atoi(v); err == nil {
+			return n, nil
+		}
+	}
+	return 0, xerrors.Errorf("invalid order %v, expected an integer", v)
+}
+
+func withoutKey(m map[string]any, key string) map[string]any {
+	res := make(map[string]any, len(m))
+	for k, v := range m {
+		if k != key {
+			res[k] = v
+		}
+	}
+	return res
+}
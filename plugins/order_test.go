@@ -0,0 +1,118 @@
+package plugins
+
+import (
+	"context"
+	"testing"
+
+	"github.com/centralmind/gateway/connectors"
+	"github.com/centralmind/gateway/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// calls records the order plugins see a query in
+type calls []string
+
+type recorderConfig struct {
+	Name string `yaml:"name"`
+}
+
+func (c recorderConfig) Tag() string { return "test_recorder" }
+func (c recorderConfig) Doc() string { return "" }
+
+type authConfig struct {
+	Name string `yaml:"name"`
+}
+
+func (c authConfig) Tag() string { return "test_auth" }
+func (c authConfig) Doc() string { return "" }
+func (c authConfig) Order() int  { return OrderAuth }
+
+type maskConfig struct{}
+
+func (c maskConfig) Tag() string { return "test_mask" }
+func (c maskConfig) Doc() string { return "" }
+func (c maskConfig) Order() int  { return OrderMasking }
+
+var recorded *calls
+
+type recorder struct {
+	name string
+}
+
+func (r recorder) Doc() string { return "" }
+
+func (r recorder) Wrap(connector connectors.Connector) (connectors.Connector, error) {
+	return &recordingConnector{Connector: connector, name: r.name}, nil
+}
+
+type recordingConnector struct {
+	connectors.Connector
+	name string
+}
+
+func (c *recordingConnector) Query(ctx context.Context, endpoint model.Endpoint, params map[string]any) ([]map[string]any, error) {
+	*recorded = append(*recorded, c.name)
+	return c.Connector.Query(ctx, endpoint, params)
+}
+
+type masker struct{}
+
+func (m masker) Doc() string { return "" }
+
+func (m masker) Process(data map[string]any, _ map[string][]string) (map[string]any, bool) {
+	return data, false
+}
+
+type stubConnector struct {
+	connectors.Connector
+	closed *int
+}
+
+func (stubConnector) Query(context.Context, model.Endpoint, map[string]any) ([]map[string]any, error) {
+	return nil, nil
+}
+
+func (s stubConnector) Close() error {
+	*s.closed++
+	return nil
+}
+
+func init() {
+	Register(func(cfg recorderConfig) (Wrapper, error) { return recorder{name: cfg.Name}, nil })
+	Register(func(cfg authConfig) (Wrapper, error) { return recorder{name: cfg.Name}, nil })
+	Register(func(cfg maskConfig) (Interceptor, error) { return masker{}, nil })
+}
+
+func TestChainOrder(t *testing.T) {
+	cfg := map[string]any{
+		"test_recorder": map[string]any{"name": "recorder"},
+		"test_auth":     map[string]any{"name": "auth"},
+		"test_mask":     nil,
+		"unknown":       map[string]any{},
+	}
+	chain, err := Chain(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test_auth", "test_mask", "test_recorder"}, chain)
+
+	// order in the config moves the plugin and is not passed to the plugin itself
+	cfg["test_recorder"] = map[string]any{"name": "recorder", "order": 50}
+	chain, err = Chain(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test_recorder", "test_auth", "test_mask"}, chain)
+
+	recorded = &calls{}
+	connector, err := Wrap(cfg, stubConnector{closed: new(int)})
+	require.NoError(t, err)
+	_, err = connector.Query(context.Background(), model.Endpoint{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, calls{"recorder", "auth"}, *recorded, "the first plugin of the chain is the outermost")
+
+	interceptors, err := Plugins[Interceptor](cfg)
+	require.NoError(t, err)
+	assert.Len(t, interceptors, 1)
+
+	cfg["test_auth"] = map[string]any{"order": "first"}
+	_, err = Chain(cfg)
+	assert.Error(t, err)
+}
@@ -1,6 +1,10 @@
 package otel
 
-import "time"
+import (
+	"time"
+
+	"github.com/centralmind/gateway/plugins"
+)
 
 // Config represents OpenTelemetry configuration
 type Config struct {
@@ -39,6 +43,10 @@ func (c Config) Tag() string {
 	return "otel"
 }
 
+func (c Config) Order() int {
+	return plugins.OrderTelemetry
+}
+
 func (c Config) Doc() string {
 	return docString
 }
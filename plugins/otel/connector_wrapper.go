@@ -2,6 +2,7 @@ package otel
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -33,6 +34,13 @@ func (c Connector) Ping(ctx context.Context) error {
 	return c.inner.Ping(ctx)
 }
 
+// Close flushes pending spans before closing the inner connector
+func (c Connector) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return errors.Join(c.tp.Shutdown(ctx), c.inner.Close())
+}
+
 func (c Connector) Query(ctx context.Context, endpoint model.Endpoint, params map[string]any) ([]map[string]any, error) {
 	tracer := c.tp.Tracer("database-connector")
 	ctx, span := tracer.Start(ctx, endpoint.MCPMethod,
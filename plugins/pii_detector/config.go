@@ -0,0 +1,57 @@
+package piidetector
+
+import "github.com/centralmind/gateway/plugins"
+
+// Config represents PII detection configuration
+type Config struct {
+	// Entities are the entity types to detect, all supported types by default
+	Entities []string `yaml:"entities"`
+
+	// ScoreThreshold is the lowest confidence of a reported entity, 0.5 by default
+	ScoreThreshold float64 `yaml:"score_threshold"`
+
+	// Columns are column name globs to analyze, e.g. "email" or "*_notes", all string columns by default
+	Columns []string `yaml:"columns"`
+
+	// ContextWords are additional words by entity type raising the confidence of nearby matches
+	ContextWords map[string][]string `yaml:"context_words"`
+
+	// Operators anonymize entities by type, the DEFAULT operator applies to other types
+	Operators map[string]Operator `yaml:"operators"`
+}
+
+// Operator defines how a found entity is anonymized
+type Operator struct {
+	// Type is one of replace (default), redact, mask or hash
+	Type string `yaml:"type"`
+
+	// NewValue replaces the entity with the replace operator, "<ENTITY_TYPE>" by default
+	NewValue string `yaml:"new_value"`
+
+	// MaskingChar masks characters with the mask operator, "*" by default
+	MaskingChar string `yaml:"masking_char"`
+
+	// CharsToMask is the number of masked characters, all by default
+	CharsToMask int `yaml:"chars_to_mask"`
+
+	// FromEnd masks the last characters instead of the first ones
+	FromEnd bool `yaml:"from_end"`
+
+	// HashType of the hash operator: sha256 (default) or sha512
+	HashType string `yaml:"hash_type"`
+
+	// Salt keys the hash operator, so values can't be recovered by hashing guesses
+	Salt string `yaml:"salt"`
+}
+
+func (c Config) Tag() string {
+	return "pii_detector"
+}
+
+func (c Config) Order() int {
+	return plugins.OrderMasking
+}
+
+func (c Config) Doc() string {
+	return docString
+}
@@ -0,0 +1,92 @@
+package piidetector
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/xerrors"
+)
+
+// defaultOperator is the key of the operator applied to entities without their own, as in Presidio
+const defaultOperator = "DEFAULT"
+
+// operator replaces the text of a found entity
+type operator func(entity, text string) string
+
+func newOperator(cfg Operator) (operator, error) {
+	switch cfg.Type {
+	case "", "replace":
+		return func(entity, _ string) string {
+			if cfg.NewValue != "" {
+				return cfg.NewValue
+			}
+			return "<" + entity + ">"
+		}, nil
+	case "redact":
+		return func(string, string) string { return "" }, nil
+	case "mask":
+		char := cfg.MaskingChar
+		if char == "" {
+			char = "*"
+		}
+		if utf8.RuneCountInString(char) != 1 {
+			return nil, xerrors.Errorf("masking_char must be a single character, got %q", char)
+		}
+		return func(_, text string) string {
+			return mask(text, char, cfg.CharsToMask, cfg.FromEnd)
+		}, nil
+	case "hash":
+		var h func() hash.Hash
+		switch cfg.HashType {
+		case "", "sha256":
+			h = sha256.New
+		case "sha512":
+			h = sha512.New
+		default:
+			return nil, xerrors.Errorf("unknown hash_type %s, expected sha256 or sha512", cfg.HashType)
+		}
+		return func(_, text string) string {
+			mac := hmac.New(h, []byte(cfg.Salt))
+			mac.Write([]byte(text))
+			return hex.EncodeToString(mac.Sum(nil))
+		}, nil
+	}
+	return nil, xerrors.Errorf("unknown operator %s, expected replace, redact, mask or hash", cfg.Type)
+}
+
+// mask replaces count characters of text with char, from the start or from the end, all characters when count is 0
+func mask(text, char string, count int, fromEnd bool) string {
+	runes := []rune(text)
+	if count <= 0 || count > len(runes) {
+		count = len(runes)
+	}
+	masked := []rune(strings.Repeat(char, count))
+	if fromEnd {
+		copy(runes[len(runes)-count:], masked)
+	} else {
+		copy(runes, masked)
+	}
+	return string(runes)
+}
+
+// anonymize replaces results in text with operators of their entities, results must not overlap
+func anonymize(text string, results []Result, operators map[string]operator) string {
+	var b strings.Builder
+	last := 0
+	for _, r := range results {
+		op, ok := operators[r.Entity]
+		if !ok {
+			op = operators[defaultOperator]
+		}
+		b.WriteString(text[last:r.Start])
+		b.WriteString(op(r.Entity, text[r.Start:r.End]))
+		last = r.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
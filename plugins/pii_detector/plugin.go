@@ -0,0 +1,111 @@
+package piidetector
+
+import (
+	"context"
+	_ "embed"
+	"strconv"
+
+	"github.com/centralmind/gateway/plugins"
+	"github.com/centralmind/gateway/xcontext"
+	"golang.org/x/xerrors"
+)
+
+//go:embed README.md
+var docString string
+
+func init() {
+	plugins.Register(func(cfg Config) (plugins.BatchInterceptor, error) {
+		return New(cfg)
+	})
+}
+
+const defaultThreshold = 0.5
+
+type Plugin struct {
+	analyzer  *analyzer
+	operators map[string]operator
+	columns   []string
+}
+
+func New(config Config) (*Plugin, error) {
+	threshold := config.ScoreThreshold
+	if threshold == 0 {
+		threshold = defaultThreshold
+	}
+	if threshold < 0 || threshold > 1 {
+		return nil, xerrors.Errorf("score_threshold must be between 0 and 1, got %v", threshold)
+	}
+	a, err := newAnalyzer(config.Entities, threshold, config.ContextWords)
+	if err != nil {
+		return nil, err
+	}
+	if err := plugins.ValidateColumns(config.Columns); err != nil {
+		return nil, err
+	}
+	p := &Plugin{
+		analyzer:  a,
+		operators: map[string]operator{},
+		columns:   config.Columns,
+	}
+	if _, ok := config.Operators[defaultOperator]; !ok {
+		p.operators[defaultOperator], _ = newOperator(Operator{})
+	}
+	for entity, cfg := range config.Operators {
+		if entity != defaultOperator && !contains(entities(), entity) {
+			return nil, xerrors.Errorf("operator for unknown entity %s", entity)
+		}
+		op, err := newOperator(cfg)
+		if err != nil {
+			return nil, xerrors.Errorf("operator for %s: %w", entity, err)
+		}
+		p.operators[entity] = op
+	}
+	return p, nil
+}
+
+func (p *Plugin) Doc() string {
+	return docString
+}
+
+// Intercept anonymizes entities found in string values of enabled columns, column names count as context words.
+// Rows are copied and the number of anonymized values is reported as Anonymized-Values metadata.
+func (p *Plugin) Intercept(ctx context.Context, _ plugins.Call, rows []map[string]any) ([]map[string]any, error) {
+	columns := map[string]*column{}
+	anonymized := 0
+	res := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		copied := make(map[string]any, len(row))
+		for name, v := range row {
+			c, ok := columns[name]
+			if !ok {
+				c = p.column(name)
+				columns[name] = c
+			}
+			if s, ok := v.(string); ok && c != nil {
+				if results := p.analyzer.analyze(s, c.context); len(results) > 0 {
+					v = anonymize(s, results, p.operators)
+					anonymized++
+				}
+			}
+			copied[name] = v
+		}
+		res[i] = copied
+	}
+	if anonymized > 0 {
+		xcontext.Stats(ctx).SetMeta("Anonymized-Values", strconv.Itoa(anonymized))
+	}
+	return res, nil
+}
+
+// column is an analyzed column with words of its name
+type column struct {
+	context []string
+}
+
+// column returns nil for columns that are not analyzed
+func (p *Plugin) column(name string) *column {
+	if len(p.columns) > 0 && !plugins.MatchColumn(p.columns, name) {
+		return nil
+	}
+	return &column{context: words(name)}
+}
@@ -0,0 +1,129 @@
+package piidetector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/centralmind/gateway/plugins"
+	"github.com/centralmind/gateway/xcontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyze(t *testing.T) {
+	a, err := newAnalyzer(nil, defaultThreshold, nil)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		text    string
+		context []string
+		want    map[string]string
+	}{
+		{text: "write to john.doe@example.com today", want: map[string]string{"john.doe@example.com": "EMAIL_ADDRESS"}},
+		{text: "call +1-555-123-4567 or (212) 555-1234", want: map[string]string{"+1-555-123-4567": "PHONE_NUMBER", "(212) 555-1234": "PHONE_NUMBER"}},
+		{text: "paid with 4111 1111 1111 1111", want: map[string]string{"4111 1111 1111 1111": "CREDIT_CARD"}},
+		{text: "card 4111-1111-1111-1112", want: map[string]string{}},
+		{text: "IBAN DE89 3704 0044 0532 0130 00, GB82WEST12345698765432", want: map[string]string{"DE89 3704 0044 0532 0130 00": "IBAN_CODE", "GB82WEST12345698765432": "IBAN_CODE"}},
+		{text: "DE88 3704 0044 0532 0130 00", want: map[string]string{}},
+		{text: "SSN 536-22-8726, not 000-12-3456 or 666-12-3456", want: map[string]string{"536-22-8726": "US_SSN"}},
+		{text: "ref 536228726", want: map[string]string{}},
+		{text: "536228726", context: []string{"customer", "ssn"}, want: map[string]string{"536228726": "US_SSN"}},
+		{text: "from 192.168.1.10 and 2001:db8::1 at 10:30:00", want: map[string]string{"192.168.1.10": "IP_ADDRESS", "2001:db8::1": "IP_ADDRESS"}},
+		{text: "ships to 742 Evergreen Terrace, Springfield, IL 62704 tomorrow", want: map[string]string{"742 Evergreen Terrace, Springfield, IL 62704": "STREET_ADDRESS"}},
+		{text: "PO Box 1234", want: map[string]string{"PO Box 1234": "STREET_ADDRESS"}},
+		{text: "order 12345 shipped on 2024-01-15 10:30, version 1.2, 999.999.999.999", want: map[string]string{}},
+	} {
+		t.Run(tc.text, func(t *testing.T) {
+			got := map[string]string{}
+			for _, r := range a.analyze(tc.text, tc.context) {
+				got[tc.text[r.Start:r.End]] = r.Entity
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestContextWords(t *testing.T) {
+	a, err := newAnalyzer([]string{"PHONE_NUMBER"}, defaultThreshold, map[string][]string{"PHONE_NUMBER": {"Viber"}})
+	require.NoError(t, err)
+	assert.Empty(t, a.analyze("ticket 555 1234", nil))
+	res := a.analyze("my viber is 555 1234", nil)
+	require.Len(t, res, 1)
+	assert.InDelta(t, 0.75, res[0].Score, 0.001)
+	assert.Len(t, a.analyze("555 1234", words("mobilePhone")), 1)
+}
+
+func TestOperators(t *testing.T) {
+	for _, tc := range []struct {
+		op   Operator
+		want string
+	}{
+		{op: Operator{}, want: "mail <EMAIL_ADDRESS>!"},
+		{op: Operator{Type: "replace", NewValue: "[email]"}, want: "mail [email]!"},
+		{op: Operator{Type: "redact"}, want: "mail !"},
+		{op: Operator{Type: "mask", CharsToMask: 3}, want: "mail ***@b.io!"},
+		{op: Operator{Type: "mask", MaskingChar: "#", CharsToMask: 4, FromEnd: true}, want: "mail abc@####!"},
+	} {
+		op, err := newOperator(tc.op)
+		require.NoError(t, err)
+		text := "mail abc@b.io!"
+		got := anonymize(text, []Result{{Entity: "EMAIL_ADDRESS", Start: 5, End: 13}}, map[string]operator{"EMAIL_ADDRESS": op})
+		assert.Equal(t, tc.want, got, tc.op)
+	}
+
+	hash, err := newOperator(Operator{Type: "hash", Salt: "s"})
+	require.NoError(t, err)
+	assert.Len(t, hash("", "a@b.io"), 64)
+	assert.Equal(t, hash("", "a@b.io"), hash("", "a@b.io"))
+	assert.NotEqual(t, hash("", "a@b.io"), hash("", "c@b.io"))
+
+	for _, op := range []Operator{{Type: "encrypt"}, {Type: "mask", MaskingChar: "**"}, {Type: "hash", HashType: "md5"}} {
+		_, err := newOperator(op)
+		assert.Error(t, err, op)
+	}
+}
+
+func TestIntercept(t *testing.T) {
+	plugin, err := New(Config{
+		Columns: []string{"notes", "*_ssn", "email"},
+		Operators: map[string]Operator{
+			"EMAIL_ADDRESS": {Type: "mask", CharsToMask: 4},
+			"US_SSN":        {Type: "redact"},
+		},
+	})
+	require.NoError(t, err)
+
+	rows := []map[string]any{{
+		"id":           1,
+		"email":        "john@example.com",
+		"customer_ssn": "536228726",
+		"notes":        "Called from +44 20 7946 0958, card 4111111111111111",
+		"comment":      "john@example.com",
+	}}
+	ctx, stats := xcontext.WithQueryStats(context.Background())
+	res, err := plugin.Intercept(ctx, plugins.Call{}, rows)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{
+		"id":           1,
+		"email":        "****@example.com",
+		"customer_ssn": "",
+		"notes":        "Called from <PHONE_NUMBER>, card <CREDIT_CARD>",
+		"comment":      "john@example.com",
+	}}, res)
+	assert.Equal(t, "john@example.com", rows[0]["email"], "rows are copied")
+	assert.Equal(t, map[string]string{"Anonymized-Values": "3"}, stats.Meta)
+}
+
+func TestConfigErrors(t *testing.T) {
+	for _, cfg := range []Config{
+		{Entities: []string{"PASSPORT"}},
+		{ScoreThreshold: 1.5},
+		{Columns: []string{"[a"}},
+		{Entities: []string{"US_SSN"}, ContextWords: map[string][]string{"PHONE_NUMBER": {"tel"}}},
+		{Operators: map[string]Operator{"PASSPORT": {}}},
+		{Operators: map[string]Operator{"DEFAULT": {Type: "encrypt"}}},
+	} {
+		_, err := New(cfg)
+		assert.Error(t, err, cfg)
+	}
+}
@@ -0,0 +1,393 @@
+package piidetector
+
+import (
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+const (
+	// contextBoost is added to the score of a match with a context word nearby
+	contextBoost = 0.35
+	// contextMinScore is the lowest score of a match with a context word nearby
+	contextMinScore = 0.4
+	// contextWindow is the number of words before a match searched for context words
+	contextWindow = 5
+)
+
+// Result is an entity found in a text, Start and End are byte offsets
+type Result struct {
+	Entity string
+	Start  int
+	End    int
+	Score  float64
+}
+
+// recognizer finds an entity by a pattern, score validates a match and returns its confidence, 0 drops it
+type recognizer struct {
+	entity  string
+	pattern *regexp.Regexp
+	score   func(match string) float64
+	context []string
+}
+
+var recognizers = []recognizer{
+	{
+		entity:  "EMAIL_ADDRESS",
+		pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+		score:   emailScore,
+		context: []string{"email", "mail"},
+	},
+	{
+		entity:  "PHONE_NUMBER",
+		pattern: regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?\(?\d{2,4}\)?[\s.-]?\d{2,4}[\s.-]?\d{2,4}(?:[\s.-]?\d{2,4})?`),
+		score:   phoneScore,
+		context: []string{"phone", "tel", "mobile", "cell", "fax", "call", "contact", "whatsapp"},
+	},
+	{
+		entity:  "CREDIT_CARD",
+		pattern: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		score:   cardScore,
+		context: []string{"credit", "card", "visa", "mastercard", "amex", "discover", "cc", "debit", "payment"},
+	},
+	{
+		entity:  "IBAN_CODE",
+		pattern: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`),
+		score:   ibanScore,
+		context: []string{"iban", "bank", "account", "transfer"},
+	},
+	{
+		entity:  "US_SSN",
+		pattern: regexp.MustCompile(`\b\d{3}[- .]?\d{2}[- .]?\d{4}\b`),
+		score:   ssnScore,
+		context: []string{"ssn", "social", "security"},
+	},
+	{
+		entity:  "IP_ADDRESS",
+		pattern: regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b|(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4}`),
+		score:   ipScore,
+		context: []string{"ip", "ipv4", "ipv6", "host", "server", "client"},
+	},
+	{
+		entity: "STREET_ADDRESS",
+		pattern: regexp.MustCompile(`\b\d{1,6}\s+(?:[A-Z][A-Za-z]*\.?\s+){1,4}(?i:street|st|avenue|ave|road|rd|boulevard|blvd|lane|ln|drive|dr|court|ct|way|place|pl|terrace|parkway|pkwy|highway|hwy|square|sq)\b\.?` +
+			`(?:,?\s+(?i:apt|suite|unit)\.?\s*[A-Za-z0-9-]+|,?\s+#\s*[A-Za-z0-9-]+)?` +
+			`(?:,\s*[A-Z][a-z]+(?:\s[A-Z][a-z]+)*)?(?:,?\s+[A-Z]{2})?(?:\s+\d{5}(?:-\d{4})?)?` +
+			`|\b(?i:p\.?\s?o\.?\s+box)\s+\d+\b`),
+		score:   addressScore,
+		context: []string{"address", "street", "addr", "shipping", "billing", "residence", "home", "mailing"},
+	},
+}
+
+// entities lists entities of all recognizers
+func entities() []string {
+	res := make([]string, len(recognizers))
+	for i, r := range recognizers {
+		res[i] = r.entity
+	}
+	return res
+}
+
+// analyzer runs recognizers of the configured entities over texts
+type analyzer struct {
+	recognizers []recognizer
+	threshold   float64
+}
+
+func newAnalyzer(enabled []string, threshold float64, contextWords map[string][]string) (*analyzer, error) {
+	if len(enabled) == 0 {
+		enabled = entities()
+	}
+	a := &analyzer{threshold: threshold}
+	for _, entity := range enabled {
+		found := false
+		for _, r := range recognizers {
+			if r.entity != entity {
+				continue
+			}
+			r.context = append(append([]string{}, r.context...), lower(contextWords[entity])...)
+			a.recognizers = append(a.recognizers, r)
+			found = true
+		}
+		if !found {
+			return nil, xerrors.Errorf("unknown entity %s, supported: %s", entity, strings.Join(entities(), ", "))
+		}
+	}
+	for entity := range contextWords {
+		if !contains(enabled, entity) {
+			return nil, xerrors.Errorf("context words for entity %s that is not enabled", entity)
+		}
+	}
+	return a, nil
+}
+
+// analyze returns entities found in text scoring at least the threshold, without overlaps.
+// Words of extraContext, e.g. of the column name, count as context of every match.
+func (a *analyzer) analyze(text string, extraContext []string) []Result {
+	var candidates []Result
+	for _, r := range a.recognizers {
+		for _, loc := range r.pattern.FindAllStringIndex(text, -1) {
+			start, end := loc[0], loc[1]
+			// a match glued to letters or digits is a part of a longer token
+			if start > 0 && isAlnum(text[start-1]) || end < len(text) && isAlnum(text[end]) {
+				continue
+			}
+			score := r.score(text[start:end])
+			if score <= 0 {
+				continue
+			}
+			if hasContext(r.context, words(text[:start]), extraContext) {
+				score = max(min(score+contextBoost, 1), contextMinScore)
+			}
+			if score >= a.threshold {
+				candidates = append(candidates, Result{Entity: r.entity, Start: start, End: end, Score: score})
+			}
+		}
+	}
+	return resolve(candidates)
+}
+
+// resolve keeps the best of overlapping results: higher score first, then the longer one
+func resolve(candidates []Result) []Result {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].End-candidates[i].Start > candidates[j].End-candidates[j].Start
+	})
+	var res []Result
+	for _, c := range candidates {
+		overlaps := false
+		for _, kept := range res {
+			if c.Start < kept.End && kept.Start < c.End {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			res = append(res, c)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Start < res[j].Start })
+	return res
+}
+
+// hasContext reports whether one of the last words before a match or an extra word contains a context word
+func hasContext(context, before, extra []string) bool {
+	if len(before) > contextWindow {
+		before = before[len(before)-contextWindow:]
+	}
+	for _, word := range append(before, extra...) {
+		for _, c := range context {
+			// short context words such as "cc" or "tel" match whole words only
+			if word == c || len(c) > 3 && strings.Contains(word, c) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var wordRe = regexp.MustCompile(`[A-Z]?[a-z]+|[A-Z]+`)
+
+// words splits text into lower case words, also snake_case and camelCase names
+func words(text string) []string {
+	return lower(wordRe.FindAllString(text, -1))
+}
+
+func emailScore(match string) float64 {
+	domain := match[strings.LastIndex(match, "@")+1:]
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" || label[0] == '-' || label[len(label)-1] == '-' {
+			return 0
+		}
+	}
+	return 1
+}
+
+// dateRe matches numbers starting with a date, e.g. 2024-01-15 or 2024-01-15 10 of a timestamp
+var dateRe = regexp.MustCompile(`^(?:\d{4}[-./]\d{1,2}[-./]\d{1,2}|\d{1,2}[-./]\d{1,2}[-./]\d{4})(?:\D|$)`)
+
+// phoneScore validates numbers the way libphonenumber does for unknown regions:
+// international numbers have 8 to 15 digits, national ones follow the North American plan
+func phoneScore(match string) float64 {
+	digits := onlyDigits(match)
+	if sameDigits(digits) || dateRe.MatchString(match) {
+		return 0
+	}
+	formatted := strings.ContainsAny(match, " .-()")
+	switch {
+	case strings.HasPrefix(match, "+"):
+		if len(digits) >= 8 && len(digits) <= 15 && digits[0] != '0' {
+			return 0.7
+		}
+		return 0
+	case northAmerican(digits):
+		if formatted {
+			return 0.6
+		}
+		return 0.4
+	case formatted && len(digits) >= 7 && len(digits) <= 12:
+		return 0.4
+	}
+	return 0
+}
+
+// northAmerican reports whether digits are a NANP number: area code and exchange don't start with 0 or 1
+func northAmerican(digits string) bool {
+	if len(digits) == 11 && digits[0] == '1' {
+		digits = digits[1:]
+	}
+	return len(digits) == 10 && digits[0] >= '2' && digits[3] >= '2'
+}
+
+// cardScore validates the Luhn checksum, numbers of known card networks score highest
+func cardScore(match string) float64 {
+	digits := onlyDigits(match)
+	if len(digits) < 13 || len(digits) > 19 || sameDigits(digits) || !luhn(digits) {
+		return 0
+	}
+	for _, prefix := range []string{"4", "34", "37", "51", "52", "53", "54", "55", "2221", "2720", "6011", "65", "35", "36", "38", "30", "62"} {
+		if strings.HasPrefix(digits, prefix) {
+			return 1
+		}
+	}
+	return 0.3
+}
+
+func luhn(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// ibanLengths are IBAN lengths of common countries
+var ibanLengths = map[string]int{
+	"AD": 24, "AE": 23, "AT": 20, "BE": 16, "BG": 22, "BR": 29, "CH": 21, "CY": 28, "CZ": 24, "DE": 22,
+	"DK": 18, "EE": 20, "ES": 24, "FI": 18, "FR": 27, "GB": 22, "GR": 27, "HR": 21, "HU": 28, "IE": 22,
+	"IL": 23, "IS": 26, "IT": 27, "LI": 21, "LT": 20, "LU": 20, "LV": 21, "MC": 27, "MT": 31, "NL": 18,
+	"NO": 15, "PL": 28, "PT": 25, "RO": 24, "SA": 24, "SE": 24, "SI": 19, "SK": 24, "SM": 27, "TR": 26, "UA": 29,
+}
+
+// ibanScore validates the mod 97 checksum and the length of the country
+func ibanScore(match string) float64 {
+	iban := strings.ReplaceAll(match, " ", "")
+	if len(iban) < 15 || len(iban) > 34 || !mod97(iban) {
+		return 0
+	}
+	length, known := ibanLengths[iban[:2]]
+	switch {
+	case !known:
+		return 0.6
+	case length != len(iban):
+		return 0
+	}
+	return 1
+}
+
+func mod97(iban string) bool {
+	rearranged := iban[4:] + iban[:4]
+	rem := 0
+	for i := 0; i < len(rearranged); i++ {
+		c := rearranged[i]
+		switch {
+		case c >= '0' && c <= '9':
+			rem = (rem*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			rem = (rem*100 + int(c-'A') + 10) % 97
+		default:
+			return false
+		}
+	}
+	return rem == 1
+}
+
+// ssnScore rejects numbers never issued as SSNs, unseparated numbers score lower
+func ssnScore(match string) float64 {
+	digits := onlyDigits(match)
+	area, group, serial := digits[:3], digits[3:5], digits[5:]
+	if area == "000" || area == "666" || area[0] == '9' || group == "00" || serial == "0000" || sameDigits(digits) {
+		return 0
+	}
+	switch digits {
+	case "123456789", "078051120", "219099999":
+		return 0
+	}
+	if len(digits) == len(match) {
+		return 0.3
+	}
+	// separators must be the same, e.g. 123-45 6789 is not an SSN
+	if match[3] != match[6] {
+		return 0
+	}
+	return 0.5
+}
+
+func ipScore(match string) float64 {
+	if strings.Trim(match, ":") == "" {
+		return 0
+	}
+	if net.ParseIP(match) == nil {
+		return 0
+	}
+	return 0.6
+}
+
+var zipRe = regexp.MustCompile(`(?:\s[A-Z]{2}|\s\d{5}(?:-\d{4})?)$`)
+
+// addressScore scores street addresses higher when they end with a state code or a ZIP code
+func addressScore(match string) float64 {
+	if zipRe.MatchString(match) {
+		return 0.7
+	}
+	return 0.5
+}
+
+func onlyDigits(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] >= '0' && s[i] <= '9' {
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func sameDigits(digits string) bool {
+	return strings.Count(digits, digits[:1]) == len(digits)
+}
+
+func isAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func lower(items []string) []string {
+	res := make([]string, len(items))
+	for i, item := range items {
+		res[i] = strings.ToLower(item)
+	}
+	return res
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
@@ -1,5 +1,7 @@
 package piiremover
 
+import "github.com/centralmind/gateway/plugins"
+
 // Config represents PII removal configuration
 type Config struct {
 	// Fields specifies which fields should be checked for PII
@@ -18,6 +20,10 @@ func (c Config) Tag() string {
 	return "pii_remover"
 }
 
+func (c Config) Order() int {
+	return plugins.OrderMasking
+}
+
 func (c Config) Doc() string {
 	return docString
 }
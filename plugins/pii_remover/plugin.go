@@ -1,19 +1,22 @@
 package piiremover
 
 import (
+	"context"
 	_ "embed"
 	"fmt"
 	"path"
 	"regexp"
+	"strconv"
 
 	"github.com/centralmind/gateway/plugins"
+	"github.com/centralmind/gateway/xcontext"
 )
 
 //go:embed README.md
 var docString string
 
 func init() {
-	plugins.Register(func(cfg Config) (plugins.Interceptor, error) {
+	plugins.Register(func(cfg Config) (plugins.BatchInterceptor, error) {
 		return New(cfg)
 	})
 }
@@ -28,36 +31,48 @@ func (p *Plugin) Doc() string {
 	return docString
 }
 
-func (p *Plugin) Process(data map[string]any, context map[string][]string) (processed map[string]any, skipped bool) {
+// Intercept redacts PII of every row and reports the number of redacted values as Redacted-Values metadata
+func (p *Plugin) Intercept(ctx context.Context, _ plugins.Call, rows []map[string]any) ([]map[string]any, error) {
+	redacted := 0
+	for _, row := range rows {
+		redacted += p.redact(row)
+	}
+	if redacted > 0 {
+		xcontext.Stats(ctx).SetMeta("Redacted-Values", strconv.Itoa(redacted))
+	}
+	return rows, nil
+}
+
+// redact replaces PII fields of data in place and returns how many were replaced
+func (p *Plugin) redact(data map[string]any) int {
+	redacted := 0
 	for k, v := range data {
-		if p.columns[k] {
+		if p.matches(k, v) {
 			data[k] = p.cfg.Replacement
-			continue
+			redacted++
 		}
+	}
+	return redacted
+}
 
-		for pattern := range p.columns {
-			if matched, _ := path.Match(pattern, k); matched {
-				data[k] = p.cfg.Replacement
-				break
-			}
+func (p *Plugin) matches(k string, v any) bool {
+	if p.columns[k] {
+		return true
+	}
+	for pattern := range p.columns {
+		if matched, _ := path.Match(pattern, k); matched {
+			return true
 		}
-
-		if strVal, ok := v.(string); ok {
-			for name, regex := range p.patterns {
-				if k != name {
-					continue
-				}
-				if regex.MatchString(strVal) {
-					data[k] = p.cfg.Replacement
-					break
-				}
-			}
+	}
+	if strVal, ok := v.(string); ok {
+		if regex, ok := p.patterns[k]; ok && regex.MatchString(strVal) {
+			return true
 		}
 	}
-	return data, false
+	return false
 }
 
-func New(config Config) (plugins.Interceptor, error) {
+func New(config Config) (*Plugin, error) {
 	p := &Plugin{
 		patterns: make(map[string]*regexp.Regexp),
 		columns:  make(map[string]bool),
@@ -1,9 +1,13 @@
 package piiremover
 
 import (
+	"context"
 	"testing"
 
+	"github.com/centralmind/gateway/plugins"
+	"github.com/centralmind/gateway/xcontext"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPIIRemover(t *testing.T) {
@@ -125,9 +129,9 @@ func TestPIIRemover(t *testing.T) {
 			plugin, err := New(tt.config)
 			assert.NoError(t, err)
 
-			result, skipped := plugin.Process(tt.input, nil)
-			assert.False(t, skipped)
-			assert.Equal(t, tt.expected, result)
+			result, err := plugin.Intercept(context.Background(), plugins.Call{}, []map[string]any{tt.input})
+			assert.NoError(t, err)
+			assert.Equal(t, []map[string]any{tt.expected}, result)
 		})
 	}
 }
@@ -143,3 +147,14 @@ func TestInvalidRegex(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid detection rule pattern")
 }
+
+func TestRedactedValuesMeta(t *testing.T) {
+	plugin, err := New(Config{Fields: []string{"email"}})
+	require.NoError(t, err)
+
+	ctx, stats := xcontext.WithQueryStats(context.Background())
+	rows := []map[string]any{{"email": "a@b.c", "id": 1}, {"email": "d@e.f", "id": 2}, {"id": 3}}
+	_, err = plugin.Intercept(ctx, plugins.Call{}, rows)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"Redacted-Values": "2"}, stats.Meta)
+}
@@ -2,6 +2,7 @@ package presidioanonymizer
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -26,7 +27,7 @@ func NewPresidioClient(analyzerURL, anonymizerURL string) *PresidioClient {
 }
 
 // Analyze sends request to Presidio Analyzer API
-func (c *PresidioClient) Analyze(text string, templates []analyzeTemplate, language string) ([]analyzeResult, error) {
+func (c *PresidioClient) Analyze(ctx context.Context, text string, templates []analyzeTemplate, language string) ([]analyzeResult, error) {
 	req := analyzerRequest{
 		Text:             text,
 		AnalyzeTemplates: templates,
@@ -38,7 +39,7 @@ func (c *PresidioClient) Analyze(text string, templates []analyzeTemplate, langu
 		return nil, xerrors.Errorf("error marshaling analyzer request: %w", err)
 	}
 
-	resp, err := c.httpClient.Post(c.analyzerURL, "application/json", bytes.NewBuffer(body))
+	resp, err := c.post(ctx, c.analyzerURL, body)
 	if err != nil {
 		return nil, xerrors.Errorf("error calling Presidio Analyzer API: %w", err)
 	}
@@ -58,7 +59,7 @@ func (c *PresidioClient) Analyze(text string, templates []analyzeTemplate, langu
 }
 
 // Anonymize sends request to Presidio Anonymizer API
-func (c *PresidioClient) Anonymize(text string, anonymizers map[string]PresidioAnonymizer, analyzerResults []analyzeResult) (string, error) {
+func (c *PresidioClient) Anonymize(ctx context.Context, text string, anonymizers map[string]PresidioAnonymizer, analyzerResults []analyzeResult) (string, error) {
 	req := anonymizeRequest{
 		Text:        text,
 		Anonymizers: anonymizers,
@@ -70,7 +71,7 @@ func (c *PresidioClient) Anonymize(text string, anonymizers map[string]PresidioA
 		return "", xerrors.Errorf("error marshaling anonymize request: %w", err)
 	}
 
-	resp, err := c.httpClient.Post(c.anonymizerURL, "application/json", bytes.NewBuffer(body))
+	resp, err := c.post(ctx, c.anonymizerURL, body)
 	if err != nil {
 		return "", xerrors.Errorf("error calling Presidio Anonymizer API: %w", err)
 	}
@@ -88,3 +89,12 @@ func (c *PresidioClient) Anonymize(text string, anonymizers map[string]PresidioA
 
 	return result.Text, nil
 }
+
+func (c *PresidioClient) post(ctx context.Context, url string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.httpClient.Do(req)
+}
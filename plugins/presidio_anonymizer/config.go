@@ -1,5 +1,7 @@
 package presidioanonymizer
 
+import "github.com/centralmind/gateway/plugins"
+
 // Config represents the configuration for the Presidio Anonymizer plugin
 type Config struct {
 	// AnonymizeURL is the URL of the Presidio Anonymizer API
@@ -14,6 +16,14 @@ type Config struct {
 	EncryptKey string `json:"encrypt_key" yaml:"encrypt_key"`
 	// AnonymizerRules defines the anonymization rules that apply to detected entities
 	AnonymizerRules []AnonymizerRule `json:"anonymizer_rules" yaml:"anonymizer_rules"`
+	// Columns are column name globs to anonymize, e.g. "email" or "*_notes" (default: all string columns)
+	Columns []string `json:"columns" yaml:"columns"`
+	// Concurrency is the maximum number of values sent to Presidio at once (default: 8)
+	Concurrency int `json:"concurrency" yaml:"concurrency"`
+	// CacheSize is the number of anonymized values kept in memory, repeated values skip Presidio (default: 10000)
+	CacheSize int `json:"cache_size" yaml:"cache_size"`
+	// FailOpen returns values as is when Presidio fails, by default the call fails
+	FailOpen bool `json:"fail_open" yaml:"fail_open"`
 }
 
 // AnonymizerRule defines how to anonymize a specific type of PII
@@ -37,3 +47,7 @@ func (c Config) Doc() string {
 func (c Config) Tag() string {
 	return "presidio_anonymizer"
 }
+
+func (c Config) Order() int {
+	return plugins.OrderMasking
+}
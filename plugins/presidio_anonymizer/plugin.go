@@ -1,64 +1,191 @@
 package presidioanonymizer
 
 import (
+	"context"
 	_ "embed"
-	"encoding/json"
-	"github.com/sirupsen/logrus"
-	"golang.org/x/xerrors"
+	"strconv"
+	"sync"
 
 	"github.com/centralmind/gateway/plugins"
+	"github.com/centralmind/gateway/xcontext"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
 )
 
 //go:embed README.md
 var docString string
 
 func init() {
-	plugins.Register(func(cfg Config) (plugins.Interceptor, error) {
+	plugins.Register(func(cfg Config) (plugins.BatchInterceptor, error) {
 		return New(cfg)
 	})
 }
 
+const (
+	defaultConcurrency = 8
+	defaultCacheSize   = 10000
+)
+
 type Plugin struct {
-	cfg    Config
-	client *PresidioClient
+	cfg         Config
+	client      *PresidioClient
+	templates   []analyzeTemplate
+	anonymizers map[string]PresidioAnonymizer
+	// cache maps analyzed values to their anonymized form
+	cache *lru.Cache[string, string]
 }
 
 func (p *Plugin) Doc() string {
 	return docString
 }
 
-func (p *Plugin) Process(data map[string]any, _ map[string][]string) (processed map[string]any, skipped bool) {
-	// Convert data to JSON string for batch processing
-	jsonData, err := json.Marshal(data)
+// Intercept anonymizes string values of enabled columns. Each distinct value is sent to Presidio once,
+// by at most Concurrency workers, and values seen before are taken from the cache.
+// Unless FailOpen is set, a value that fails to be anonymized fails the call so that PII is never returned as is.
+func (p *Plugin) Intercept(ctx context.Context, _ plugins.Call, rows []map[string]any) ([]map[string]any, error) {
+	enabled := map[string]bool{}
+	anonymized := map[string]string{}
+	var missing []string
+	for _, row := range rows {
+		for column, v := range row {
+			s, ok := v.(string)
+			if !ok || s == "" || !p.enabled(enabled, column) {
+				continue
+			}
+			if _, ok := anonymized[s]; ok {
+				continue
+			}
+			if res, ok := p.cache.Get(s); ok {
+				anonymized[s] = res
+				continue
+			}
+			// kept as is until anonymized
+			anonymized[s] = s
+			missing = append(missing, s)
+		}
+	}
+
+	results, errs, err := p.anonymizeAll(ctx, missing)
 	if err != nil {
-		logrus.Errorf("Error marshaling data: %v\n", err)
-		return data, false
+		return nil, err
+	}
+	failed := 0
+	for i, value := range missing {
+		if errs[i] != nil {
+			failed++
+			continue
+		}
+		anonymized[value] = results[i]
+		p.cache.Add(value, results[i])
+	}
+	if failed > 0 {
+		logrus.Warnf("presidio_anonymizer: %d values returned as is: %v", failed, firstError(errs))
+		xcontext.Stats(ctx).SetMeta("Anonymizer-Errors", strconv.Itoa(failed))
 	}
 
-	// Convert rules to analyzer templates
-	analyzeTemplates := make([]analyzeTemplate, 0)
-	for _, rule := range p.cfg.AnonymizerRules {
-		template := analyzeTemplate{
-			PII: rule.Type,
+	changed := 0
+	res := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		copied := make(map[string]any, len(row))
+		for column, v := range row {
+			if s, ok := v.(string); ok && enabled[column] {
+				if a, ok := anonymized[s]; ok && a != s {
+					v = a
+					changed++
+				}
+			}
+			copied[column] = v
 		}
-		analyzeTemplates = append(analyzeTemplates, template)
+		res[i] = copied
+	}
+	if changed > 0 {
+		xcontext.Stats(ctx).SetMeta("Anonymized-Values", strconv.Itoa(changed))
 	}
+	return res, nil
+}
 
-	// Call Analyzer API
-	analyzerResults, err := p.client.Analyze(string(jsonData), analyzeTemplates, p.cfg.Language)
-	if err != nil {
-		logrus.Errorf("Error analyzing data: %v\n", err)
-		return data, false
+// enabled reports whether values of column are anonymized, memoized in known
+func (p *Plugin) enabled(known map[string]bool, column string) bool {
+	if ok, seen := known[column]; seen {
+		return ok
 	}
+	ok := len(p.cfg.Columns) == 0 || plugins.MatchColumn(p.cfg.Columns, column)
+	known[column] = ok
+	return ok
+}
 
-	// If no PII found, return original data
+// anonymizeAll anonymizes values with bounded concurrency and returns per value results and errors.
+// Unless FailOpen is set, the first error stops remaining requests and is returned.
+func (p *Plugin) anonymizeAll(ctx context.Context, values []string) ([]string, []error, error) {
+	results := make([]string, len(values))
+	errs := make([]error, len(values))
+	if len(values) == 0 {
+		return results, errs, nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		once     sync.Once
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	jobs := make(chan int)
+	for w := 0; w < min(p.cfg.Concurrency, len(values)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if errs[i] = ctx.Err(); errs[i] == nil {
+					results[i], errs[i] = p.anonymize(ctx, values[i])
+				}
+				if errs[i] != nil && !p.cfg.FailOpen {
+					once.Do(func() {
+						firstErr = errs[i]
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+	for i := range values {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results, errs, firstErr
+}
+
+// anonymize returns value with PII found by Presidio replaced
+func (p *Plugin) anonymize(ctx context.Context, value string) (string, error) {
+	analyzerResults, err := p.client.Analyze(ctx, value, p.templates, p.cfg.Language)
+	if err != nil {
+		return "", xerrors.Errorf("unable to analyze data: %w", err)
+	}
 	if len(analyzerResults) == 0 {
-		return data, false
+		return value, nil
+	}
+	anonymizedText, err := p.client.Anonymize(ctx, value, p.anonymizers, analyzerResults)
+	if err != nil {
+		return "", xerrors.Errorf("unable to anonymize data: %w", err)
 	}
+	return anonymizedText, nil
+}
 
-	// Convert rules to Presidio format
-	anonymizers := make(map[string]PresidioAnonymizer)
-	for _, rule := range p.cfg.AnonymizerRules {
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// anonymizers converts rules to Presidio format
+func anonymizers(cfg Config) map[string]PresidioAnonymizer {
+	res := make(map[string]PresidioAnonymizer)
+	for _, rule := range cfg.AnonymizerRules {
 		anonymizer := PresidioAnonymizer{}
 
 		switch rule.Operator {
@@ -72,40 +199,20 @@ func (p *Plugin) Process(data map[string]any, _ map[string][]string) (processed
 			anonymizer.NewValue = rule.NewValue
 		case "hash":
 			anonymizer.Type = "hash"
-			anonymizer.HashType = p.cfg.HashType
+			anonymizer.HashType = cfg.HashType
 		case "encrypt":
 			anonymizer.Type = "encrypt"
-			anonymizer.CryptoKey = p.cfg.EncryptKey
+			anonymizer.CryptoKey = cfg.EncryptKey
 		default:
 			anonymizer.Type = "replace"
 		}
 
-		anonymizers[rule.Type] = anonymizer
-	}
-
-	// Call Anonymizer API
-	anonymizedText, err := p.client.Anonymize(string(jsonData), anonymizers, analyzerResults)
-	if err != nil {
-		logrus.Errorf("Error anonymizing data: %v\n", err)
-		return data, false
-	}
-
-	// Parse the anonymized JSON back into the data map
-	var anonymizedData map[string]any
-	if err := json.Unmarshal([]byte(anonymizedText), &anonymizedData); err != nil {
-		logrus.Errorf("Error unmarshaling anonymized data: %v\n", err)
-		return data, false
-	}
-
-	// Update all fields with anonymized data
-	for field, val := range anonymizedData {
-		data[field] = val
+		res[rule.Type] = anonymizer
 	}
-
-	return data, false
+	return res
 }
 
-func New(config Config) (plugins.Interceptor, error) {
+func New(config Config) (*Plugin, error) {
 	if config.AnonymizeURL == "" {
 		return nil, xerrors.Errorf("presidio_url is required")
 	}
@@ -115,11 +222,30 @@ func New(config Config) (plugins.Interceptor, error) {
 	if config.Language == "" {
 		config.Language = "en"
 	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = defaultConcurrency
+	}
+	if config.CacheSize <= 0 {
+		config.CacheSize = defaultCacheSize
+	}
+	if err := plugins.ValidateColumns(config.Columns); err != nil {
+		return nil, err
+	}
+	cache, err := lru.New[string, string](config.CacheSize)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to create cache: %w", err)
+	}
 
-	client := NewPresidioClient(config.AnalyzerURL, config.AnonymizeURL)
+	templates := make([]analyzeTemplate, 0, len(config.AnonymizerRules))
+	for _, rule := range config.AnonymizerRules {
+		templates = append(templates, analyzeTemplate{PII: rule.Type})
+	}
 
 	return &Plugin{
-		cfg:    config,
-		client: client,
+		cfg:         config,
+		client:      NewPresidioClient(config.AnalyzerURL, config.AnonymizeURL),
+		templates:   templates,
+		anonymizers: anonymizers(config),
+		cache:       cache,
 	}, nil
 }
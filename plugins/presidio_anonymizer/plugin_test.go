@@ -2,9 +2,17 @@ package presidioanonymizer
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/centralmind/gateway/plugins"
+	"github.com/centralmind/gateway/xcontext"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go"
@@ -112,9 +120,116 @@ func TestPluginWithContainer(t *testing.T) {
 	// Run test cases
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, skipped := plugin.Process(tt.input, nil)
-			assert.False(t, skipped)
-			assert.Equal(t, tt.expected, result)
+			result, err := plugin.Intercept(ctx, plugins.Call{}, []map[string]any{tt.input})
+			require.NoError(t, err)
+			assert.Equal(t, []map[string]any{tt.expected}, result)
 		})
 	}
 }
+
+// presidioStub stands in for the analyzer and anonymizer, it detects emails and replaces them with <EMAIL_ADDRESS>
+type presidioStub struct {
+	analyzed atomic.Int32
+	inFlight atomic.Int32
+	maxIn    atomic.Int32
+	fail     atomic.Bool
+}
+
+var emailRe = regexp.MustCompile(`[\w.]+@[\w.]+`)
+
+func (s *presidioStub) start(t *testing.T) (analyzer, anonymizer string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/analyze", func(w http.ResponseWriter, r *http.Request) {
+		n := s.inFlight.Add(1)
+		defer s.inFlight.Add(-1)
+		for peak := s.maxIn.Load(); n > peak && !s.maxIn.CompareAndSwap(peak, n); peak = s.maxIn.Load() {
+		}
+		s.analyzed.Add(1)
+		time.Sleep(time.Millisecond)
+		if s.fail.Load() {
+			http.Error(w, "analyzer is down", http.StatusServiceUnavailable)
+			return
+		}
+		var req analyzerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		results := []analyzeResult{}
+		for _, loc := range emailRe.FindAllStringIndex(req.Text, -1) {
+			results = append(results, analyzeResult{Type: "EMAIL_ADDRESS", Score: 1, StartIndex: loc[0], EndIndex: loc[1]})
+		}
+		_ = json.NewEncoder(w).Encode(results)
+	})
+	mux.HandleFunc("/anonymize", func(w http.ResponseWriter, r *http.Request) {
+		var req anonymizeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(anonymizeResponse{Text: emailRe.ReplaceAllString(req.Text, "<EMAIL_ADDRESS>")})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server.URL + "/analyze", server.URL + "/anonymize"
+}
+
+func TestInterceptBatchesAndCaches(t *testing.T) {
+	stub := &presidioStub{}
+	analyzer, anonymizer := stub.start(t)
+	plugin, err := New(Config{
+		AnalyzerURL:  analyzer,
+		AnonymizeURL: anonymizer,
+		Columns:      []string{"email", "*_notes"},
+		Concurrency:  4,
+	})
+	require.NoError(t, err)
+
+	var rows []map[string]any
+	for i := 0; i < 1000; i++ {
+		rows = append(rows, map[string]any{
+			"id":          i,
+			"email":       fmt.Sprintf("user%d@example.com", i%50),
+			"admin_notes": "call me at boss@example.com",
+			"name":        "john@example.com",
+		})
+	}
+	ctx, stats := xcontext.WithQueryStats(context.Background())
+	res, err := plugin.Intercept(ctx, plugins.Call{}, rows)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"id": 7, "email": "<EMAIL_ADDRESS>", "admin_notes": "call me at <EMAIL_ADDRESS>", "name": "john@example.com"}, res[7])
+	assert.Equal(t, "user7@example.com", rows[7]["email"], "rows are copied")
+	assert.Equal(t, int32(51), stub.analyzed.Load(), "each distinct value is analyzed once")
+	assert.LessOrEqual(t, stub.maxIn.Load(), int32(4))
+	assert.Equal(t, "2000", stats.Meta["Anonymized-Values"])
+
+	_, err = plugin.Intercept(context.Background(), plugins.Call{}, rows)
+	require.NoError(t, err)
+	assert.Equal(t, int32(51), stub.analyzed.Load(), "values analyzed before are cached")
+}
+
+func TestInterceptFailures(t *testing.T) {
+	stub := &presidioStub{}
+	stub.fail.Store(true)
+	analyzer, anonymizer := stub.start(t)
+	rows := []map[string]any{{"email": "a@example.com"}, {"email": "b@example.com"}}
+
+	plugin, err := New(Config{AnalyzerURL: analyzer, AnonymizeURL: anonymizer})
+	require.NoError(t, err)
+	_, err = plugin.Intercept(context.Background(), plugins.Call{}, rows)
+	assert.ErrorContains(t, err, "analyzer is down", "fails closed by default")
+
+	plugin, err = New(Config{AnalyzerURL: analyzer, AnonymizeURL: anonymizer, FailOpen: true})
+	require.NoError(t, err)
+	ctx, stats := xcontext.WithQueryStats(context.Background())
+	res, err := plugin.Intercept(ctx, plugins.Call{}, rows)
+	require.NoError(t, err)
+	assert.Equal(t, rows, res)
+	assert.Equal(t, map[string]string{"Anonymizer-Errors": "2"}, stats.Meta)
+
+	// failures are not cached
+	stub.fail.Store(false)
+	res, err = plugin.Intercept(context.Background(), plugins.Call{}, rows)
+	require.NoError(t, err)
+	assert.Equal(t, "<EMAIL_ADDRESS>", res[0]["email"])
+}
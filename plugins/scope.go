@@ -0,0 +1,233 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/centralmind/gateway/connectors"
+	"github.com/centralmind/gateway/model"
+	"golang.org/x/xerrors"
+)
+
+// Merge overrides plugin configs of base: a map config is merged key by key, false disables
+// the plugin and other values replace the config. Arguments are not modified.
+func Merge(base, override map[string]any) map[string]any {
+	res := make(map[string]any, len(base)+len(override))
+	for tag, config := range base {
+		res[tag] = config
+	}
+	for tag, config := range override {
+		if disabled, ok := config.(bool); ok && !disabled {
+			delete(res, tag)
+			continue
+		}
+		baseMap, baseOk := res[tag].(map[string]any)
+		overrideMap, overrideOk := config.(map[string]any)
+		if baseOk && overrideOk {
+			merged := make(map[string]any, len(baseMap)+len(overrideMap))
+			for k, v := range baseMap {
+				merged[k] = v
+			}
+			for k, v := range overrideMap {
+				merged[k] = v
+			}
+			config = merged
+		}
+		res[tag] = config
+	}
+	return res
+}
+
+// Scope resolves plugins of endpoints: global plugins are overridden by the endpoint group,
+// then by the endpoint itself. Endpoints with the same effective config share plugin instances,
+// queries without an endpoint, such as raw ones, use global plugins.
+type Scope struct {
+	global    map[string]any
+	groups    map[string]model.EndpointGroup
+	endpoints []model.Endpoint
+
+	mu   sync.Mutex
+	sets map[string]*set
+}
+
+// set holds plugins constructed for a single effective config
+type set struct {
+	interceptors []BatchInterceptor
+	visibility   []Visibility
+}
+
+// NewScope constructs plugins of every configured endpoint, so invalid overrides fail on start
+func NewScope(cfg model.Config) (*Scope, error) {
+	s := &Scope{
+		global:    cfg.Plugins,
+		groups:    cfg.Database.Groups,
+		endpoints: cfg.Database.Endpoints,
+		sets:      map[string]*set{},
+	}
+	if _, err := s.set(model.Endpoint{}); err != nil {
+		return nil, err
+	}
+	for _, endpoint := range s.endpoints {
+		if _, err := s.set(endpoint); err != nil {
+			return nil, xerrors.Errorf("endpoint %s: %w", endpoint.MCPMethod, err)
+		}
+	}
+	return s, nil
+}
+
+// Global returns plugins configured for the whole gateway
+func (s *Scope) Global() map[string]any {
+	return s.global
+}
+
+// Config returns the effective plugin config of endpoint
+func (s *Scope) Config(endpoint model.Endpoint) map[string]any {
+	res := s.global
+	if group, ok := s.groups[endpoint.Group]; ok && endpoint.Group != "" {
+		res = Merge(res, group.Plugins)
+	}
+	return Merge(res, endpoint.Plugins)
+}
+
+// Interceptors returns interceptors of endpoint in chain order
+func (s *Scope) Interceptors(endpoint model.Endpoint) ([]BatchInterceptor, error) {
+	set, err := s.set(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return set.interceptors, nil
+}
+
+// Visible reports whether the caller described by ctx may use endpoint,
+// it is visible only if all visibility plugins of the endpoint allow it.
+func (s *Scope) Visible(ctx context.Context, endpoint model.Endpoint) bool {
+	set, err := s.set(endpoint)
+	if err != nil {
+		return false
+	}
+	for _, plug := range set.visibility {
+		if !plug.Visible(ctx, endpoint.MCPMethod) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Scope) set(endpoint model.Endpoint) (*set, error) {
+	config := s.Config(endpoint)
+	key, err := configKey(config)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if res, ok := s.sets[key]; ok {
+		return res, nil
+	}
+	interceptors, err := Interceptors(config)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to init interceptors: %w", err)
+	}
+	visibility, err := Plugins[Visibility](config)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to init visibility plugins: %w", err)
+	}
+	res := &set{interceptors: interceptors, visibility: visibility}
+	s.sets[key] = res
+	return res, nil
+}
+
+// configKey identifies an effective config, JSON encoding sorts map keys
+func configKey(config map[string]any) (string, error) {
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return "", xerrors.Errorf("unable to encode plugins config: %w", err)
+	}
+	return string(raw), nil
+}
+
+// Wrap wraps connector with the wrapper chain of every configured endpoint, a query runs through
+// the chain of its endpoint. Other calls and queries without an endpoint use the global chain.
+func (s *Scope) Wrap(connector connectors.Connector) (connectors.Connector, error) {
+	globalKey, err := configKey(s.global)
+	if err != nil {
+		return nil, err
+	}
+	global, err := Wrap(s.global, connector)
+	if err != nil {
+		return nil, err
+	}
+	res := &scopedConnector{
+		Connector: global,
+		base:      unclosed{Connector: connector},
+		scope:     s,
+		chains:    map[string]connectors.Connector{globalKey: global},
+	}
+	for _, endpoint := range s.endpoints {
+		if _, err := res.chain(endpoint); err != nil {
+			_ = res.Close()
+			return nil, xerrors.Errorf("endpoint %s: %w", endpoint.MCPMethod, err)
+		}
+	}
+	return res, nil
+}
+
+// scopedConnector routes queries to the wrapper chain of their endpoint,
+// all chains share the underlying connector
+type scopedConnector struct {
+	connectors.Connector // global chain
+	base                 connectors.Connector
+	scope                *Scope
+
+	mu     sync.Mutex
+	chains map[string]connectors.Connector
+}
+
+func (s *scopedConnector) Query(ctx context.Context, endpoint model.Endpoint, params map[string]any) ([]map[string]any, error) {
+	chain, err := s.chain(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return chain.Query(ctx, endpoint, params)
+}
+
+func (s *scopedConnector) chain(endpoint model.Endpoint) (connectors.Connector, error) {
+	config := s.scope.Config(endpoint)
+	key, err := configKey(config)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if chain, ok := s.chains[key]; ok {
+		return chain, nil
+	}
+	chain, err := Wrap(config, s.base)
+	if err != nil {
+		return nil, err
+	}
+	s.chains[key] = chain
+	return chain, nil
+}
+
+// Close releases resources of endpoint chains, the global chain closes the underlying connector
+func (s *scopedConnector) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, chain := range s.chains {
+		if chain != s.Connector {
+			_ = chain.Close()
+		}
+	}
+	return s.Connector.Close()
+}
+
+// unclosed shares a connector between chains, it is closed once by the global chain
+type unclosed struct {
+	connectors.Connector
+}
+
+func (unclosed) Close() error {
+	return nil
+}
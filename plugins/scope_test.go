@@ -0,0 +1,89 @@
+package plugins
+
+import (
+	"context"
+	"testing"
+
+	"github.com/centralmind/gateway/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	base := map[string]any{
+		"lru_cache": map[string]any{"max_size": 100, "ttl": "1m"},
+		"otel":      map[string]any{"service_name": "gw"},
+	}
+	merged := Merge(base, map[string]any{
+		"lru_cache":   map[string]any{"ttl": "1h"},
+		"otel":        false,
+		"pii_remover": map[string]any{"fields": []any{"email"}},
+	})
+	assert.Equal(t, map[string]any{
+		"lru_cache":   map[string]any{"max_size": 100, "ttl": "1h"},
+		"pii_remover": map[string]any{"fields": []any{"email"}},
+	}, merged)
+	assert.Equal(t, map[string]any{"max_size": 100, "ttl": "1m"}, base["lru_cache"], "base is not modified")
+	assert.Contains(t, base, "otel")
+}
+
+func TestScope(t *testing.T) {
+	heavy := model.Endpoint{MCPMethod: "heavy", Group: "reports", Query: "SELECT 1"}
+	public := model.Endpoint{MCPMethod: "public", Query: "SELECT 2", Plugins: map[string]any{"test_auth": false}}
+	plain := model.Endpoint{MCPMethod: "plain", Query: "SELECT 3"}
+	scope, err := NewScope(model.Config{
+		Plugins: map[string]any{
+			"test_auth": map[string]any{"name": "auth"},
+			"test_mask": nil,
+		},
+		Database: model.Database{
+			Endpoints: []model.Endpoint{heavy, public, plain},
+			Groups: map[string]model.EndpointGroup{
+				"reports": {Plugins: map[string]any{
+					"test_recorder": map[string]any{"name": "recorder"},
+					"test_auth":     map[string]any{"name": "reports auth"},
+					"test_mask":     false,
+				}},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	closed := 0
+	connector, err := scope.Wrap(stubConnector{closed: &closed})
+	require.NoError(t, err)
+	ctx := context.Background()
+	for _, tc := range []struct {
+		endpoint model.Endpoint
+		calls    calls
+		masks    int
+	}{
+		{endpoint: heavy, calls: calls{"reports auth", "recorder"}, masks: 0},
+		{endpoint: public, calls: calls{}, masks: 1},
+		{endpoint: plain, calls: calls{"auth"}, masks: 1},
+		{endpoint: model.Endpoint{Query: "SELECT raw"}, calls: calls{"auth"}, masks: 1},
+	} {
+		t.Run(tc.endpoint.MCPMethod, func(t *testing.T) {
+			recorded = &calls{}
+			_, err := connector.Query(ctx, tc.endpoint, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tc.calls, *recorded)
+
+			interceptors, err := scope.Interceptors(tc.endpoint)
+			require.NoError(t, err)
+			assert.Len(t, interceptors, tc.masks)
+		})
+	}
+
+	require.NoError(t, connector.Close())
+	assert.Equal(t, 1, closed, "chains share the connector, it is closed once")
+}
+
+func TestScopeInvalidOverride(t *testing.T) {
+	_, err := NewScope(model.Config{
+		Database: model.Database{
+			Endpoints: []model.Endpoint{{MCPMethod: "broken", Plugins: map[string]any{"test_auth": map[string]any{"order": "last"}}}},
+		},
+	})
+	assert.ErrorContains(t, err, "broken")
+}
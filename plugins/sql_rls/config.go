@@ -0,0 +1,25 @@
+package sqlrls
+
+import "github.com/centralmind/gateway/plugins"
+
+// Config represents SQL row-level security configuration
+type Config struct {
+	// Policies map possibly qualified table names to predicates their rows must match,
+	// claims of the caller are referenced as :claims.<name>, e.g. tenant_id = :claims.org_id
+	Policies map[string]string `yaml:"policies"`
+
+	// AllowUnprotected lets raw queries read tables without a policy, they are rejected by default
+	AllowUnprotected bool `yaml:"allow_unprotected"`
+}
+
+func (c Config) Tag() string {
+	return "sql_rls"
+}
+
+func (c Config) Order() int {
+	return plugins.OrderRowFilter
+}
+
+func (c Config) Doc() string {
+	return docString
+}
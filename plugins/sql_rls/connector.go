@@ -0,0 +1,56 @@
+package sqlrls
+
+import (
+	"context"
+
+	"github.com/centralmind/gateway/connectors"
+	"github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/sqlguard"
+	"github.com/centralmind/gateway/xcontext"
+)
+
+// Connector rewrites queries so that the database returns only rows allowed by policies
+type Connector struct {
+	connectors.Connector
+	config Config
+	filter *sqlguard.RowFilter
+}
+
+// Query filters protected tables of the query by claims of the caller.
+// Raw queries, which have no MCP method or HTTP path, may read only protected tables unless AllowUnprotected is set.
+func (c *Connector) Query(ctx context.Context, endpoint model.Endpoint, params map[string]any) ([]map[string]any, error) {
+	raw := endpoint.MCPMethod == "" && endpoint.HTTPPath == ""
+	query, bound, values, err := c.filter.Apply(endpoint.Query, xcontext.Claims(ctx), raw && !c.config.AllowUnprotected)
+	if err != nil {
+		return nil, err
+	}
+	endpoint.Query = query
+	endpoint.Params = append(append([]model.EndpointParams{}, endpoint.Params...), bound...)
+	merged := make(map[string]any, len(params)+len(values))
+	for k, v := range params {
+		merged[k] = v
+	}
+	for k, v := range values {
+		merged[k] = v
+	}
+	return c.Connector.Query(ctx, endpoint, merged)
+}
+
+// InferQuery returns no rows, so the query is only checked for unprotected tables
+func (c *Connector) InferQuery(ctx context.Context, query string) ([]model.ColumnSchema, error) {
+	if !c.config.AllowUnprotected {
+		if err := c.filter.Check(query); err != nil {
+			return nil, err
+		}
+	}
+	return c.Connector.InferQuery(ctx, query)
+}
+
+// Sample is not tied to a caller, so samples of protected tables are empty,
+// as are samples of unprotected ones raw queries may not read
+func (c *Connector) Sample(ctx context.Context, table model.Table) ([]map[string]any, error) {
+	if c.filter.Protected(table.Name) || !c.config.AllowUnprotected {
+		return []map[string]any{}, nil
+	}
+	return c.Connector.Sample(ctx, table)
+}
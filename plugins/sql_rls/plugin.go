@@ -0,0 +1,54 @@
+package sqlrls
+
+import (
+	_ "embed"
+
+	"github.com/centralmind/gateway/connectors"
+	"github.com/centralmind/gateway/plugins"
+	"github.com/centralmind/gateway/sqlguard"
+	"golang.org/x/xerrors"
+)
+
+//go:embed README.md
+var docString string
+
+func init() {
+	plugins.Register(func(cfg Config) (plugins.Wrapper, error) {
+		return New(cfg)
+	})
+}
+
+type Plugin struct {
+	config Config
+}
+
+func New(config Config) (*Plugin, error) {
+	if len(config.Policies) == 0 {
+		return nil, xerrors.New("at least one policy is required")
+	}
+	// predicates are checked with ANSI rules here, Wrap checks them again for the connector dialect
+	if _, err := sqlguard.NewRowFilter("", config.Policies); err != nil {
+		return nil, err
+	}
+	return &Plugin{config: config}, nil
+}
+
+func (p *Plugin) Doc() string {
+	return docString
+}
+
+func (p *Plugin) Wrap(connector connectors.Connector) (connectors.Connector, error) {
+	typ := connector.Config().Type()
+	filter, err := sqlguard.NewRowFilter(typ, p.config.Policies)
+	if err != nil {
+		return nil, err
+	}
+	if filter == nil {
+		return nil, xerrors.Errorf("sql_rls supports SQL connectors only, got %s", typ)
+	}
+	return &Connector{
+		Connector: connector,
+		config:    p.config,
+		filter:    filter,
+	}, nil
+}
@@ -0,0 +1,103 @@
+package sqlrls
+
+import (
+	"context"
+	"testing"
+
+	"github.com/centralmind/gateway/connectors"
+	"github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/sqlguard"
+	"github.com/centralmind/gateway/xcontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct{ typ string }
+
+func (c testConfig) Type() string          { return c.typ }
+func (c testConfig) Doc() string           { return "" }
+func (c testConfig) ExtraPrompt() []string { return nil }
+func (c testConfig) Readonly() bool        { return true }
+
+// recorder remembers the last query it ran
+type recorder struct {
+	connectors.Connector
+	typ      string
+	endpoint model.Endpoint
+	params   map[string]any
+}
+
+func (r *recorder) Config() connectors.Config { return testConfig{typ: r.typ} }
+
+func (r *recorder) Query(_ context.Context, endpoint model.Endpoint, params map[string]any) ([]map[string]any, error) {
+	r.endpoint, r.params = endpoint, params
+	return []map[string]any{{"id": 1}}, nil
+}
+
+func (r *recorder) Sample(context.Context, model.Table) ([]map[string]any, error) {
+	return []map[string]any{{"id": 1}}, nil
+}
+
+func wrap(t *testing.T, cfg Config) (connectors.Connector, *recorder) {
+	plugin, err := New(cfg)
+	require.NoError(t, err)
+	rec := &recorder{typ: "postgres"}
+	connector, err := plugin.Wrap(rec)
+	require.NoError(t, err)
+	return connector, rec
+}
+
+func TestQueryIsFiltered(t *testing.T) {
+	connector, rec := wrap(t, Config{Policies: map[string]string{"orders": "tenant_id = :claims.org_id"}})
+	ctx := xcontext.WithClaims(context.Background(), map[string]any{"org_id": "acme"})
+
+	endpoint := model.Endpoint{
+		MCPMethod: "list_orders",
+		Query:     "SELECT o.* FROM orders o JOIN statuses s ON s.id = o.status_id WHERE o.id > :from",
+		Params:    []model.EndpointParams{{Name: "from", Type: "number"}},
+	}
+	_, err := connector.Query(ctx, endpoint, map[string]any{"from": 10})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT o.* FROM (SELECT * FROM orders WHERE (tenant_id = :rls_claims_org_id)) o JOIN statuses s ON s.id = o.status_id WHERE o.id > :from", rec.endpoint.Query)
+	assert.Equal(t, []model.EndpointParams{
+		{Name: "from", Type: "number"},
+		{Name: "rls_claims_org_id", Type: "string", Required: true},
+	}, rec.endpoint.Params)
+	assert.Equal(t, map[string]any{"from": 10, "rls_claims_org_id": "acme"}, rec.params)
+	assert.Len(t, endpoint.Params, 1, "the endpoint of the caller is not modified")
+
+	// raw queries may not read unprotected tables
+	_, err = connector.Query(ctx, model.Endpoint{Query: endpoint.Query}, map[string]any{})
+	assert.ErrorIs(t, err, sqlguard.ErrRejected)
+	_, err = connector.Query(ctx, model.Endpoint{Query: "SELECT count(*) FROM orders"}, map[string]any{})
+	assert.NoError(t, err)
+}
+
+func TestAllowUnprotected(t *testing.T) {
+	connector, _ := wrap(t, Config{
+		Policies:         map[string]string{"orders": "tenant_id = :claims.org_id"},
+		AllowUnprotected: true,
+	})
+	ctx := xcontext.WithClaims(context.Background(), map[string]any{"org_id": "acme"})
+	_, err := connector.Query(ctx, model.Endpoint{Query: "SELECT * FROM statuses"}, map[string]any{})
+	assert.NoError(t, err)
+
+	sample, err := connector.Sample(ctx, model.Table{Name: "statuses"})
+	require.NoError(t, err)
+	assert.Len(t, sample, 1)
+	sample, err = connector.Sample(ctx, model.Table{Name: "orders"})
+	require.NoError(t, err)
+	assert.Empty(t, sample)
+}
+
+func TestConfigErrors(t *testing.T) {
+	_, err := New(Config{})
+	assert.Error(t, err)
+	_, err = New(Config{Policies: map[string]string{"orders": "tenant_id = :tenant"}})
+	assert.Error(t, err)
+
+	plugin, err := New(Config{Policies: map[string]string{"orders": "tenant_id = 1"}})
+	require.NoError(t, err)
+	_, err = plugin.Wrap(&recorder{typ: "mongodb"})
+	assert.Error(t, err)
+}
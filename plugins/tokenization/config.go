@@ -0,0 +1,36 @@
+package tokenization
+
+import (
+	"github.com/centralmind/gateway/claimrule"
+	"github.com/centralmind/gateway/plugins"
+)
+
+// Config represents tokenization configuration
+type Config struct {
+	// Columns are column name globs of values replaced with tokens, e.g. "email" or "*_phone"
+	Columns []string `yaml:"columns"`
+
+	// Vault is the path of the SQLite file storing encrypted values of tokens
+	Vault string `yaml:"vault"`
+
+	// Key is the secret deriving token and encryption keys, tokens change with it
+	Key string `yaml:"key"`
+
+	// Prefix starts every token, "tok_" by default
+	Prefix string `yaml:"prefix"`
+
+	// DetokenizeFor lists claim rules of callers allowed to pass tokens as params, a single matching rule is enough
+	DetokenizeFor []claimrule.Rule `yaml:"detokenize_for"`
+}
+
+func (c Config) Tag() string {
+	return "tokenization"
+}
+
+func (c Config) Order() int {
+	return plugins.OrderColumnMasking
+}
+
+func (c Config) Doc() string {
+	return docString
+}
@@ -0,0 +1,179 @@
+package tokenization
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/centralmind/gateway/claimrule"
+	"github.com/centralmind/gateway/connectors"
+	gw_errors "github.com/centralmind/gateway/errors"
+	"github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/plugins"
+	"github.com/centralmind/gateway/sqlguard"
+	"github.com/centralmind/gateway/xcontext"
+	"golang.org/x/xerrors"
+)
+
+// Connector replaces values of matching columns with tokens and tokens passed as params with their values
+type Connector struct {
+	connectors.Connector
+	vault         *vault
+	columns       []string
+	detokenizeFor []claimrule.Rule
+	typ           string
+}
+
+// Query tokenizes columns of the result. Raw queries, which have no MCP method or HTTP path, could return
+// values under other names, so they may read tokenized columns only through a plain * in the select list.
+func (c *Connector) Query(ctx context.Context, endpoint model.Endpoint, params map[string]any) ([]map[string]any, error) {
+	if endpoint.MCPMethod == "" && endpoint.HTTPPath == "" {
+		if err := c.checkRaw(endpoint.Query); err != nil {
+			return nil, err
+		}
+	}
+	params, err := c.detokenize(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := c.Connector.Query(ctx, endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+	return c.tokenize(ctx, rows)
+}
+
+func (c *Connector) Sample(ctx context.Context, table model.Table) ([]map[string]any, error) {
+	rows, err := c.Connector.Sample(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	return c.tokenize(ctx, rows)
+}
+
+func (c *Connector) Close() error {
+	return errors.Join(c.vault.Close(), c.Connector.Close())
+}
+
+// checkRaw rejects a raw query referring to a tokenized column, or one that can't be analyzed
+func (c *Connector) checkRaw(query string) error {
+	columns, renamed, ok := sqlguard.Columns(c.typ, query)
+	if !ok {
+		return fmt.Errorf("%w: query can't be checked for tokenized columns", sqlguard.ErrRejected)
+	}
+	if renamed {
+		return fmt.Errorf("%w: query may return tokenized columns under other names, select them with *", sqlguard.ErrRejected)
+	}
+	for _, column := range columns {
+		if plugins.MatchColumn(c.columns, column) {
+			return fmt.Errorf("%w: column %s is tokenized, select it with *", sqlguard.ErrRejected, column)
+		}
+	}
+	return nil
+}
+
+// detokenize returns a copy of params with tokens replaced by their values, also inside list params.
+// Only callers matching a detokenize_for rule may pass tokens.
+func (c *Connector) detokenize(ctx context.Context, params map[string]any) (map[string]any, error) {
+	var res map[string]any
+	for name, v := range params {
+		value, changed, err := c.detokenizeValue(ctx, v)
+		if err != nil {
+			return nil, xerrors.Errorf("param %s: %w", name, err)
+		}
+		if !changed {
+			continue
+		}
+		if res == nil {
+			res = make(map[string]any, len(params))
+			for k, v := range params {
+				res[k] = v
+			}
+		}
+		res[name] = value
+	}
+	if res == nil {
+		return params, nil
+	}
+	return res, nil
+}
+
+func (c *Connector) detokenizeValue(ctx context.Context, v any) (any, bool, error) {
+	switch v := v.(type) {
+	case string:
+		if !c.vault.isToken(v) {
+			return v, false, nil
+		}
+		if !claimrule.MatchAny(c.detokenizeFor, xcontext.Claims(ctx)) {
+			return nil, false, fmt.Errorf("%w: detokenization is not allowed", gw_errors.ErrNotAuthorized)
+		}
+		value, ok, err := c.vault.lookup(ctx, v)
+		if err != nil {
+			return nil, false, err
+		}
+		if !ok {
+			return nil, false, xerrors.Errorf("unknown token %s", v)
+		}
+		return value, true, nil
+	case []any:
+		var res []any
+		for i, item := range v {
+			value, changed, err := c.detokenizeValue(ctx, item)
+			if err != nil {
+				return nil, false, err
+			}
+			if changed && res == nil {
+				res = append(make([]any, 0, len(v)), v...)
+			}
+			if res != nil {
+				res[i] = value
+			}
+		}
+		if res == nil {
+			return v, false, nil
+		}
+		return res, true, nil
+	}
+	return v, false, nil
+}
+
+// tokenize returns copies of rows with values of matching columns replaced by tokens,
+// stores new tokens in the vault and reports the number of tokenized values as Tokenized-Values metadata.
+// Rows are not returned when tokens can't be stored, a token must always be reversible.
+func (c *Connector) tokenize(ctx context.Context, rows []map[string]any) ([]map[string]any, error) {
+	tokenized := 0
+	byColumn := map[string]bool{}
+	pending := map[string][]byte{}
+	res := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		copied := make(map[string]any, len(row))
+		for column, v := range row {
+			match, ok := byColumn[column]
+			if !ok {
+				match = plugins.MatchColumn(c.columns, column)
+				byColumn[column] = match
+			}
+			if match && v != nil {
+				token, raw, err := c.vault.token(v)
+				if err != nil {
+					return nil, xerrors.Errorf("column %s: %w", column, err)
+				}
+				if !c.vault.known.Contains(token) {
+					pending[token] = raw
+				}
+				v = token
+				tokenized++
+			}
+			copied[column] = v
+		}
+		res[i] = copied
+	}
+	if err := c.vault.store(ctx, pending); err != nil {
+		return nil, err
+	}
+	if tokenized > 0 {
+		xcontext.Stats(ctx).SetMeta("Tokenized-Values", strconv.Itoa(tokenized))
+	}
+	return res, nil
+}
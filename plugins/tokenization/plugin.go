@@ -0,0 +1,62 @@
+package tokenization
+
+import (
+	_ "embed"
+
+	"github.com/centralmind/gateway/connectors"
+	"github.com/centralmind/gateway/plugins"
+	"golang.org/x/xerrors"
+)
+
+//go:embed README.md
+var docString string
+
+func init() {
+	plugins.Register(func(cfg Config) (plugins.Wrapper, error) {
+		return New(cfg)
+	})
+}
+
+const defaultPrefix = "tok_"
+
+type Plugin struct {
+	config Config
+}
+
+func New(config Config) (*Plugin, error) {
+	if len(config.Columns) == 0 {
+		return nil, xerrors.New("columns are required")
+	}
+	if err := plugins.ValidateColumns(config.Columns); err != nil {
+		return nil, err
+	}
+	if config.Vault == "" {
+		return nil, xerrors.New("vault is required")
+	}
+	if config.Key == "" {
+		return nil, xerrors.New("key is required")
+	}
+	if config.Prefix == "" {
+		config.Prefix = defaultPrefix
+	}
+	return &Plugin{config: config}, nil
+}
+
+func (p *Plugin) Doc() string {
+	return docString
+}
+
+// Wrap opens the vault, it is closed with the returned connector
+func (p *Plugin) Wrap(connector connectors.Connector) (connectors.Connector, error) {
+	v, err := openVault(p.config.Vault, p.config.Key, p.config.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	return &Connector{
+		Connector:     connector,
+		vault:         v,
+		columns:       p.config.Columns,
+		detokenizeFor: p.config.DetokenizeFor,
+		typ:           connector.Config().Type(),
+	}, nil
+}
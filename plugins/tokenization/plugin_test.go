@@ -0,0 +1,167 @@
+package tokenization
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/centralmind/gateway/claimrule"
+	"github.com/centralmind/gateway/connectors"
+	gw_errors "github.com/centralmind/gateway/errors"
+	"github.com/centralmind/gateway/model"
+	"github.com/centralmind/gateway/sqlguard"
+	"github.com/centralmind/gateway/xcontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct{}
+
+func (testConfig) Type() string          { return "postgres" }
+func (testConfig) Doc() string           { return "" }
+func (testConfig) ExtraPrompt() []string { return nil }
+func (testConfig) Readonly() bool        { return true }
+
+// recordingConnector returns the same rows for every call and records params it was called with
+type recordingConnector struct {
+	connectors.Connector
+	rows   []map[string]any
+	params map[string]any
+}
+
+func (c *recordingConnector) Config() connectors.Config { return testConfig{} }
+
+func (c *recordingConnector) Query(_ context.Context, _ model.Endpoint, params map[string]any) ([]map[string]any, error) {
+	c.params = params
+	return c.rows, nil
+}
+
+func (c *recordingConnector) Sample(context.Context, model.Table) ([]map[string]any, error) {
+	return c.rows, nil
+}
+
+func (c *recordingConnector) Close() error {
+	return nil
+}
+
+func newConnector(t *testing.T, vault string, inner connectors.Connector) connectors.Connector {
+	plugin, err := New(Config{
+		Columns:       []string{"*email", "customer_id"},
+		Vault:         vault,
+		Key:           "secret",
+		DetokenizeFor: []claimrule.Rule{{Claim: "roles", Operation: "contains", Value: "support"}},
+	})
+	require.NoError(t, err)
+	connector, err := plugin.Wrap(inner)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = connector.Close() })
+	return connector
+}
+
+func TestTokenizeAndDetokenize(t *testing.T) {
+	vault := filepath.Join(t.TempDir(), "tokens.db")
+	raw := []map[string]any{
+		{"id": 1, "email": "a@b.com", "customer_id": int64(42), "backup_email": nil},
+		{"id": 2, "email": "a@b.com", "customer_id": int64(43), "backup_email": "c@d.com"},
+	}
+	inner := &recordingConnector{rows: raw}
+	connector := newConnector(t, vault, inner)
+
+	ctx, stats := xcontext.WithQueryStats(context.Background())
+	res, err := connector.Query(ctx, model.Endpoint{MCPMethod: "list_customers"}, nil)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Equal(t, map[string]string{"Tokenized-Values": "5"}, stats.Meta)
+	assert.Equal(t, "a@b.com", raw[0]["email"], "rows of the wrapped connector are not modified")
+	assert.Equal(t, 1, res[0]["id"])
+	assert.Nil(t, res[0]["backup_email"])
+	email := res[0]["email"].(string)
+	assert.Regexp(t, `^tok_[a-z2-7]{24}$`, email)
+	assert.Equal(t, email, res[1]["email"], "equal values get equal tokens")
+	assert.NotEqual(t, res[0]["customer_id"], res[1]["customer_id"])
+
+	params := map[string]any{"email": email, "ids": []any{res[1]["customer_id"], "plain"}, "limit": 10}
+	_, err = connector.Query(ctx, model.Endpoint{MCPMethod: "get_customer"}, params)
+	assert.ErrorIs(t, err, gw_errors.ErrNotAuthorized, "callers without privileged claims can't detokenize")
+	assert.Nil(t, inner.params)
+
+	support := xcontext.WithClaims(context.Background(), map[string]any{"roles": []any{"support"}})
+	res, err = connector.Query(support, model.Endpoint{MCPMethod: "get_customer"}, params)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"email": "a@b.com", "ids": []any{int64(43), "plain"}, "limit": 10}, inner.params)
+	assert.Equal(t, email, params["email"], "params of the caller are not modified")
+	assert.Equal(t, email, res[0]["email"], "results stay tokenized for privileged callers")
+
+	// tokens survive restarts with the same key
+	reopened := newConnector(t, vault, inner)
+	_, err = reopened.Query(support, model.Endpoint{}, map[string]any{"email": email})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"email": "a@b.com"}, inner.params)
+
+	_, err = reopened.Query(support, model.Endpoint{}, map[string]any{"email": "tok_aaaaaaaaaaaaaaaaaaaaaaaa"})
+	assert.ErrorContains(t, err, "unknown token")
+}
+
+func TestSampleIsTokenized(t *testing.T) {
+	connector := newConnector(t, filepath.Join(t.TempDir(), "tokens.db"), &recordingConnector{rows: []map[string]any{{"email": "a@b.com", "name": "A"}}})
+	res, err := connector.Sample(context.Background(), model.Table{Name: "customers"})
+	require.NoError(t, err)
+	assert.Regexp(t, `^tok_`, res[0]["email"])
+	assert.Equal(t, "A", res[0]["name"])
+}
+
+func TestRawQueries(t *testing.T) {
+	connector := newConnector(t, filepath.Join(t.TempDir(), "tokens.db"), &recordingConnector{rows: []map[string]any{{"email": "a@b.com", "name": "A"}}})
+	support := xcontext.WithClaims(context.Background(), map[string]any{"roles": []any{"support"}})
+
+	res, err := connector.Query(support, model.Endpoint{Query: "SELECT * FROM customers WHERE name = 'A'"}, nil)
+	require.NoError(t, err)
+	assert.Regexp(t, `^tok_`, res[0]["email"], "a star returns tokenized columns under their names")
+
+	for _, query := range []string{
+		"SELECT email AS e FROM customers",
+		"SELECT upper(email) FROM customers",
+		"SELECT name FROM customers ORDER BY backup_email",
+		"SELECT to_json(c) FROM customers c",
+		"SELECT (",
+	} {
+		_, err := connector.Query(support, model.Endpoint{Query: query}, nil)
+		assert.ErrorIs(t, err, sqlguard.ErrRejected, query)
+	}
+	_, err = connector.Query(support, model.Endpoint{MCPMethod: "find_customer", Query: "SELECT email AS e FROM customers"}, nil)
+	assert.NoError(t, err, "endpoint queries are written by the configuration author")
+}
+
+func TestVaultIsEncrypted(t *testing.T) {
+	v, err := openVault(filepath.Join(t.TempDir(), "tokens.db"), "secret", defaultPrefix)
+	require.NoError(t, err)
+	defer v.Close()
+
+	token, raw, err := v.token("a@b.com")
+	require.NoError(t, err)
+	require.NoError(t, v.store(context.Background(), map[string][]byte{token: raw}))
+	var stored []byte
+	require.NoError(t, v.db.QueryRow(`SELECT value FROM tokens WHERE token = ?`, token).Scan(&stored))
+	assert.NotContains(t, string(stored), "a@b.com")
+
+	other, err := openVault(filepath.Join(t.TempDir(), "other.db"), "other", defaultPrefix)
+	require.NoError(t, err)
+	defer other.Close()
+	otherToken, _, err := other.token("a@b.com")
+	require.NoError(t, err)
+	assert.NotEqual(t, token, otherToken, "tokens depend on the key")
+	assert.True(t, v.isToken(token))
+	assert.False(t, v.isToken("tok_short"))
+}
+
+func TestConfigErrors(t *testing.T) {
+	for _, cfg := range []Config{
+		{Vault: "tokens.db", Key: "k"},
+		{Columns: []string{"[a"}, Vault: "tokens.db", Key: "k"},
+		{Columns: []string{"email"}, Key: "k"},
+		{Columns: []string{"email"}, Vault: "tokens.db"},
+	} {
+		_, err := New(cfg)
+		assert.Error(t, err, cfg)
+	}
+}
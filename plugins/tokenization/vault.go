@@ -0,0 +1,166 @@
+package tokenization
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"encoding/json"
+	"strings"
+
+	_ "github.com/glebarez/go-sqlite"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// tokenBytes of the value HMAC are encoded in a token, 24 characters of base32
+	tokenBytes = 15
+	// knownTokens is the number of tokens remembered as stored, so repeated values are not written again
+	knownTokens = 10000
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// vault maps tokens to values encrypted with AES-GCM. Tokens are HMACs of values,
+// so a value gets the same token on every call and every gateway sharing the key.
+type vault struct {
+	db     *sql.DB
+	aead   cipher.AEAD
+	mac    []byte
+	prefix string
+	known  *lru.Cache[string, struct{}]
+}
+
+func openVault(path, secret, prefix string) (*vault, error) {
+	block, err := aes.NewCipher(deriveKey(secret, "encryption"))
+	if err != nil {
+		return nil, xerrors.Errorf("unable to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to create cipher: %w", err)
+	}
+	// several gateways or scopes may share the file, writers wait for each other
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, xerrors.Errorf("unable to open vault: %w", err)
+	}
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS tokens (token TEXT PRIMARY KEY, value BLOB NOT NULL)`); err != nil {
+		db.Close()
+		return nil, xerrors.Errorf("unable to create vault table: %w", err)
+	}
+	known, _ := lru.New[string, struct{}](knownTokens)
+	return &vault{
+		db:     db,
+		aead:   aead,
+		mac:    deriveKey(secret, "token"),
+		prefix: prefix,
+		known:  known,
+	}, nil
+}
+
+func deriveKey(secret, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// token returns the token of value with the encoded value to store
+func (v *vault) token(value any) (string, []byte, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", nil, xerrors.Errorf("unable to encode value: %w", err)
+	}
+	mac := hmac.New(sha256.New, v.mac)
+	mac.Write(raw)
+	return v.prefix + strings.ToLower(encoding.EncodeToString(mac.Sum(nil)[:tokenBytes])), raw, nil
+}
+
+// isToken reports whether s has the shape of a token
+func (v *vault) isToken(s string) bool {
+	body, ok := strings.CutPrefix(s, v.prefix)
+	if !ok || len(body) != encoding.EncodedLen(tokenBytes) {
+		return false
+	}
+	_, err := encoding.DecodeString(strings.ToUpper(body))
+	return err == nil
+}
+
+// store saves encrypted values of tokens not stored yet in a single transaction
+func (v *vault) store(ctx context.Context, values map[string][]byte) error {
+	if len(values) == 0 {
+		return nil
+	}
+	tx, err := v.db.BeginTx(ctx, nil)
+	if err != nil {
+		return xerrors.Errorf("unable to begin vault transaction: %w", err)
+	}
+	defer tx.Rollback()
+	for token, raw := range values {
+		nonce := make([]byte, v.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return xerrors.Errorf("unable to generate nonce: %w", err)
+		}
+		// the token is authenticated with the value, so stored values can't be swapped between tokens
+		sealed := v.aead.Seal(nonce, nonce, raw, []byte(token))
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO tokens (token, value) VALUES (?, ?)`, token, sealed); err != nil {
+			return xerrors.Errorf("unable to store token: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return xerrors.Errorf("unable to commit vault transaction: %w", err)
+	}
+	for token := range values {
+		v.known.Add(token, struct{}{})
+	}
+	return nil
+}
+
+// lookup returns the value of token, ok is false for tokens not in the vault
+func (v *vault) lookup(ctx context.Context, token string) (value any, ok bool, err error) {
+	var sealed []byte
+	err = v.db.QueryRowContext(ctx, `SELECT value FROM tokens WHERE token = ?`, token).Scan(&sealed)
+	if xerrors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, xerrors.Errorf("unable to read token: %w", err)
+	}
+	size := v.aead.NonceSize()
+	if len(sealed) < size {
+		return nil, false, xerrors.New("corrupted token value")
+	}
+	raw, err := v.aead.Open(nil, sealed[:size], sealed[size:], []byte(token))
+	if err != nil {
+		return nil, false, xerrors.Errorf("unable to decrypt token value: %w", err)
+	}
+	return decode(raw)
+}
+
+// decode restores a stored value, integral numbers become int64
+func decode(raw []byte) (any, bool, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, false, xerrors.Errorf("unable to decode token value: %w", err)
+	}
+	if n, ok := value.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i, true, nil
+		}
+		f, err := n.Float64()
+		return f, err == nil, err
+	}
+	return value, true, nil
+}
+
+func (v *vault) Close() error {
+	return v.db.Close()
+}
@@ -1,12 +1,12 @@
 package restgenerator
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"path"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/centralmind/gateway/connectors"
@@ -14,8 +14,10 @@ import (
 	gw_model "github.com/centralmind/gateway/model"
 	"github.com/centralmind/gateway/plugins"
 	"github.com/centralmind/gateway/prompter"
+	"github.com/centralmind/gateway/sqlguard"
 	"github.com/centralmind/gateway/swaggerator"
 	"github.com/centralmind/gateway/xcontext"
+	"github.com/danielgtaylor/huma/v2"
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
 	"golang.org/x/xerrors"
@@ -24,43 +26,32 @@ import (
 // Rest handles OpenAPI schema generation and sample data serving.
 type Rest struct {
 	Schema       gw_model.Config
-	interceptors []plugins.Interceptor
+	scope        *plugins.Scope
+	interceptors []plugins.BatchInterceptor // global ones, for queries without an endpoint
 	connector    connectors.Connector
+	guard        *sqlguard.Guard
 	prefix       string
 }
 
-// New initializes a new Rest instance.
+// New initializes a new Rest instance on top of a connector already wrapped by plugins of the scope,
+// guard checks raw queries and may be nil.
 func New(
 	schema gw_model.Config,
 	prefix string,
+	connector connectors.Connector,
+	guard *sqlguard.Guard,
+	scope *plugins.Scope,
 ) (*Rest, error) {
-	var interceptors []plugins.Interceptor
-	for k, v := range schema.Plugins {
-		plugin, err := plugins.New(k, v)
-		if err != nil {
-			return nil, err
-		}
-		interceptor, ok := plugin.(plugins.Interceptor)
-		if !ok {
-			continue
-		}
-		interceptors = append(interceptors, interceptor)
-	}
-	connector, err := connectors.New(schema.Database.Type, schema.Database.Connection)
-	if err != nil {
-		return nil, xerrors.Errorf("unable to init connector: %w", err)
-	}
-	connector, err = plugins.Wrap(schema.Plugins, connector)
+	interceptors, err := scope.Interceptors(gw_model.Endpoint{})
 	if err != nil {
-		return nil, xerrors.Errorf("unable to init connector plugins: %w", err)
-	}
-	if err := connector.Ping(context.Background()); err != nil {
-		return nil, xerrors.Errorf("unable to ping: %w", err)
+		return nil, err
 	}
 	return &Rest{
 		Schema:       schema,
+		scope:        scope,
 		interceptors: interceptors,
 		connector:    connector,
+		guard:        guard,
 		prefix:       prefix,
 	}, nil
 }
@@ -72,7 +63,7 @@ func (r *Rest) RegisterRoutes(mux *http.ServeMux, disableSwagger bool, rawMode b
 	}
 
 	// Pass all addresses to swaggerator.Schema
-	swagger, err := swaggerator.Schema(r.Schema, r.prefix, addresses...)
+	swagger, err := swaggerator.Schema(r.Schema, r.connector, r.prefix, addresses...)
 	if err != nil {
 		return xerrors.Errorf("unable to build swagger doc: %w", err)
 	}
@@ -85,13 +76,12 @@ func (r *Rest) RegisterRoutes(mux *http.ServeMux, disableSwagger bool, rawMode b
 		}
 	}
 
-	raw, err := json.Marshal(swagger)
-	if err != nil {
-		return xerrors.Errorf("unable to build swagger: %w", err)
-	}
-
 	if !disableSwagger {
-		swaggerator.RegisterRoute(mux, r.prefix, raw)
+		specHandler, err := r.swaggerHandler(swagger)
+		if err != nil {
+			return xerrors.Errorf("unable to build swagger: %w", err)
+		}
+		swaggerator.RegisterRouteHandler(mux, r.prefix, specHandler)
 	}
 
 	d := gin.Default()
@@ -126,11 +116,40 @@ func (r *Rest) RegisterRoutes(mux *http.ServeMux, disableSwagger bool, rawMode b
 	return nil
 }
 
+// swaggerHandler serves the OpenAPI spec with endpoint operations hidden from callers
+// that are not allowed to use them
+func (r *Rest) swaggerHandler(swagger *huma.OpenAPI) (http.Handler, error) {
+	endpoints := map[string]gw_model.Endpoint{}
+	for _, endpoint := range r.Schema.Database.Endpoints {
+		endpoints[endpoint.MCPMethod] = endpoint
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := xcontext.WithHeader(req.Context(), req.Header)
+		spec := swaggerator.Filter(swagger, func(operationID string) bool {
+			endpoint, ok := endpoints[operationID]
+			return !ok || r.scope.Visible(ctx, endpoint)
+		})
+		raw, err := json.Marshal(spec)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to build swagger: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(raw)
+	}), nil
+}
+
 func (r *Rest) Handler(endpoint gw_model.Endpoint) gin.HandlerFunc {
+	interceptors, interceptorsErr := r.scope.Interceptors(endpoint)
 	return func(c *gin.Context) {
+		if interceptorsErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": interceptorsErr.Error()})
+			return
+		}
 		params := make(map[string]any)
 		ctx := c.Request.Context()
 		ctx = xcontext.WithHeader(ctx, c.Request.Header)
+		ctx, stats := xcontext.WithQueryStats(ctx)
 		for _, param := range c.Params {
 			params[param.Key] = param.Value
 		}
@@ -150,24 +169,15 @@ func (r *Rest) Handler(endpoint gw_model.Endpoint) gin.HandlerFunc {
 
 		raw, err := r.connector.Query(ctx, endpoint, params)
 		if err != nil {
-			code := http.StatusInternalServerError
-			if errors.Is(err, gw_errors.ErrNotAuthorized) {
-				code = http.StatusUnauthorized
-			}
-			c.JSON(code, gin.H{"error": err.Error()})
+			setStatsHeaders(c, stats)
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
-		var res []map[string]any
-	MAIN:
-		for _, row := range raw {
-			for _, interceptor := range r.interceptors {
-				r, skip := interceptor.Process(row, c.Request.Header)
-				if skip {
-					continue MAIN
-				}
-				row = r
-			}
-			res = append(res, row)
+		res, err := plugins.Intercept(ctx, interceptors, plugins.Call{Endpoint: endpoint, Params: params}, raw)
+		setStatsHeaders(c, stats)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
 		}
 		if !endpoint.IsArrayResult {
 			if len(res) == 0 {
@@ -195,6 +205,7 @@ func (r *Rest) ListTablesHandler() gin.HandlerFunc {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to discover data: %v", err)})
 			return
 		}
+		data = r.guard.Tables(data)
 
 		// Format the response
 		var result []map[string]interface{}
@@ -236,6 +247,7 @@ func (r *Rest) DiscoverDataHandler() gin.HandlerFunc {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to discover all tables: %v", err)})
 			return
 		}
+		allTables = r.guard.Tables(allTables)
 
 		tablesList := c.Query("tables_list")
 		tableSet := map[string]bool{}
@@ -264,17 +276,10 @@ func (r *Rest) DiscoverDataHandler() gin.HandlerFunc {
 				return
 			}
 
-			var res []map[string]any
-		MAIN:
-			for _, row := range sample {
-				for _, interceptor := range r.interceptors {
-					r, skip := interceptor.Process(row, c.Request.Header)
-					if skip {
-						continue MAIN
-					}
-					row = r
-				}
-				res = append(res, row)
+			res, err := plugins.Intercept(ctx, r.interceptors, plugins.Call{}, r.guard.Sample(table, sample))
+			if err != nil {
+				c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+				return
 			}
 
 			// Convert columns to a format suitable for JSON
@@ -289,7 +294,7 @@ func (r *Rest) DiscoverDataHandler() gin.HandlerFunc {
 			result = append(result, map[string]interface{}{
 				"name":      table.Name,
 				"columns":   columns,
-				"sample":    sample,
+				"sample":    res,
 				"row_count": table.RowCount,
 			})
 		}
@@ -310,7 +315,15 @@ func (r *Rest) PrepareQueryHandler() gin.HandlerFunc {
 			return
 		}
 
+		query, err := r.guard.Check(ctx, query)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx, stats := xcontext.WithQueryStats(ctx)
 		resSchema, err := r.connector.InferQuery(ctx, query)
+		setStatsHeaders(c, stats)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to infer query: %v", err)})
 			return
@@ -341,35 +354,60 @@ func (r *Rest) QueryHandler() gin.HandlerFunc {
 			return
 		}
 
+		query, err := r.guard.Check(ctx, query)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx, stats := xcontext.WithQueryStats(ctx)
 		resData, err := r.connector.Query(
 			ctx,
 			gw_model.Endpoint{Query: query},
 			make(map[string]any),
 		)
 		if err != nil {
-			code := http.StatusInternalServerError
-			if errors.Is(err, gw_errors.ErrNotAuthorized) {
-				code = http.StatusUnauthorized
-			}
-			c.JSON(code, gin.H{"error": err.Error()})
+			setStatsHeaders(c, stats)
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
-		var res []map[string]any
-	MAIN:
-		for _, row := range resData {
-			for _, interceptor := range r.interceptors {
-				r, skip := interceptor.Process(row, c.Request.Header)
-				if skip {
-					continue MAIN
-				}
-				row = r
-			}
-			res = append(res, row)
+		res, err := plugins.Intercept(ctx, r.interceptors, plugins.Call{Endpoint: gw_model.Endpoint{Query: query}}, resData)
+		setStatsHeaders(c, stats)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
 		}
 		c.JSON(http.StatusOK, res)
 	}
 }
 
+// setStatsHeaders reports query stats collected by the connector
+func setStatsHeaders(c *gin.Context, stats *xcontext.QueryStats) {
+	if stats.EstimatedBytes > 0 {
+		c.Header("X-Estimated-Bytes", strconv.FormatInt(stats.EstimatedBytes, 10))
+	}
+	for key, value := range stats.Meta {
+		c.Header("X-Gateway-"+key, value)
+	}
+}
+
+// errorStatus maps query errors to HTTP status codes
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, gw_errors.ErrNotAuthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, sqlguard.ErrRejected):
+		return http.StatusBadRequest
+	case errors.Is(err, gw_errors.ErrTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, gw_errors.ErrTooManyRows), errors.Is(err, gw_errors.ErrTooExpensive):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, gw_errors.ErrTooManyRequests):
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
 func convertSwaggerToGin(swaggerURL string) string {
 	re := regexp.MustCompile(`\{([^}]+)\}`)
 	return re.ReplaceAllString(swaggerURL, ":$1")
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/centralmind/gateway/mcp"
+	"github.com/centralmind/gateway/xcontext"
+)
+
+// MessageSender delivers a server-initiated JSON-RPC message to a connected client.
+type MessageSender func(message mcp.JSONRPCMessage) error
+
+// clientSession tracks what is needed to send requests to a connected client
+type clientSession struct {
+	send         MessageSender
+	capabilities mcp.ClientCapabilities
+}
+
+// outgoing keeps state of server-initiated requests waiting for client responses
+type outgoing struct {
+	mu       sync.Mutex
+	seq      int64
+	sessions map[string]*clientSession
+	pending  map[pendingKey]chan json.RawMessage
+}
+
+// pendingKey identifies a request by the session it was sent to, so only that session can answer it
+type pendingKey struct {
+	session string
+	id      string
+}
+
+// RegisterSession makes a transport session reachable for server-initiated requests.
+func (s *MCPServer) RegisterSession(sessionID string, send MessageSender) {
+	s.outgoing.mu.Lock()
+	defer s.outgoing.mu.Unlock()
+	if session, ok := s.outgoing.sessions[sessionID]; ok {
+		session.send = send
+		return
+	}
+	s.outgoing.sessions[sessionID] = &clientSession{send: send}
+}
+
+// UnregisterSession removes a transport session once the client disconnects.
+func (s *MCPServer) UnregisterSession(sessionID string) {
+	s.outgoing.mu.Lock()
+	defer s.outgoing.mu.Unlock()
+	delete(s.outgoing.sessions, sessionID)
+}
+
+// setClientCapabilities remembers capabilities a client announced in initialize
+func (s *MCPServer) setClientCapabilities(sessionID string, capabilities mcp.ClientCapabilities) {
+	s.outgoing.mu.Lock()
+	defer s.outgoing.mu.Unlock()
+	session, ok := s.outgoing.sessions[sessionID]
+	if !ok {
+		session = &clientSession{}
+		s.outgoing.sessions[sessionID] = session
+	}
+	session.capabilities = capabilities
+}
+
+// ClientSupportsSampling reports whether the client of the session in ctx can serve sampling requests.
+func (s *MCPServer) ClientSupportsSampling(ctx context.Context) bool {
+	s.outgoing.mu.Lock()
+	defer s.outgoing.mu.Unlock()
+	session, ok := s.outgoing.sessions[xcontext.Session(ctx)]
+	return ok && session.send != nil && session.capabilities.Sampling != nil
+}
+
+// SendRequest sends a request to the client of the session in ctx and waits for its response.
+// The call is bound to ctx, so callers should set a deadline.
+func (s *MCPServer) SendRequest(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	s.outgoing.mu.Lock()
+	session, ok := s.outgoing.sessions[xcontext.Session(ctx)]
+	if !ok || session.send == nil {
+		s.outgoing.mu.Unlock()
+		return nil, fmt.Errorf("no client connected for session %q", xcontext.Session(ctx))
+	}
+	s.outgoing.seq++
+	id := fmt.Sprintf("gateway-%d", s.outgoing.seq)
+	key := pendingKey{session: xcontext.Session(ctx), id: id}
+	waiter := make(chan json.RawMessage, 1)
+	s.outgoing.pending[key] = waiter
+	send := session.send
+	s.outgoing.mu.Unlock()
+
+	defer func() {
+		s.outgoing.mu.Lock()
+		delete(s.outgoing.pending, key)
+		s.outgoing.mu.Unlock()
+	}()
+
+	if err := send(mcp.JSONRPCRequest{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		ID:      id,
+		Params:  params,
+		Request: mcp.Request{Method: method},
+	}); err != nil {
+		return nil, fmt.Errorf("unable to send %s request: %w", method, err)
+	}
+
+	select {
+	case raw := <-waiter:
+		var response struct {
+			Result json.RawMessage `json:"result"`
+			Error  *struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(raw, &response); err != nil {
+			return nil, fmt.Errorf("unable to parse %s response: %w", method, err)
+		}
+		if response.Error != nil {
+			return nil, fmt.Errorf("client rejected %s: %d: %s", method, response.Error.Code, response.Error.Message)
+		}
+		return response.Result, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%s request cancelled: %w", method, ctx.Err())
+	}
+}
+
+// RequestSampling asks the client's LLM to generate a message via sampling/createMessage.
+func (s *MCPServer) RequestSampling(ctx context.Context, request mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	if !s.ClientSupportsSampling(ctx) {
+		return nil, fmt.Errorf("client does not support sampling")
+	}
+	raw, err := s.SendRequest(ctx, "sampling/createMessage", request.Params)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.ParseCreateMessageResult(&raw)
+}
+
+// handleResponse routes a client response to the server-initiated request waiting for it,
+// responses to requests sent to other sessions are dropped
+func (s *MCPServer) handleResponse(ctx context.Context, id interface{}, message json.RawMessage) {
+	key := pendingKey{session: xcontext.Session(ctx), id: fmt.Sprintf("%v", id)}
+	s.outgoing.mu.Lock()
+	waiter, ok := s.outgoing.pending[key]
+	s.outgoing.mu.Unlock()
+	if !ok {
+		return
+	}
+	select {
+	case waiter <- message:
+	default:
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/centralmind/gateway/mcp"
+	"github.com/centralmind/gateway/xcontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMCPServer_RequestSampling(t *testing.T) {
+	server := NewMCPServer("test-server", "1.0.0")
+	ctx := xcontext.WithSession(context.Background(), "session-1")
+
+	// Fake client answers every request with a sampling result, delivered as a separate message
+	server.RegisterSession("session-1", func(message mcp.JSONRPCMessage) error {
+		request := message.(mcp.JSONRPCRequest)
+		assert.Equal(t, "sampling/createMessage", request.Method)
+		go server.HandleMessage(ctx, []byte(fmt.Sprintf(`{
+          "jsonrpc": "2.0",
+          "id": %q,
+          "result": {"role": "assistant", "model": "test-model", "content": {"type": "text", "text": "summary"}}
+        }`, request.ID)))
+		return nil
+	})
+
+	var request mcp.CreateMessageRequest
+	request.Params.MaxTokens = 10
+	_, err := server.RequestSampling(ctx, request)
+	require.Error(t, err, "client did not announce sampling capability")
+
+	server.HandleMessage(ctx, []byte(`{
+      "jsonrpc": "2.0",
+      "id": 1,
+      "method": "initialize",
+      "params": {"capabilities": {"sampling": {}}}
+    }`))
+	assert.True(t, server.ClientSupportsSampling(ctx))
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	result, err := server.RequestSampling(ctx, request)
+	require.NoError(t, err)
+	assert.Equal(t, "test-model", result.Model)
+	assert.Equal(t, "summary", result.Content.(mcp.TextContent).Text)
+}
+
+func TestMCPServer_SendRequestClientError(t *testing.T) {
+	server := NewMCPServer("test-server", "1.0.0")
+	ctx := xcontext.WithSession(context.Background(), "session-1")
+	server.RegisterSession("session-1", func(message mcp.JSONRPCMessage) error {
+		request := message.(mcp.JSONRPCRequest)
+		raw, _ := json.Marshal(CreateErrorResponse(request.ID, mcp.INVALID_REQUEST, "user rejected"))
+		go server.HandleMessage(ctx, raw)
+		return nil
+	})
+
+	_, err := server.SendRequest(ctx, "sampling/createMessage", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "user rejected")
+}
+
+func TestMCPServer_SendRequestTimeout(t *testing.T) {
+	server := NewMCPServer("test-server", "1.0.0")
+	ctx := xcontext.WithSession(context.Background(), "session-1")
+	server.RegisterSession("session-1", func(message mcp.JSONRPCMessage) error {
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err := server.SendRequest(ctx, "sampling/createMessage", nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestMCPServer_ResponseFromOtherSession(t *testing.T) {
+	server := NewMCPServer("test-server", "1.0.0")
+	ctx := xcontext.WithSession(context.Background(), "session-1")
+	other := xcontext.WithSession(context.Background(), "session-2")
+	server.RegisterSession("session-1", func(message mcp.JSONRPCMessage) error {
+		request := message.(mcp.JSONRPCRequest)
+		response := fmt.Sprintf(`{"jsonrpc": "2.0", "id": %q, "result": {"answer": %%q}}`, request.ID)
+		// another session guessing the id is ignored, the response of the session itself is taken
+		server.HandleMessage(other, []byte(fmt.Sprintf(response, "forged")))
+		go server.HandleMessage(ctx, []byte(fmt.Sprintf(response, "genuine")))
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	raw, err := server.SendRequest(ctx, "sampling/createMessage", nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"answer": "genuine"}`, string(raw))
+}
@@ -12,6 +12,7 @@ import (
 	"sync/atomic"
 
 	"github.com/centralmind/gateway/mcp"
+	"github.com/centralmind/gateway/xcontext"
 )
 
 // resourceEntry holds both a resource and its handler
@@ -44,6 +45,9 @@ type ToolHandlerFunc func(ctx context.Context, request mcp.CallToolRequest) (*mc
 // ToolMiddlewareFunc add a middleware interceptor for tool
 type ToolMiddlewareFunc func(ctx context.Context, tool ServerTool, request mcp.CallToolRequest) (*mcp.CallToolResult, error)
 
+// ToolFilterFunc decides whether a tool is visible to the caller described by ctx
+type ToolFilterFunc func(ctx context.Context, tool mcp.Tool) bool
+
 // AuthChecker verify sse request
 type AuthChecker func(r *http.Request) bool
 
@@ -80,6 +84,7 @@ type MCPServer struct {
 	promptHandlers       map[string]PromptHandlerFunc
 	tools                map[string]ServerTool
 	toolMiddlewares      []ToolMiddlewareFunc
+	toolFilters          []ToolFilterFunc
 	authCheckers         []AuthChecker
 	notificationHandlers map[string]NotificationHandlerFunc
 	instructions         string
@@ -88,6 +93,7 @@ type MCPServer struct {
 	clientMu             sync.Mutex // Separate mutex for client context
 	currentClient        NotificationContext
 	initialized          atomic.Bool // Use atomic for the initialized flag
+	outgoing             outgoing    // Server-initiated requests and client sessions
 }
 
 // serverKey is the context key for storing the server instance
@@ -215,6 +221,10 @@ func NewMCPServer(
 		version:              version,
 		notificationHandlers: make(map[string]NotificationHandlerFunc),
 		notifications:        make(chan ServerNotification, 100),
+		outgoing: outgoing{
+			sessions: make(map[string]*clientSession),
+			pending:  make(map[pendingKey]chan json.RawMessage),
+		},
 	}
 
 	for _, opt := range opts {
@@ -268,6 +278,12 @@ func (s *MCPServer) HandleMessage(
 		return nil // Return nil for notifications
 	}
 
+	if baseMessage.Method == "" {
+		// Response to a server-initiated request, nothing to answer
+		s.handleResponse(ctx, baseMessage.ID, message)
+		return nil
+	}
+
 	switch baseMessage.Method {
 	case "initialize":
 		var request mcp.InitializeRequest
@@ -471,6 +487,23 @@ func (s *MCPServer) AddToolMiddleware(f ToolMiddlewareFunc) {
 	s.toolMiddlewares = append(s.toolMiddlewares, f)
 }
 
+// AddToolFilter registers a filter that hides tools from callers in tools/list and tools/call
+func (s *MCPServer) AddToolFilter(f ToolFilterFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.toolFilters = append(s.toolFilters, f)
+}
+
+// toolVisible checks tool against all registered filters, must be called without s.mu held
+func (s *MCPServer) toolVisible(ctx context.Context, tool mcp.Tool, filters []ToolFilterFunc) bool {
+	for _, filter := range filters {
+		if !filter(ctx, tool) {
+			return false
+		}
+	}
+	return true
+}
+
 // AddTools registers multiple tools at once
 func (s *MCPServer) AddTools(tools ...ServerTool) {
 	s.mu.Lock()
@@ -548,6 +581,7 @@ func (s *MCPServer) handleInitialize(
 	id interface{},
 	request mcp.InitializeRequest,
 ) mcp.JSONRPCMessage {
+	s.setClientCapabilities(xcontext.Session(ctx), request.Params.Capabilities)
 	capabilities := mcp.ServerCapabilities{}
 
 	capabilities.Resources = nil // Not Supported
@@ -763,8 +797,19 @@ func (s *MCPServer) handleListTools(
 	for _, name := range toolNames {
 		tools = append(tools, s.tools[name].Tool)
 	}
+	filters := s.toolFilters
 	s.mu.RUnlock()
 
+	if len(filters) > 0 {
+		visible := make([]mcp.Tool, 0, len(tools))
+		for _, tool := range tools {
+			if s.toolVisible(ctx, tool, filters) {
+				visible = append(visible, tool)
+			}
+		}
+		tools = visible
+	}
+
 	result := mcp.ListToolsResult{
 		Tools: tools,
 	}
@@ -781,8 +826,12 @@ func (s *MCPServer) handleToolCall(
 ) mcp.JSONRPCMessage {
 	s.mu.RLock()
 	tool, ok := s.tools[request.Params.Name]
+	filters := s.toolFilters
 	s.mu.RUnlock()
 
+	if ok && !s.toolVisible(ctx, tool.Tool, filters) {
+		ok = false
+	}
 	if !ok {
 		return CreateErrorResponse(
 			id,
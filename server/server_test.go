@@ -205,6 +205,36 @@ func TestMCPServer_Tools(t *testing.T) {
 	}
 }
 
+func TestMCPServer_ToolFilters(t *testing.T) {
+	ctx := context.Background()
+	server := NewMCPServer("test-server", "1.0.0")
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	}
+	server.AddTool(mcp.NewTool("public-tool"), handler)
+	server.AddTool(mcp.NewTool("secret-tool"), handler)
+	server.AddToolFilter(func(ctx context.Context, tool mcp.Tool) bool {
+		return tool.Name != "secret-tool"
+	})
+
+	toolsList := server.HandleMessage(ctx, []byte(`{
+      "jsonrpc": "2.0",
+      "id": 1,
+      "method": "tools/list"
+    }`))
+	tools := toolsList.(mcp.JSONRPCResponse).Result.(mcp.ListToolsResult).Tools
+	assert.Len(t, tools, 1)
+	assert.Equal(t, "public-tool", tools[0].Name)
+
+	callResult := server.HandleMessage(ctx, []byte(`{
+      "jsonrpc": "2.0",
+      "id": 2,
+      "method": "tools/call",
+      "params": {"name": "secret-tool"}
+    }`))
+	assert.Equal(t, "Tool not found: secret-tool", callResult.(mcp.JSONRPCError).Error.Message)
+}
+
 func TestMCPServer_HandleValidMessages(t *testing.T) {
 	server := NewMCPServer("test-server", "1.0.0",
 		WithResourceCapabilities(true, true),
@@ -29,9 +29,15 @@ type sseSession struct {
 	writer     http.ResponseWriter
 	flusher    http.Flusher
 	done       chan struct{}
+	closeOnce  sync.Once
 	eventQueue chan string // Channel for queuing events
 }
 
+// close ends the session, it is called both by the handler and by Shutdown
+func (s *sseSession) close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
 // NewSSEServer creates a new SSE server instance with the given MCP server and base URL.
 func NewSSEServer(server *MCPServer, baseURL string, prefix string) *SSEServer {
 	return &SSEServer{
@@ -64,17 +70,17 @@ func (s *SSEServer) Start(addr string) error {
 }
 
 // Shutdown gracefully stops the SSE server, closing all active sessions
-// and shutting down the HTTP server.
+// and shutting down the HTTP server if it was started by Start.
 func (s *SSEServer) Shutdown(ctx context.Context) error {
+	// sessions are closed even when the server is mounted on another mux
+	s.sessions.Range(func(key, value interface{}) bool {
+		if session, ok := value.(*sseSession); ok {
+			session.close()
+		}
+		s.sessions.Delete(key)
+		return true
+	})
 	if s.srv != nil {
-		s.sessions.Range(func(key, value interface{}) bool {
-			if session, ok := value.(*sseSession); ok {
-				close(session.done)
-			}
-			s.sessions.Delete(key)
-			return true
-		})
-
 		return s.srv.Shutdown(ctx)
 	}
 	return nil
@@ -114,6 +120,10 @@ func (s *SSEServer) handleSSE(w http.ResponseWriter, r *http.Request) {
 
 	s.sessions.Store(sessionID, session)
 	defer s.sessions.Delete(sessionID)
+	s.server.RegisterSession(sessionID, func(message mcp.JSONRPCMessage) error {
+		return s.SendEventToSession(sessionID, message)
+	})
+	defer s.server.UnregisterSession(sessionID)
 
 	// Start notification handler for this session
 	go func() {
@@ -158,7 +168,9 @@ func (s *SSEServer) handleSSE(w http.ResponseWriter, r *http.Request) {
 			fmt.Fprint(w, event)
 			flusher.Flush()
 		case <-r.Context().Done():
-			close(session.done)
+			session.close()
+			return
+		case <-session.done:
 			return
 		}
 	}
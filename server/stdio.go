@@ -10,6 +10,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 
 	"github.com/centralmind/gateway/mcp"
@@ -21,6 +22,7 @@ import (
 type StdioServer struct {
 	server    *MCPServer
 	errLogger *log.Logger
+	writeMu   sync.Mutex // Responses, notifications and server requests share stdout
 }
 
 // NewStdioServer creates a new stdio server wrapper around an MCPServer.
@@ -59,6 +61,11 @@ func (s *StdioServer) Listen(
 	ctx = xcontext.WithSession(ctx, "stdio")
 	reader := bufio.NewReader(stdin)
 
+	s.server.RegisterSession("stdio", func(message mcp.JSONRPCMessage) error {
+		return s.writeResponse(message, stdout)
+	})
+	defer s.server.UnregisterSession("stdio")
+
 	// Start notification handler
 	go func() {
 		for {
@@ -138,6 +145,21 @@ func (s *StdioServer) processMessage(
 		return s.writeResponse(response, writer)
 	}
 
+	// Tool calls may wait for the client (e.g. sampling), so they must not block reading its responses
+	var base struct {
+		Method string `json:"method"`
+	}
+	if err := json.Unmarshal(rawMessage, &base); err == nil && base.Method == "tools/call" {
+		go func() {
+			if response := s.server.HandleMessage(ctx, rawMessage); response != nil {
+				if err := s.writeResponse(response, writer); err != nil {
+					s.errLogger.Printf("Error writing response: %v", err)
+				}
+			}
+		}()
+		return nil
+	}
+
 	// Handle the message using the wrapped server
 	response := s.server.HandleMessage(ctx, rawMessage)
 
@@ -162,6 +184,9 @@ func (s *StdioServer) writeResponse(
 		return err
 	}
 
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
 	// Write response followed by newline
 	if _, err := fmt.Fprintf(writer, "%s\n", responseBytes); err != nil {
 		return err
@@ -0,0 +1,447 @@
+package sqlguard
+
+import (
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+type tableRef struct {
+	parts    []string
+	function bool // table function such as read_csv(...)
+	// pos and end are byte offsets of the reference, namePos of its last part
+	pos, namePos, end int
+	aliased           bool // followed by an alias
+	// statement is set for TABLE name, a statement reading the whole table, statementPos is the offset of TABLE
+	statement    bool
+	statementPos int
+}
+
+type columnRef struct {
+	qualifier []string
+	column    string
+	star      bool
+	// alias is set for names that look like aliases, types or date parts.
+	// They are still checked against denied columns, since the heuristics may be wrong.
+	alias bool
+	index int // token index, CTE names depend on where they are used
+}
+
+func (r columnRef) String() string {
+	return strings.Join(append(append([]string{}, r.qualifier...), r.column), ".")
+}
+
+// analysis is what the guard needs to know about a query
+type analysis struct {
+	tables        []tableRef
+	aliases       map[string][]string // table alias -> table name
+	derived       map[string]bool     // aliases of subqueries and CTE references
+	ctes          []cte
+	cteNames      map[int]bool    // token indexes of CTE names in their definitions
+	columnLists   bool            // column alias lists rename columns by position, e.g. AS t(a, b)
+	outputAliases map[string]bool // select list and column list aliases
+	columns       []columnRef
+}
+
+// cte is a name defined by WITH, it refers to the CTE only between from and to token indexes:
+// after its definition and until the end of the statement defining it, or from WITH when recursive.
+// Elsewhere, including its own body, the name is a table.
+type cte struct {
+	name     string
+	from, to int
+}
+
+type aliasKind int
+
+const (
+	aliasNone aliasKind = iota
+	aliasTable
+	aliasDerived
+)
+
+// scope tracks one level of parentheses
+type scope struct {
+	selectSeen   bool      // SELECT appeared at this level, so FROM starts a table list
+	fromList     bool      // inside FROM ... list, commas separate tables
+	expect       bool      // next token starts a table reference
+	pendingAlias aliasKind // a table reference just ended and may be followed by an alias
+	lastRef      []string
+	lastTable    int  // index of the table reference that may be followed by an alias, -1 if none
+	lastCTE      bool // the last reference is a CTE
+	statement    int  // byte offset of TABLE when the next table reference is a TABLE statement, -1 otherwise
+	openFunction bool // next parenthesis holds table function arguments
+	derived      bool // opened by a subquery in FROM
+	function     bool // opened by table function arguments in FROM
+}
+
+// analyze walks tokens and collects table and column references.
+// It is not a full parser, but it never skips tokens, so every identifier ends up either as a table, an alias or a column.
+func analyze(tokens []token) (*analysis, error) {
+	a := &analysis{
+		aliases:       map[string][]string{},
+		derived:       map[string]bool{},
+		outputAliases: map[string]bool{},
+		cteNames:      map[int]bool{},
+	}
+	a.collectCTEs(tokens)
+
+	scopes := []*scope{{statement: -1}}
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		s := scopes[len(scopes)-1]
+
+		if t.isSymbol("(") {
+			child := &scope{statement: -1}
+			switch {
+			case s.openFunction:
+				s.openFunction = false
+				child.function = true
+			case s.expect:
+				s.expect = false
+				child.derived = true
+				// parenthesized join: FROM (a JOIN b ON ...)
+				if i+1 < len(tokens) && tokens[i+1].name() {
+					child.expect = true
+					child.fromList = true
+				}
+			}
+			scopes = append(scopes, child)
+			continue
+		}
+		if t.isSymbol(")") {
+			if len(scopes) == 1 {
+				return nil, xerrors.New("unbalanced parentheses")
+			}
+			scopes = scopes[:len(scopes)-1]
+			parent := scopes[len(scopes)-1]
+			switch {
+			case s.derived:
+				parent.pendingAlias = aliasDerived
+			case s.function:
+				parent.pendingAlias = aliasTable
+			}
+			continue
+		}
+
+		if s.expect {
+			if t.is("LATERAL") || t.is("ONLY") {
+				continue
+			}
+			s.expect = false
+			if t.kind == tokenString {
+				return nil, xerrors.Errorf("reading files with %s is not allowed", t.text)
+			}
+			if t.name() {
+				pos := t.pos
+				if i > 0 && tokens[i-1].is("ONLY") {
+					// FROM ONLY table, the reference includes ONLY
+					pos = tokens[i-1].pos
+				}
+				parts, end := chain(tokens, i)
+				s.lastRef = parts
+				s.lastTable = -1
+				s.lastCTE = len(parts) == 1 && a.cte(parts[0], i)
+				ref := tableRef{parts: parts, pos: pos, namePos: tokens[end].pos, end: tokens[end].end}
+				if s.statement >= 0 {
+					ref.statement, ref.statementPos = true, s.statement
+					s.statement = -1
+				}
+				i = end
+				if i+1 < len(tokens) && tokens[i+1].isSymbol("(") {
+					s.openFunction = true
+					if !strings.EqualFold(parts[len(parts)-1], "UNNEST") {
+						ref.function = true
+						a.tables = append(a.tables, ref)
+					}
+					continue
+				}
+				if !s.lastCTE {
+					a.tables = append(a.tables, ref)
+					s.lastTable = len(a.tables) - 1
+				}
+				s.pendingAlias = aliasTable
+				continue
+			}
+		}
+
+		if s.pendingAlias != aliasNone {
+			kind := s.pendingAlias
+			s.pendingAlias = aliasNone
+			alias := -1
+			switch {
+			case t.is("AS") && i+1 < len(tokens) && tokens[i+1].name():
+				alias = i + 1
+			case t.name():
+				alias = i
+			}
+			if alias >= 0 {
+				key := strings.ToUpper(tokens[alias].text)
+				if kind == aliasTable && !s.lastCTE {
+					a.aliases[key] = s.lastRef
+					if s.lastTable >= 0 {
+						a.tables[s.lastTable].aliased = true
+					}
+				} else {
+					a.derived[key] = true
+				}
+				i = alias
+				// column aliases: AS t(a, b)
+				if i+1 < len(tokens) && tokens[i+1].isSymbol("(") {
+					i = a.skipNames(tokens, i+1)
+				}
+				continue
+			}
+		}
+
+		switch {
+		case t.is("SELECT"):
+			s.selectSeen = true
+			s.fromList = false
+		case t.is("FROM"):
+			// FROM also appears in EXTRACT(x FROM y) and IS DISTINCT FROM
+			if s.selectSeen && !(i > 1 && tokens[i-1].is("DISTINCT") && (tokens[i-2].is("IS") || tokens[i-2].is("NOT"))) {
+				s.fromList = true
+				s.expect = true
+			}
+		case t.is("JOIN"):
+			s.fromList = true
+			s.expect = true
+		case t.is("TABLE") && i+1 < len(tokens) && (tokens[i+1].name() || tokens[i+1].is("ONLY")):
+			// TABLE name reads all columns of the table, as SELECT * FROM name
+			next := i + 1
+			if tokens[next].is("ONLY") && next+1 < len(tokens) {
+				next++
+			}
+			parts, _ := chain(tokens, next)
+			a.columns = append(a.columns, columnRef{qualifier: parts, column: "*", star: true, index: next})
+			s.statement = t.pos
+			s.expect = true
+		case t.isSymbol(","):
+			if s.fromList {
+				s.expect = true
+			}
+		case t.kind == tokenIdent && clauseEnd[t.upper()]:
+			s.fromList = false
+		case t.isSymbol("*"):
+			if i > 0 && (tokens[i-1].is("SELECT") || tokens[i-1].is("DISTINCT") || tokens[i-1].is("ALL") || tokens[i-1].isSymbol(",")) {
+				a.columns = append(a.columns, columnRef{column: "*", star: true, index: i})
+			}
+		case t.name():
+			i = a.reference(tokens, i)
+		}
+	}
+	if len(scopes) != 1 {
+		return nil, xerrors.New("unbalanced parentheses")
+	}
+	return a, nil
+}
+
+// reference handles a name outside of FROM lists: a column, a function or an alias
+func (a *analysis) reference(tokens []token, i int) int {
+	var prev, prevprev token
+	if i > 0 {
+		prev = tokens[i-1]
+	}
+	if i > 1 {
+		prevprev = tokens[i-2]
+	}
+	switch {
+	case prev.isSymbol("::"):
+		// type cast
+		return a.alias(tokens[i].text, false, i)
+	case prev.isSymbol("(") && prevprev.kind == tokenIdent && datePartFunctions[prevprev.upper()]:
+		// EXTRACT(YEAR FROM ...)
+		return a.alias(tokens[i].text, false, i)
+	case prev.is("AS"):
+		return a.alias(tokens[i].text, true, i)
+	case a.cteNames[i]:
+		// CTE definition: WITH name AS (...)
+		return a.alias(tokens[i].text, false, i)
+	}
+
+	parts, end := chain(tokens, i)
+	if end+1 < len(tokens) && tokens[end+1].isSymbol("(") {
+		// function call, arguments are visited as usual
+		return end
+	}
+	if len(parts) == 1 {
+		if tokens[i].kind == tokenIdent && end+1 < len(tokens) && tokens[end+1].kind == tokenString {
+			// typed literal: DATE '2024-01-01'
+			return a.alias(parts[0], false, end)
+		}
+		if i > 0 && (prev.name() || prev.kind == tokenNumber || prev.kind == tokenString || prev.isSymbol(")") || prev.is("END")) {
+			// implicit alias: SELECT count(*) total
+			return a.alias(parts[0], true, end)
+		}
+	}
+	last := parts[len(parts)-1]
+	a.columns = append(a.columns, columnRef{
+		qualifier: parts[:len(parts)-1],
+		column:    last,
+		star:      last == "*",
+		index:     i,
+	})
+	return end
+}
+
+// alias records a name that is not a column reference, output aliases may be referenced later in ORDER BY
+func (a *analysis) alias(name string, output bool, end int) int {
+	if output {
+		a.outputAliases[strings.ToUpper(name)] = true
+	}
+	a.columns = append(a.columns, columnRef{column: name, alias: true, index: end})
+	return end
+}
+
+// resolve returns tables a column may belong to, derived is set for columns of subqueries and CTEs
+func (a *analysis) resolve(ref columnRef) (candidates [][]string, derived bool) {
+	switch len(ref.qualifier) {
+	case 0:
+		var tables [][]string
+		for _, table := range a.tables {
+			if !table.function {
+				tables = append(tables, table.parts)
+			}
+		}
+		return tables, len(tables) == 0 && (len(a.derived) > 0 || len(a.ctes) > 0)
+	case 1:
+		// table aliases and tables win over derived names, so a name used for both is checked
+		key := strings.ToUpper(ref.qualifier[0])
+		if table, ok := a.aliases[key]; ok {
+			return [][]string{table}, false
+		}
+		if (a.derived[key] || a.cte(key, ref.index)) && !a.reads(key) {
+			return nil, true
+		}
+	}
+	return [][]string{ref.qualifier}, false
+}
+
+// wholeRow reports whether an unqualified name refers to a whole table row, e.g. row_to_json(u)
+func (a *analysis) wholeRow(name string) ([]string, bool) {
+	key := strings.ToUpper(name)
+	if table, ok := a.aliases[key]; ok {
+		return table, true
+	}
+	for _, table := range a.tables {
+		if !table.function && strings.EqualFold(table.parts[len(table.parts)-1], name) {
+			return table.parts, true
+		}
+	}
+	return nil, false
+}
+
+// reads reports whether a table with the unqualified name is read
+func (a *analysis) reads(name string) bool {
+	for _, table := range a.tables {
+		if !table.function && strings.EqualFold(table.parts[len(table.parts)-1], name) {
+			return true
+		}
+	}
+	return false
+}
+
+// cte reports whether name refers to a CTE at token index i
+func (a *analysis) cte(name string, i int) bool {
+	for _, c := range a.ctes {
+		if c.from <= i && i < c.to && strings.EqualFold(c.name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectCTEs finds names defined by WITH name [(columns)] AS [NOT] [MATERIALIZED] (...)
+// and where they are visible
+func (a *analysis) collectCTEs(tokens []token) {
+	// closing parenthesis of every opening one and the innermost opening one around every token
+	closing := make([]int, len(tokens))
+	parent := make([]int, len(tokens))
+	var open []int
+	for i, t := range tokens {
+		parent[i] = -1
+		if len(open) > 0 {
+			parent[i] = open[len(open)-1]
+		}
+		switch {
+		case t.isSymbol("("):
+			open = append(open, i)
+		case t.isSymbol(")") && len(open) > 0:
+			closing[open[len(open)-1]] = i
+			open = open[:len(open)-1]
+		}
+	}
+	for _, i := range open {
+		closing[i] = len(tokens)
+	}
+	// WITH of the list being read at each nesting level, keyed by the opening parenthesis
+	with := map[int]int{}
+	for i := range tokens {
+		if tokens[i].is("WITH") {
+			with[parent[i]] = i
+		}
+		if i == 0 {
+			continue
+		}
+		prev := tokens[i-1]
+		if !tokens[i].name() || !(prev.is("WITH") || prev.is("RECURSIVE") || prev.isSymbol(",")) {
+			continue
+		}
+		j := i + 1
+		var columns []string
+		if j < len(tokens) && tokens[j].isSymbol("(") {
+			for j++; j < len(tokens) && !tokens[j].isSymbol(")"); j++ {
+				if tokens[j].name() {
+					columns = append(columns, tokens[j].text)
+				}
+			}
+			j++
+		}
+		if j >= len(tokens) || !tokens[j].is("AS") {
+			continue
+		}
+		for j++; j < len(tokens) && (tokens[j].is("NOT") || tokens[j].is("MATERIALIZED")); j++ {
+		}
+		if j < len(tokens) && tokens[j].isSymbol("(") {
+			start, ok := with[parent[i]]
+			if !ok {
+				continue
+			}
+			c := cte{name: tokens[i].text, from: closing[j] + 1, to: len(tokens)}
+			if parent[i] >= 0 {
+				c.to = closing[parent[i]]
+			}
+			if start+1 < len(tokens) && tokens[start+1].is("RECURSIVE") {
+				c.from = start
+			}
+			a.ctes = append(a.ctes, c)
+			a.cteNames[i] = true
+			a.columnLists = a.columnLists || len(columns) > 0
+			for _, column := range columns {
+				a.outputAliases[strings.ToUpper(column)] = true
+			}
+		}
+	}
+}
+
+// skipNames consumes a parenthesized list of column aliases starting at "(" and returns index of ")"
+func (a *analysis) skipNames(tokens []token, i int) int {
+	a.columnLists = true
+	for i++; i < len(tokens) && !tokens[i].isSymbol(")"); i++ {
+		if tokens[i].name() {
+			a.outputAliases[strings.ToUpper(tokens[i].text)] = true
+		}
+	}
+	return i
+}
+
+// chain reads a dotted name starting at i and returns its parts and the index of its last token
+func chain(tokens []token, i int) ([]string, int) {
+	parts := []string{tokens[i].text}
+	for i+2 < len(tokens) && tokens[i+1].isSymbol(".") &&
+		(tokens[i+2].kind == tokenIdent || tokens[i+2].kind == tokenQuotedIdent || tokens[i+2].isSymbol("*")) {
+		parts = append(parts, tokens[i+2].text)
+		i += 2
+	}
+	return parts, i
+}
@@ -0,0 +1,140 @@
+package sqlguard
+
+import "strings"
+
+type limitStyle int
+
+const (
+	limitClause limitStyle = iota // ... LIMIT n
+	limitTop                      // SELECT TOP n ...
+	limitFetch                    // ... FETCH FIRST n ROWS ONLY
+)
+
+// dialect describes lexical rules of a database that matter for the guard
+type dialect struct {
+	backslashEscapes    bool // backslash escapes quotes inside literals
+	doubleQuotedStrings bool // "..." is a string literal rather than an identifier
+	bracketIdents       bool // [...] quotes identifiers
+	hashComments        bool // # starts a line comment
+	escapeStrings       bool // E'...' literals with backslash escapes
+	atParams            bool // named parameters are @name rather than :name
+	nestedComments      bool // /* ... */ comments nest
+	executableComments  bool // /*! ... */ bodies run as SQL
+	limit               limitStyle
+	// functions are rejected anywhere in a query, also as parts of qualified names such as utl_http.request
+	functions map[string]bool
+}
+
+// dialects by connector type, unknown SQL connectors fall back to ANSI rules
+var dialects = map[string]dialect{
+	"postgres":   {escapeStrings: true, nestedComments: true, functions: postgresFunctions},
+	"postgresql": {escapeStrings: true, nestedComments: true, functions: postgresFunctions},
+	"duckdb":     {escapeStrings: true, nestedComments: true, functions: duckdbFunctions},
+	"files":      {escapeStrings: true, nestedComments: true, functions: duckdbFunctions},
+	"mysql":      {backslashEscapes: true, doubleQuotedStrings: true, hashComments: true, executableComments: true, functions: mysqlFunctions},
+	"clickhouse": {backslashEscapes: true, hashComments: true, functions: clickhouseFunctions},
+	"bigquery":   {backslashEscapes: true, doubleQuotedStrings: true, atParams: true, functions: bigqueryFunctions},
+	"snowflake":  {backslashEscapes: true},
+	"sqlite":     {bracketIdents: true, functions: sqliteFunctions},
+	"mssql":      {bracketIdents: true, limit: limitTop, functions: mssqlFunctions},
+	"oracle":     {limit: limitFetch, functions: oracleFunctions},
+	"trino":      {},
+}
+
+// nonSQL connectors take JSON queries and are not guarded
+var nonSQL = map[string]bool{
+	"mongodb":       true,
+	"elasticsearch": true,
+	"http":          true,
+}
+
+// forbidden keywords change data, schema, session state or files, they are rejected anywhere in a query
+var forbidden = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true, "UPSERT": true,
+	"DROP": true, "CREATE": true, "ALTER": true, "TRUNCATE": true, "RENAME": true,
+	"GRANT": true, "REVOKE": true, "COPY": true, "CALL": true, "EXEC": true, "EXECUTE": true,
+	"ATTACH": true, "DETACH": true, "PRAGMA": true, "INTO": true, "INSTALL": true, "VACUUM": true,
+}
+
+// Functions that read files, reach other servers, run SQL passed as text, wait or change server state.
+// Reading a table by name or running a query given as a string would also bypass table and column checks.
+var (
+	postgresFunctions = names(
+		"pg_read_file", "pg_read_binary_file", "pg_ls_dir", "pg_stat_file", "pg_file_write",
+		"lo_import", "lo_export", "lo_get", "lo_put", "lo_create", "lo_from_bytea", "lo_unlink",
+		"dblink", "dblink_exec", "dblink_connect", "dblink_send_query", "dblink_open",
+		"set_config", "nextval", "setval", "pg_sleep", "pg_sleep_for", "pg_sleep_until",
+		"pg_terminate_backend", "pg_cancel_backend", "pg_reload_conf", "pg_rotate_logfile",
+		"pg_notify", "pg_advisory_lock", "pg_advisory_xact_lock", "pg_logical_emit_message",
+		"query_to_xml", "query_to_xml_and_xmlschema", "query_to_xmlschema", "cursor_to_xml",
+		"table_to_xml", "table_to_xml_and_xmlschema", "schema_to_xml", "database_to_xml", "ts_stat",
+	)
+	duckdbFunctions = names("read_text", "read_blob", "glob", "getenv", "query", "query_table")
+	mysqlFunctions  = names(
+		"load_file", "sleep", "benchmark", "get_lock", "release_lock", "release_all_locks",
+		"master_pos_wait", "source_pos_wait",
+	)
+	clickhouseFunctions = names(
+		"file", "url", "s3", "s3cluster", "hdfs", "remote", "remotesecure", "cluster", "clusterallreplicas",
+		"mysql", "postgresql", "jdbc", "odbc", "mongodb", "redis", "sqlite", "executable", "input",
+		"azureblobstorage", "gcs", "sleep", "sleepeachrow",
+	)
+	bigqueryFunctions = names("external_query")
+	sqliteFunctions   = names("load_extension", "readfile", "writefile", "edit")
+	mssqlFunctions    = names("openrowset", "opendatasource", "openquery", "openxml")
+	oracleFunctions   = names(
+		"utl_http", "utl_file", "utl_tcp", "utl_smtp", "utl_inaddr", "utl_mail", "httpuritype",
+		"dbms_lock", "dbms_pipe", "dbms_sql", "dbms_xmlgen", "dbms_xmlquery", "dbms_ldap", "dbms_session",
+		"dbms_scheduler", "dbms_java",
+	)
+)
+
+func names(items ...string) map[string]bool {
+	res := make(map[string]bool, len(items))
+	for _, item := range items {
+		res[strings.ToUpper(item)] = true
+	}
+	return res
+}
+
+// clauseEnd keywords finish a FROM list
+var clauseEnd = map[string]bool{
+	"WHERE": true, "GROUP": true, "HAVING": true, "ORDER": true, "LIMIT": true, "OFFSET": true,
+	"FETCH": true, "UNION": true, "INTERSECT": true, "EXCEPT": true, "MINUS": true, "WINDOW": true,
+	"QUALIFY": true, "PREWHERE": true, "SETTINGS": true, "FORMAT": true, "FOR": true,
+}
+
+// setOperations combine several selects into one result
+var setOperations = map[string]bool{
+	"UNION": true, "INTERSECT": true, "EXCEPT": true, "MINUS": true,
+}
+
+// datePartFunctions take an unquoted date part such as YEAR as their first argument
+var datePartFunctions = map[string]bool{
+	"EXTRACT": true, "DATEADD": true, "DATEDIFF": true, "DATEPART": true, "DATENAME": true,
+	"TIMESTAMPADD": true, "TIMESTAMPDIFF": true, "TIMESTAMP_TRUNC": true,
+}
+
+// keywords are never treated as table, column or alias names.
+// Words that are common column names (date, year, name, value...) are deliberately absent.
+var keywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "AND": true, "OR": true, "NOT": true, "IN": true,
+	"IS": true, "NULL": true, "LIKE": true, "ILIKE": true, "RLIKE": true, "REGEXP": true, "SIMILAR": true,
+	"ESCAPE": true, "BETWEEN": true, "EXISTS": true, "CASE": true, "WHEN": true, "THEN": true,
+	"ELSE": true, "END": true, "AS": true, "ON": true, "JOIN": true, "INNER": true, "LEFT": true,
+	"RIGHT": true, "FULL": true, "OUTER": true, "CROSS": true, "NATURAL": true, "USING": true,
+	"SEMI": true, "ANTI": true, "ASOF": true, "GROUP": true, "BY": true, "ORDER": true, "HAVING": true,
+	"LIMIT": true, "OFFSET": true, "FETCH": true, "FIRST": true, "NEXT": true, "ROWS": true, "ROW": true,
+	"ONLY": true, "TOP": true, "PERCENT": true, "TIES": true, "DISTINCT": true, "ALL": true,
+	"UNION": true, "INTERSECT": true, "EXCEPT": true, "MINUS": true, "WITH": true, "RECURSIVE": true,
+	"MATERIALIZED": true, "ASC": true, "DESC": true, "NULLS": true, "LAST": true, "TRUE": true,
+	"FALSE": true, "UNKNOWN": true, "INTERVAL": true, "OVER": true, "PARTITION": true, "WINDOW": true,
+	"FILTER": true, "WITHIN": true, "LATERAL": true, "ANY": true, "SOME": true, "COLLATE": true,
+	"QUALIFY": true, "PREWHERE": true, "FINAL": true, "SAMPLE": true, "TABLESAMPLE": true,
+	"PIVOT": true, "UNPIVOT": true, "FOR": true, "SETTINGS": true, "FORMAT": true, "DIV": true,
+	"MOD": true, "XOR": true, "UNBOUNDED": true, "PRECEDING": true, "FOLLOWING": true,
+	"CURRENT": true, "RANGE": true, "GROUPS": true, "ROLLUP": true, "CUBE": true, "GROUPING": true,
+	"SETS": true, "CURRENT_DATE": true, "CURRENT_TIME": true, "CURRENT_TIMESTAMP": true,
+	"LOCALTIME": true, "LOCALTIMESTAMP": true, "CURRENT_USER": true, "SESSION_USER": true,
+	"ARRAY": true, "VALUES": true, "TABLE": true,
+}
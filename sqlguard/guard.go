@@ -0,0 +1,384 @@
+// Package sqlguard checks raw SQL sent by callers before it reaches the database.
+// It permits a single SELECT (or WITH) statement without writes or known functions that read files,
+// reach other servers or change server state, enforces table and column allow/deny lists,
+// adds a default LIMIT and optionally rejects expensive queries.
+package sqlguard
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/centralmind/gateway/connectors"
+	"github.com/centralmind/gateway/model"
+	"golang.org/x/xerrors"
+)
+
+// ErrRejected is returned (wrapped) when a query violates the guard rules
+var ErrRejected = xerrors.New("query rejected")
+
+// Guard validates and rewrites raw queries, a nil Guard accepts everything as is.
+type Guard struct {
+	config    model.RawParams
+	dialect   dialect
+	explainer connectors.Explainer
+
+	allowedTables  [][]string
+	deniedTables   [][]string
+	allowedColumns []columnRule
+	deniedColumns  []columnRule
+}
+
+// columnRule matches column in any table when table is empty
+type columnRule struct {
+	table  []string
+	column string
+}
+
+// New builds a guard for the connector. It returns nil for connectors that don't take SQL.
+// connector shall not be wrapped by plugins, since cost estimation needs its Explainer implementation.
+func New(config model.RawParams, connector connectors.Connector) (*Guard, error) {
+	typ := connector.Config().Type()
+	if nonSQL[typ] {
+		return nil, nil
+	}
+	g := &Guard{
+		config:  config,
+		dialect: dialects[typ],
+	}
+	if config.MaxCost > 0 {
+		explainer, ok := connector.(connectors.Explainer)
+		if !ok {
+			return nil, xerrors.Errorf("%s connector does not support query cost estimation, unset max_cost", typ)
+		}
+		g.explainer = explainer
+	}
+	for _, table := range config.AllowedTables {
+		g.allowedTables = append(g.allowedTables, splitName(table))
+	}
+	for _, table := range config.DeniedTables {
+		g.deniedTables = append(g.deniedTables, splitName(table))
+	}
+	for _, column := range config.AllowedColumns {
+		g.allowedColumns = append(g.allowedColumns, parseColumnRule(column))
+	}
+	for _, column := range config.DeniedColumns {
+		g.deniedColumns = append(g.deniedColumns, parseColumnRule(column))
+	}
+	return g, nil
+}
+
+// Check validates query and returns it ready for execution, with a LIMIT added if configured.
+// Violations are reported as errors wrapping ErrRejected.
+func (g *Guard) Check(ctx context.Context, query string) (string, error) {
+	if g == nil {
+		return query, nil
+	}
+	tokens, err := lex(query, g.dialect)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	for len(tokens) > 0 && tokens[len(tokens)-1].isSymbol(";") {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) == 0 {
+		return "", fmt.Errorf("%w: empty query", ErrRejected)
+	}
+	if err := checkStatement(tokens, g.dialect); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	a, err := analyze(tokens)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	if err := g.checkTables(a); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	if err := g.checkColumns(a); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+
+	query = g.limit(query[:tokens[len(tokens)-1].end], tokens)
+	if g.explainer != nil {
+		cost, err := g.explainer.Explain(ctx, query)
+		if err != nil {
+			return "", xerrors.Errorf("unable to estimate query cost: %w", err)
+		}
+		if cost > g.config.MaxCost {
+			return "", fmt.Errorf("%w: estimated cost %.0f exceeds limit %.0f, narrow the query down", ErrRejected, cost, g.config.MaxCost)
+		}
+	}
+	return query, nil
+}
+
+// checkStatement makes sure tokens form a single read-only statement without forbidden functions
+func checkStatement(tokens []token, d dialect) error {
+	first := tokens[0]
+	for i := 0; first.isSymbol("(") && i+1 < len(tokens); i++ {
+		first = tokens[i+1]
+	}
+	if !first.is("SELECT") && !first.is("WITH") {
+		return xerrors.Errorf("only SELECT statements are allowed, got %s", first.text)
+	}
+	for i, t := range tokens {
+		if t.isSymbol(";") {
+			return xerrors.New("only a single statement is allowed")
+		}
+		if t.kind == tokenIdent && forbidden[t.upper()] {
+			return xerrors.Errorf("%s is not allowed", t.upper())
+		}
+		if t.isSymbol("(") && i > 0 {
+			// function name, possibly qualified, quoted names count too
+			for j := i - 1; j >= 0 && (tokens[j].kind == tokenIdent || tokens[j].kind == tokenQuotedIdent); j -= 2 {
+				if d.functions[strings.ToUpper(tokens[j].text)] {
+					return xerrors.Errorf("function %s is not allowed", tokens[j].text)
+				}
+				if j == 0 || !tokens[j-1].isSymbol(".") {
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Writes returns upper-cased keywords of statements that modify data inside a query, such as DELETE in
+// WITH x AS (DELETE ... RETURNING *) SELECT * FROM x. String literals, comments, function calls like replace(...)
+// and row locks (FOR UPDATE) don't count. Quoting follows ANSI rules, ok is false if the query can't be lexed.
+func Writes(query string) (keywords []string, ok bool) {
+	tokens, err := lex(query, dialect{})
+	if err != nil {
+		return nil, false
+	}
+	for i, t := range tokens {
+		if t.kind != tokenIdent || !forbidden[t.upper()] {
+			continue
+		}
+		if i+1 < len(tokens) && tokens[i+1].isSymbol("(") && !t.is("INTO") {
+			// mysql insert(str, pos, len, newstr)
+			continue
+		}
+		if i > 0 && (tokens[i-1].isSymbol(".") || tokens[i-1].is("FOR") || tokens[i-1].is("KEY")) {
+			// qualified names and FOR [NO KEY] UPDATE
+			continue
+		}
+		keywords = append(keywords, t.upper())
+	}
+	return keywords, true
+}
+
+// Tables returns discovered tables raw queries may read, with only the columns they may select,
+// so discovery and samples don't reveal what queries can't
+func (g *Guard) Tables(tables []model.Table) []model.Table {
+	if g == nil {
+		return tables
+	}
+	var res []model.Table
+	for _, table := range tables {
+		parts := splitName(table.Name)
+		if !g.tableAllowed(parts) {
+			continue
+		}
+		var columns []model.ColumnSchema
+		for _, column := range table.Columns {
+			if g.columnAllowed(parts, column.Name) {
+				columns = append(columns, column)
+			}
+		}
+		if len(columns) == 0 && len(table.Columns) > 0 {
+			continue
+		}
+		table.Columns = columns
+		res = append(res, table)
+	}
+	return res
+}
+
+// Sample drops values of columns raw queries may not select from sample rows of table
+func (g *Guard) Sample(table model.Table, rows []map[string]any) []map[string]any {
+	if g == nil {
+		return rows
+	}
+	parts := splitName(table.Name)
+	res := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		copied := make(map[string]any, len(row))
+		for column, v := range row {
+			if g.columnAllowed(parts, column) {
+				copied[column] = v
+			}
+		}
+		res[i] = copied
+	}
+	return res
+}
+
+func (g *Guard) checkTables(a *analysis) error {
+	for _, ref := range a.tables {
+		if g.tableAllowed(ref.parts) {
+			continue
+		}
+		what := "table"
+		if ref.function {
+			what = "table function"
+		}
+		return xerrors.Errorf("%s %s is not allowed", what, strings.Join(ref.parts, "."))
+	}
+	return nil
+}
+
+// tableAllowed checks a table against allowed and denied tables
+func (g *Guard) tableAllowed(table []string) bool {
+	for _, denied := range g.deniedTables {
+		if namesMatch(denied, table) {
+			return false
+		}
+	}
+	if len(g.allowedTables) == 0 {
+		return true
+	}
+	for _, entry := range g.allowedTables {
+		if namesMatch(entry, table) {
+			return true
+		}
+	}
+	return false
+}
+
+// columnAllowed checks a column of a known table against allowed and denied columns
+func (g *Guard) columnAllowed(table []string, column string) bool {
+	candidates := [][]string{table}
+	if g.anyColumnRule(g.deniedColumns, column, candidates) {
+		return false
+	}
+	return len(g.allowedColumns) == 0 || g.anyColumnRule(g.allowedColumns, column, candidates)
+}
+
+func (g *Guard) checkColumns(a *analysis) error {
+	for _, ref := range a.columns {
+		candidates, derived := a.resolve(ref)
+		if derived {
+			// columns of subqueries and CTEs are checked where they are selected
+			continue
+		}
+		star := ref.star
+		if !ref.alias && len(ref.qualifier) == 0 {
+			if table, ok := a.wholeRow(ref.column); ok {
+				star, candidates = true, [][]string{table}
+			}
+		}
+		if star {
+			if g.anyRule(g.deniedColumns, candidates) || len(g.allowedColumns) > 0 {
+				return xerrors.Errorf("%s selects all columns, list allowed columns explicitly", ref)
+			}
+			continue
+		}
+		if g.anyColumnRule(g.deniedColumns, ref.column, candidates) {
+			return xerrors.Errorf("column %s is not allowed", ref)
+		}
+		if len(g.allowedColumns) == 0 || ref.alias || (len(ref.qualifier) == 0 && a.outputAliases[strings.ToUpper(ref.column)]) {
+			continue
+		}
+		if !g.anyColumnRule(g.allowedColumns, ref.column, candidates) {
+			return xerrors.Errorf("column %s is not allowed", ref)
+		}
+	}
+	return nil
+}
+
+// anyRule reports whether some rule may apply to one of candidate tables
+func (g *Guard) anyRule(rules []columnRule, candidates [][]string) bool {
+	for _, rule := range rules {
+		if rule.appliesTo(candidates) {
+			return true
+		}
+	}
+	return false
+}
+
+// anyColumnRule reports whether a rule for column applies to one of candidate tables
+func (g *Guard) anyColumnRule(rules []columnRule, column string, candidates [][]string) bool {
+	for _, rule := range rules {
+		if strings.EqualFold(rule.column, column) && rule.appliesTo(candidates) {
+			return true
+		}
+	}
+	return false
+}
+
+// limit adds the dialect specific row limit when the query has none
+func (g *Guard) limit(query string, tokens []token) string {
+	if g.config.DefaultLimit <= 0 {
+		return query
+	}
+	depth := 0
+	topSelect := -1
+	setOperation := false
+	for i, t := range tokens {
+		switch {
+		case t.isSymbol("("):
+			depth++
+		case t.isSymbol(")"):
+			depth--
+		case depth != 0:
+		case t.is("LIMIT"), t.is("FETCH"), t.is("TOP"):
+			return query
+		case t.is("SELECT") && topSelect < 0:
+			topSelect = i
+		case t.kind == tokenIdent && setOperations[t.upper()]:
+			setOperation = true
+		}
+	}
+	switch g.dialect.limit {
+	case limitFetch:
+		return fmt.Sprintf("%s FETCH FIRST %d ROWS ONLY", query, g.config.DefaultLimit)
+	case limitTop:
+		if setOperation && !tokens[0].is("WITH") {
+			return fmt.Sprintf("SELECT TOP %d * FROM (%s) AS limited", g.config.DefaultLimit, query)
+		}
+		if topSelect < 0 || setOperation {
+			return query
+		}
+		at := tokens[topSelect].end
+		if next := topSelect + 1; next < len(tokens) && (tokens[next].is("DISTINCT") || tokens[next].is("ALL")) {
+			at = tokens[next].end
+		}
+		return fmt.Sprintf("%s TOP %d%s", query[:at], g.config.DefaultLimit, query[at:])
+	default:
+		return fmt.Sprintf("%s LIMIT %d", query, g.config.DefaultLimit)
+	}
+}
+
+func (r columnRule) appliesTo(candidates [][]string) bool {
+	if len(r.table) == 0 {
+		return true
+	}
+	for _, candidate := range candidates {
+		if namesMatch(r.table, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseColumnRule(column string) columnRule {
+	parts := splitName(column)
+	return columnRule{table: parts[:len(parts)-1], column: parts[len(parts)-1]}
+}
+
+func splitName(name string) []string {
+	return strings.Split(strings.TrimSpace(name), ".")
+}
+
+// namesMatch compares possibly qualified names on their common suffix, so "users" matches "public.users"
+func namesMatch(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return false
+	}
+	for i, j := len(a)-1, len(b)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if !strings.EqualFold(a[i], b[j]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,331 @@
+package sqlguard
+
+import (
+	"context"
+	"testing"
+
+	"github.com/centralmind/gateway/connectors"
+	"github.com/centralmind/gateway/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct{ typ string }
+
+func (c testConfig) Type() string          { return c.typ }
+func (c testConfig) Doc() string           { return "" }
+func (c testConfig) ExtraPrompt() []string { return nil }
+func (c testConfig) Readonly() bool        { return false }
+
+type testConnector struct {
+	connectors.Connector
+	typ  string
+	cost float64
+}
+
+func (c testConnector) Config() connectors.Config { return testConfig{typ: c.typ} }
+
+func (c testConnector) Explain(ctx context.Context, query string) (float64, error) {
+	return c.cost, nil
+}
+
+func newGuard(t *testing.T, typ string, cfg model.RawParams) *Guard {
+	g, err := New(cfg, testConnector{typ: typ})
+	require.NoError(t, err)
+	return g
+}
+
+func TestStatements(t *testing.T) {
+	g := newGuard(t, "postgres", model.RawParams{})
+	for _, query := range []string{
+		"SELECT 1",
+		"select * from users where id = 1;",
+		"WITH x AS (SELECT id FROM users) SELECT * FROM x",
+		"(SELECT id FROM a) UNION (SELECT id FROM b)",
+		"SELECT 'DROP TABLE users; --' AS text",
+		"SELECT $tag$ ; DELETE FROM users $tag$",
+		"SELECT id -- ; DELETE FROM users\nFROM users",
+		"SELECT E'\\' ; DELETE FROM users; --'",
+	} {
+		_, err := g.Check(context.Background(), query)
+		assert.NoError(t, err, query)
+	}
+	for _, query := range []string{
+		"",
+		"DELETE FROM users",
+		"SELECT 1; DROP TABLE users",
+		"WITH x AS (DELETE FROM users RETURNING *) SELECT * FROM x",
+		"SELECT * INTO copy FROM users",
+		"SELECT * FROM users FOR UPDATE",
+		"SELECT '\\' ; DELETE FROM users; --'",
+		"EXPLAIN ANALYZE SELECT 1",
+		"SELECT 'unterminated",
+	} {
+		_, err := g.Check(context.Background(), query)
+		assert.ErrorIs(t, err, ErrRejected, query)
+	}
+	_, err := g.Check(context.Background(), "DELETE FROM users")
+	assert.EqualError(t, err, "query rejected: only SELECT statements are allowed, got DELETE")
+}
+
+func TestFunctions(t *testing.T) {
+	for typ, queries := range map[string][]string{
+		"postgres": {
+			"SELECT pg_read_file('/etc/passwd')",
+			"SELECT pg_catalog.pg_read_file ('/etc/passwd')",
+			`SELECT "pg_read_file"('/etc/passwd')`,
+			"SELECT * FROM dblink('host=evil', 'SELECT 1') AS t(a int)",
+			"SELECT set_config('statement_timeout', '0', false)",
+			"SELECT query_to_xml('SELECT * FROM secrets', true, false, '')",
+		},
+		"mysql":      {"SELECT LOAD_FILE('/etc/passwd')", "SELECT SLEEP(10)"},
+		"clickhouse": {"SELECT * FROM url('http://evil/', CSV, 'a String')"},
+		"duckdb":     {"SELECT getenv('AWS_SECRET_ACCESS_KEY')", "SELECT read_text('/etc/passwd')"},
+		"oracle":     {"SELECT utl_http.request('http://evil/') FROM dual"},
+		"mssql":      {"SELECT * FROM OPENROWSET('SQLNCLI', 'Server=evil;', 'SELECT 1')"},
+	} {
+		g := newGuard(t, typ, model.RawParams{})
+		for _, query := range queries {
+			_, err := g.Check(context.Background(), query)
+			assert.ErrorIs(t, err, ErrRejected, query)
+		}
+	}
+	// names of forbidden functions are fine as columns and in literals
+	for _, query := range []string{
+		"SELECT sleep, 'pg_read_file(x)' FROM users",
+		"SELECT lower(name) FROM users",
+	} {
+		_, err := newGuard(t, "mysql", model.RawParams{}).Check(context.Background(), query)
+		assert.NoError(t, err, query)
+	}
+}
+
+func TestWrites(t *testing.T) {
+	for query, expected := range map[string][]string{
+		"WITH x AS (DELETE FROM users RETURNING *) SELECT * FROM x":          {"DELETE"},
+		"WITH t AS (SELECT 1 AS id) INSERT INTO users (id) SELECT id FROM t": {"INSERT", "INTO"},
+		"SELECT * INTO archive FROM users":                                   {"INTO"},
+		"SELECT replace(name, 'a', 'b'), insert(name, 1, 2, 'x') FROM users": nil,
+		"SELECT * FROM notes WHERE note LIKE '%update%' /* delete */":        nil,
+		"SELECT id, lock FROM jobs FOR NO KEY UPDATE":                        nil,
+	} {
+		writes, ok := Writes(query)
+		assert.True(t, ok, query)
+		assert.Equal(t, expected, writes, query)
+	}
+	_, ok := Writes("SELECT 'unterminated")
+	assert.False(t, ok)
+}
+
+func TestBackslashEscapes(t *testing.T) {
+	query := `SELECT 'it\'s' ; DELETE FROM users -- '`
+	_, err := newGuard(t, "mysql", model.RawParams{}).Check(context.Background(), query)
+	assert.ErrorIs(t, err, ErrRejected, "mysql sees the second statement")
+
+	query = `SELECT 'a\' ; DELETE FROM users -- '`
+	_, err = newGuard(t, "postgres", model.RawParams{}).Check(context.Background(), query)
+	assert.ErrorIs(t, err, ErrRejected, "postgres has no backslash escapes in standard strings")
+}
+
+func TestComments(t *testing.T) {
+	cfg := model.RawParams{AllowedTables: []string{"orders"}, DeniedTables: []string{"secret"}}
+	mysql := newGuard(t, "mysql", cfg)
+	_, err := mysql.Check(context.Background(), "SELECT id /* plain, secret */ FROM orders")
+	assert.NoError(t, err)
+	for _, query := range []string{
+		"SELECT id FROM orders /*!, secret */ WHERE 1=1",
+		"SELECT id FROM orders /*!50000 , secret */",
+		"SELECT id FROM orders /*M!, secret */",
+		"SELECT /*+ MAX_EXECUTION_TIME(1) */ id FROM orders",
+	} {
+		_, err := mysql.Check(context.Background(), query)
+		assert.ErrorIs(t, err, ErrRejected, query)
+	}
+
+	query := "SELECT id /* /* */ ' */ , (SELECT pw FROM secret) -- ' FROM orders"
+	for _, typ := range []string{"postgres", "duckdb", "files"} {
+		_, err := newGuard(t, typ, cfg).Check(context.Background(), query)
+		assert.ErrorIs(t, err, ErrRejected, typ)
+		_, err = newGuard(t, typ, cfg).Check(context.Background(), "SELECT id /* outer /* inner */ still comment */ FROM orders")
+		assert.NoError(t, err, typ)
+	}
+	_, err = newGuard(t, "postgres", cfg).Check(context.Background(), "SELECT id /* /* */ FROM orders")
+	assert.ErrorIs(t, err, ErrRejected, "unterminated nested comment")
+}
+
+func TestTables(t *testing.T) {
+	g := newGuard(t, "postgres", model.RawParams{
+		AllowedTables: []string{"public.orders", "customers"},
+		DeniedTables:  []string{"customers_secret"},
+	})
+	for _, query := range []string{
+		"SELECT * FROM orders",
+		"SELECT * FROM public.orders o JOIN customers c ON c.id = o.customer_id",
+		"SELECT * FROM orders, customers",
+		"WITH recent AS (SELECT * FROM orders) SELECT * FROM recent r",
+		"SELECT * FROM (SELECT * FROM orders) sub",
+		"SELECT EXTRACT(YEAR FROM created_at) FROM orders",
+		"SELECT a IS DISTINCT FROM b FROM orders",
+	} {
+		_, err := g.Check(context.Background(), query)
+		assert.NoError(t, err, query)
+	}
+	for _, query := range []string{
+		"SELECT * FROM users",
+		"SELECT * FROM private.orders",
+		"SELECT * FROM orders, users",
+		"SELECT * FROM orders JOIN (SELECT * FROM users) u ON true",
+		"SELECT * FROM orders WHERE id IN (SELECT id FROM users)",
+		"SELECT * FROM (orders JOIN users ON true)",
+		"SELECT * FROM read_csv('/etc/passwd')",
+		"SELECT * FROM '/etc/passwd'",
+		"SELECT * FROM customers_secret",
+		"WITH customers_secret AS (SELECT * FROM customers_secret) SELECT * FROM customers_secret",
+		"WITH users AS (SELECT * FROM users) SELECT * FROM users",
+		"WITH a AS (SELECT 1), users AS (SELECT * FROM users) SELECT * FROM users",
+		"SELECT * FROM (WITH users AS (SELECT 1) SELECT * FROM users) x, users",
+		"SELECT * FROM orders UNION ALL TABLE users",
+		"WITH x AS (TABLE customers_secret) SELECT * FROM x",
+	} {
+		_, err := g.Check(context.Background(), query)
+		assert.ErrorIs(t, err, ErrRejected, query)
+	}
+}
+
+func TestColumns(t *testing.T) {
+	g := newGuard(t, "postgres", model.RawParams{
+		DeniedColumns: []string{"users.ssn", "accounts.password"},
+	})
+	for _, query := range []string{
+		"SELECT id, name FROM users",
+		"SELECT ssn FROM orders",
+		"SELECT count(*) FROM users",
+		"SELECT * FROM orders",
+		"SELECT o.* FROM orders o JOIN users u ON u.id = o.user_id",
+	} {
+		_, err := g.Check(context.Background(), query)
+		assert.NoError(t, err, query)
+	}
+	for _, query := range []string{
+		"SELECT ssn FROM users",
+		"SELECT u.ssn FROM users u",
+		"SELECT \"SSN\" FROM users",
+		"SELECT id FROM users WHERE ssn LIKE '1%'",
+		"SELECT * FROM users",
+		"SELECT u.* FROM users u",
+		"SELECT row_to_json(u) FROM users u",
+		"SELECT password FROM accounts",
+		"SELECT * FROM accounts",
+		"SELECT x FROM (SELECT ssn AS x FROM users) s",
+		"WITH users AS (SELECT users.ssn FROM users) SELECT * FROM users",
+		"SELECT id FROM orders UNION ALL TABLE users",
+	} {
+		_, err := g.Check(context.Background(), query)
+		assert.ErrorIs(t, err, ErrRejected, query)
+	}
+
+	// a column denied in every table may hide behind any star
+	_, err := newGuard(t, "postgres", model.RawParams{DeniedColumns: []string{"password"}}).
+		Check(context.Background(), "SELECT * FROM orders")
+	assert.ErrorIs(t, err, ErrRejected)
+
+	g = newGuard(t, "postgres", model.RawParams{
+		AllowedColumns: []string{"orders.id", "orders.total", "created_at"},
+	})
+	for _, query := range []string{
+		"SELECT id, total FROM orders",
+		"SELECT o.id, sum(o.total) AS amount FROM orders o GROUP BY o.id ORDER BY amount DESC",
+		"SELECT date_trunc('day', created_at) day, count(*) FROM orders GROUP BY 1",
+		"SELECT id FROM orders WHERE created_at > DATE '2024-01-01' AND total::numeric > 10",
+	} {
+		_, err := g.Check(context.Background(), query)
+		assert.NoError(t, err, query)
+	}
+	for _, query := range []string{
+		"SELECT * FROM orders",
+		"SELECT id, customer_id FROM orders",
+		"SELECT id FROM users",
+	} {
+		_, err := g.Check(context.Background(), query)
+		assert.ErrorIs(t, err, ErrRejected, query)
+	}
+}
+
+func TestDiscovery(t *testing.T) {
+	columns := func(names ...string) []model.ColumnSchema {
+		var res []model.ColumnSchema
+		for _, name := range names {
+			res = append(res, model.ColumnSchema{Name: name})
+		}
+		return res
+	}
+	tables := []model.Table{
+		{Name: "users", Columns: columns("id", "name", "ssn")},
+		{Name: "orders", Columns: columns("id", "total")},
+		{Name: "secret", Columns: columns("id")},
+	}
+	g := newGuard(t, "postgres", model.RawParams{
+		DeniedTables:  []string{"secret"},
+		DeniedColumns: []string{"users.ssn"},
+	})
+	assert.Equal(t, []model.Table{
+		{Name: "users", Columns: columns("id", "name")},
+		{Name: "orders", Columns: columns("id", "total")},
+	}, g.Tables(tables))
+	assert.Equal(t,
+		[]map[string]any{{"id": 1, "name": "alice"}},
+		g.Sample(tables[0], []map[string]any{{"id": 1, "name": "alice", "ssn": "536-22-8726"}}),
+	)
+
+	g = newGuard(t, "postgres", model.RawParams{AllowedColumns: []string{"orders.total", "name"}})
+	assert.Equal(t, []model.Table{
+		{Name: "users", Columns: columns("name")},
+		{Name: "orders", Columns: columns("total")},
+	}, g.Tables(tables))
+
+	var none *Guard
+	assert.Equal(t, tables, none.Tables(tables))
+}
+
+func TestLimit(t *testing.T) {
+	cases := []struct {
+		typ      string
+		query    string
+		expected string
+	}{
+		{"postgres", "SELECT * FROM t;", "SELECT * FROM t LIMIT 100"},
+		{"postgres", "SELECT * FROM t -- comment", "SELECT * FROM t LIMIT 100"},
+		{"postgres", "SELECT * FROM t LIMIT 5", "SELECT * FROM t LIMIT 5"},
+		{"postgres", "SELECT * FROM (SELECT * FROM t LIMIT 5) s", "SELECT * FROM (SELECT * FROM t LIMIT 5) s LIMIT 100"},
+		{"oracle", "SELECT * FROM t", "SELECT * FROM t FETCH FIRST 100 ROWS ONLY"},
+		{"mssql", "SELECT DISTINCT a FROM t", "SELECT DISTINCT TOP 100 a FROM t"},
+		{"mssql", "WITH x AS (SELECT a FROM t) SELECT a FROM x", "WITH x AS (SELECT a FROM t) SELECT TOP 100 a FROM x"},
+		{"mssql", "SELECT a FROM t UNION SELECT a FROM s", "SELECT TOP 100 * FROM (SELECT a FROM t UNION SELECT a FROM s) AS limited"},
+		{"mssql", "SELECT TOP 5 a FROM t", "SELECT TOP 5 a FROM t"},
+	}
+	for _, c := range cases {
+		res, err := newGuard(t, c.typ, model.RawParams{DefaultLimit: 100}).Check(context.Background(), c.query)
+		require.NoError(t, err, c.query)
+		assert.Equal(t, c.expected, res, c.query)
+	}
+}
+
+func TestCost(t *testing.T) {
+	g, err := New(model.RawParams{MaxCost: 1000}, testConnector{typ: "postgres", cost: 5000})
+	require.NoError(t, err)
+	_, err = g.Check(context.Background(), "SELECT * FROM t")
+	assert.ErrorIs(t, err, ErrRejected)
+
+	g, err = New(model.RawParams{MaxCost: 1000}, testConnector{typ: "postgres", cost: 10})
+	require.NoError(t, err)
+	_, err = g.Check(context.Background(), "SELECT * FROM t")
+	assert.NoError(t, err)
+}
+
+func TestNonSQL(t *testing.T) {
+	g := newGuard(t, "mongodb", model.RawParams{DefaultLimit: 10})
+	assert.Nil(t, g)
+	res, err := g.Check(context.Background(), `{"collection": "users"}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"collection": "users"}`, res)
+}
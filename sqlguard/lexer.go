@@ -0,0 +1,244 @@
+package sqlguard
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/xerrors"
+)
+
+type tokenKind int
+
+const (
+	tokenIdent       tokenKind = iota // bare identifier or keyword
+	tokenQuotedIdent                  // "ident", `ident` or [ident]
+	tokenString                       // string literal
+	tokenNumber                       // numeric literal
+	tokenParam                        // $1, ?, :name
+	tokenSymbol                       // operators and punctuation
+)
+
+type token struct {
+	kind tokenKind
+	text string // original text, for identifiers without quotes
+	pos  int    // byte offset of the token start
+	end  int    // byte offset right after the token
+}
+
+// upper returns normalized text used for keyword and name comparison
+func (t token) upper() string {
+	return strings.ToUpper(t.text)
+}
+
+func (t token) is(keyword string) bool {
+	return t.kind == tokenIdent && strings.EqualFold(t.text, keyword)
+}
+
+func (t token) isSymbol(symbol string) bool {
+	return t.kind == tokenSymbol && t.text == symbol
+}
+
+// name reports whether the token can name a table, column or alias
+func (t token) name() bool {
+	return t.kind == tokenQuotedIdent || (t.kind == tokenIdent && !keywords[t.upper()])
+}
+
+// lex splits query into tokens, comments are dropped.
+// Quoting rules must match the database exactly, otherwise code could hide inside what looks like a string.
+func lex(query string, d dialect) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(query) {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return tokens, nil
+			}
+			i += end + 1
+		case strings.HasPrefix(query[i:], "/*"):
+			if d.executableComments && (strings.HasPrefix(query[i+2:], "!") || strings.HasPrefix(query[i+2:], "+") ||
+				strings.HasPrefix(query[i+2:], "M!")) {
+				// mysql runs the body of /*! ... */ as SQL, hints change how a query runs
+				return nil, xerrors.New("executable comments and optimizer hints are not allowed")
+			}
+			end, err := scanComment(query, i, d.nestedComments)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		case c == '\'':
+			end, err := scanQuoted(query, i, '\'', d.backslashEscapes)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, text: query[i:end], pos: i, end: end})
+			i = end
+		case d.hashComments && c == '#':
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return tokens, nil
+			}
+			i += end + 1
+		case d.escapeStrings && (c == 'E' || c == 'e') && i+1 < len(query) && query[i+1] == '\'':
+			// postgres escape string E'...'
+			end, err := scanQuoted(query, i+1, '\'', true)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, text: query[i:end], pos: i, end: end})
+			i = end
+		case c == '"':
+			end, err := scanQuoted(query, i, '"', d.backslashEscapes)
+			if err != nil {
+				return nil, err
+			}
+			kind := tokenQuotedIdent
+			if d.doubleQuotedStrings {
+				kind = tokenString
+			}
+			tokens = append(tokens, token{kind: kind, text: unquote(query[i:end]), pos: i, end: end})
+			i = end
+		case c == '`':
+			end, err := scanQuoted(query, i, '`', false)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenQuotedIdent, text: unquote(query[i:end]), pos: i, end: end})
+			i = end
+		case c == '[' && d.bracketIdents:
+			end := strings.IndexByte(query[i:], ']')
+			if end < 0 {
+				return nil, xerrors.New("unterminated identifier")
+			}
+			tokens = append(tokens, token{kind: tokenQuotedIdent, text: query[i+1 : i+end], pos: i, end: i + end + 1})
+			i += end + 1
+		case c == '$':
+			end, kind, err := scanDollar(query, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: kind, text: query[i:end], pos: i, end: end})
+			i = end
+		case c >= '0' && c <= '9' || (c == '.' && i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9'):
+			end := i + 1
+			for end < len(query) && (isIdentChar(rune(query[end])) || query[end] == '.' ||
+				((query[end] == '+' || query[end] == '-') && (query[end-1] == 'e' || query[end-1] == 'E'))) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: query[i:end], pos: i, end: end})
+			i = end
+		case c == ':' && i+1 < len(query) && query[i+1] == ':':
+			tokens = append(tokens, token{kind: tokenSymbol, text: "::", pos: i, end: i + 2})
+			i += 2
+		case (c == ':' || c == '@') && i+1 < len(query) && isIdentStart(rune(query[i+1])):
+			end := i + 1
+			for end < len(query) && isIdentChar(rune(query[end])) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenParam, text: query[i:end], pos: i, end: end})
+			i = end
+		case c == '?':
+			tokens = append(tokens, token{kind: tokenParam, text: "?", pos: i, end: i + 1})
+			i++
+		case isIdentStart(rune(c)) || c >= 0x80:
+			end := i
+			for end < len(query) {
+				r := rune(query[end])
+				if r >= 0x80 {
+					// multi-byte letters are part of identifiers
+					_, size := utf8.DecodeRuneInString(query[end:])
+					end += size
+					continue
+				}
+				if !isIdentChar(r) {
+					break
+				}
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: query[i:end], pos: i, end: end})
+			i = end
+		default:
+			tokens = append(tokens, token{kind: tokenSymbol, text: query[i : i+1], pos: i, end: i + 1})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// scanQuoted returns the offset right after the closing quote, doubled quotes are escapes
+func scanQuoted(query string, start int, quote byte, backslash bool) (int, error) {
+	for i := start + 1; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			if backslash {
+				i++
+			}
+		case quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1, nil
+		}
+	}
+	return 0, xerrors.New("unterminated quoted literal")
+}
+
+// scanComment returns the offset right after the block comment starting at start
+func scanComment(query string, start int, nested bool) (int, error) {
+	depth := 0
+	for i := start; i+1 < len(query); i++ {
+		switch {
+		case query[i] == '/' && query[i+1] == '*' && (depth == 0 || nested):
+			depth++
+			i++
+		case query[i] == '*' && query[i+1] == '/':
+			if depth--; depth == 0 {
+				return i + 2, nil
+			}
+			i++
+		}
+	}
+	return 0, xerrors.New("unterminated comment")
+}
+
+// scanDollar handles postgres dollar-quoted strings ($tag$...$tag$) and positional parameters ($1)
+func scanDollar(query string, start int) (int, tokenKind, error) {
+	end := start + 1
+	for end < len(query) && query[end] >= '0' && query[end] <= '9' {
+		end++
+	}
+	if end > start+1 {
+		return end, tokenParam, nil
+	}
+	for end < len(query) && query[end] != '$' && isIdentChar(rune(query[end])) {
+		end++
+	}
+	if end >= len(query) || query[end] != '$' {
+		return start + 1, tokenSymbol, nil
+	}
+	tag := query[start : end+1]
+	closing := strings.Index(query[end+1:], tag)
+	if closing < 0 {
+		return 0, 0, xerrors.New("unterminated dollar-quoted string")
+	}
+	return end + 1 + closing + len(tag), tokenString, nil
+}
+
+func unquote(quoted string) string {
+	q := quoted[:1]
+	return strings.ReplaceAll(quoted[1:len(quoted)-1], q+q, q)
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isIdentChar(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
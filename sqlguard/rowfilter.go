@@ -0,0 +1,432 @@
+package sqlguard
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	gw_errors "github.com/centralmind/gateway/errors"
+	"github.com/centralmind/gateway/model"
+	"golang.org/x/xerrors"
+)
+
+// claimsParam prefixes claim references in policy predicates, e.g. tenant_id = :claims.org_id
+const claimsParam = ":claims"
+
+// RowFilter rewrites queries so that rows of protected tables are filtered by the database:
+// every reference of a protected table is replaced by a subquery selecting only rows matching the policy.
+type RowFilter struct {
+	dialect  dialect
+	policies []policy
+}
+
+// policy is a predicate split into SQL text and claim references
+type policy struct {
+	table    []string
+	segments []segment
+}
+
+// segment is either SQL text or a claim reference
+type segment struct {
+	text  string
+	claim string
+}
+
+// NewRowFilter builds a row filter for queries of the connector type, policies map
+// a possibly qualified table name to a predicate over its columns.
+// It returns nil for connectors that don't take SQL.
+func NewRowFilter(connectorType string, policies map[string]string) (*RowFilter, error) {
+	if nonSQL[connectorType] {
+		return nil, nil
+	}
+	f := &RowFilter{dialect: dialects[connectorType]}
+	tables := make([]string, 0, len(policies))
+	for table := range policies {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		segments, err := parsePredicate(policies[table], f.dialect)
+		if err != nil {
+			return nil, xerrors.Errorf("policy of %s: %w", table, err)
+		}
+		f.policies = append(f.policies, policy{table: splitName(table), segments: segments})
+	}
+	return f, nil
+}
+
+// parsePredicate checks that predicate is a single read-only expression and finds claim references in it
+func parsePredicate(predicate string, d dialect) ([]segment, error) {
+	tokens, err := lex(predicate, d)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, xerrors.New("empty predicate")
+	}
+	depth := 0
+	var segments []segment
+	last := 0
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.isSymbol("("):
+			depth++
+		case t.isSymbol(")"):
+			if depth--; depth < 0 {
+				return nil, xerrors.New("unbalanced parentheses")
+			}
+		case t.isSymbol(";"):
+			return nil, xerrors.New("only a single expression is allowed")
+		case t.kind == tokenIdent && (forbidden[t.upper()] || t.is("SELECT")):
+			return nil, xerrors.Errorf("%s is not allowed", t.upper())
+		case t.kind == tokenParam:
+			if !strings.EqualFold(t.text, claimsParam) || i+2 >= len(tokens) || !tokens[i+1].isSymbol(".") || tokens[i+2].kind != tokenIdent {
+				return nil, xerrors.Errorf("parameter %s is not supported, reference claims as %s.<name>", t.text, claimsParam)
+			}
+			segments = append(segments, segment{text: predicate[last:t.pos]}, segment{claim: tokens[i+2].text})
+			last = tokens[i+2].end
+			i += 2
+		}
+	}
+	if depth != 0 {
+		return nil, xerrors.New("unbalanced parentheses")
+	}
+	return append(segments, segment{text: predicate[last:]}), nil
+}
+
+// Apply rewrites query for a caller with claims and returns params binding the referenced claims,
+// they must be added to params of the endpoint the query runs with.
+// Policies of UPDATE and DELETE targets are added to their WHERE clause, statements changing
+// several tables at once are rejected.
+// A missing claim is reported as errors.ErrNotAuthorized. When strict is set, a query reading
+// or changing a table without a policy is rejected.
+func (f *RowFilter) Apply(query string, claims map[string]any, strict bool) (string, []model.EndpointParams, map[string]any, error) {
+	tokens, err := lex(query, f.dialect)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	a, err := analyze(tokens)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	var (
+		params []model.EndpointParams
+		values = map[string]any{}
+		edits  []edit
+	)
+	render := func(p policy) (string, error) {
+		return f.render(p, claims, values, &params)
+	}
+	for _, ref := range a.tables {
+		policy, ok := f.policy(ref)
+		if !ok {
+			if strict {
+				return "", nil, nil, fmt.Errorf("%w: table %s has no row policy", ErrRejected, strings.Join(ref.parts, "."))
+			}
+			continue
+		}
+		predicate, err := render(policy)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		text := fmt.Sprintf("(SELECT * FROM %s WHERE (%s))", query[ref.pos:ref.end], predicate)
+		if !ref.aliased {
+			// keeps column references qualified with the table name working
+			text += " " + query[ref.namePos:ref.end]
+		}
+		pos := ref.pos
+		if ref.statement {
+			// TABLE name becomes SELECT * FROM (...) name
+			pos, text = ref.statementPos, "SELECT * FROM "+text
+		}
+		edits = append(edits, edit{pos: pos, end: ref.end, text: text})
+	}
+	writes, err := f.writes(tokens, strict, render)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	edits = append(edits, writes...)
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].pos < edits[j].pos
+	})
+	var res strings.Builder
+	last := 0
+	for _, e := range edits {
+		res.WriteString(query[last:e.pos])
+		res.WriteString(e.text)
+		last = e.end
+	}
+	res.WriteString(query[last:])
+	return res.String(), params, values, nil
+}
+
+// Check rejects query reading or changing a table without a policy, it is meant for queries that return no rows
+func (f *RowFilter) Check(query string) error {
+	tokens, err := lex(query, f.dialect)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	a, err := analyze(tokens)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	for _, ref := range a.tables {
+		if _, ok := f.policy(ref); !ok {
+			return fmt.Errorf("%w: table %s has no row policy", ErrRejected, strings.Join(ref.parts, "."))
+		}
+	}
+	_, err = f.writes(tokens, true, func(policy) (string, error) { return "", nil })
+	return err
+}
+
+// edit replaces query[pos:end] with text, pos equals end for insertions
+type edit struct {
+	pos, end int
+	text     string
+}
+
+// writes returns edits adding policies to the WHERE clause of UPDATE and DELETE statements on protected tables
+func (f *RowFilter) writes(tokens []token, strict bool, render func(policy) (string, error)) ([]edit, error) {
+	var edits []edit
+	for i, t := range tokens {
+		// UPDATE also appears in FOR UPDATE and ON DUPLICATE KEY UPDATE, statements start a query or follow parentheses
+		if !t.is("UPDATE") && !t.is("DELETE") || i > 0 && !tokens[i-1].isSymbol("(") && !tokens[i-1].isSymbol(")") {
+			continue
+		}
+		target, where, end, err := writeTarget(tokens, i)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrRejected, err)
+		}
+		policy, ok := f.policy(tableRef{parts: target})
+		if !ok {
+			if strict {
+				return nil, fmt.Errorf("%w: table %s has no row policy", ErrRejected, strings.Join(target, "."))
+			}
+			continue
+		}
+		predicate, err := render(policy)
+		if err != nil {
+			return nil, err
+		}
+		if where < 0 {
+			edits = append(edits, edit{pos: tokens[end-1].end, end: tokens[end-1].end, text: fmt.Sprintf(" WHERE (%s)", predicate)})
+			continue
+		}
+		edits = append(edits,
+			edit{pos: tokens[where+1].pos, end: tokens[where+1].pos, text: "("},
+			edit{pos: tokens[end-1].end, end: tokens[end-1].end, text: fmt.Sprintf(") AND (%s)", predicate)},
+		)
+	}
+	return edits, nil
+}
+
+// writeTarget parses UPDATE [ONLY] table [[AS] alias] SET ... and DELETE FROM [ONLY] table [[AS] alias] ...
+// starting at start. It returns the target table, the index of its WHERE keyword, -1 without one,
+// and the index of the token ending the filtered part of the statement.
+func writeTarget(tokens []token, start int) (target []string, where, end int, err error) {
+	update := tokens[start].is("UPDATE")
+	i := start + 1
+	for i < len(tokens) && (tokens[i].is("LOW_PRIORITY") || tokens[i].is("QUICK") || tokens[i].is("IGNORE")) {
+		i++
+	}
+	multiTable := xerrors.Errorf("%s of several tables is not supported with row policies", tokens[start].upper())
+	if !update {
+		if i >= len(tokens) || !tokens[i].is("FROM") {
+			return nil, 0, 0, multiTable
+		}
+		i++
+	}
+	if i < len(tokens) && tokens[i].is("ONLY") {
+		i++
+	}
+	if i >= len(tokens) || !tokens[i].name() {
+		return nil, 0, 0, xerrors.Errorf("%s has no target table", tokens[start].upper())
+	}
+	target, i = chain(tokens, i)
+	i++
+	if i < len(tokens) && tokens[i].isSymbol("*") {
+		i++
+	}
+	if i < len(tokens) && tokens[i].is("AS") {
+		i++
+	}
+	if i < len(tokens) && tokens[i].name() && !tokens[i].is("SET") && !tokens[i].is("RETURNING") {
+		i++
+	}
+	switch {
+	case update && (i >= len(tokens) || !tokens[i].is("SET")):
+		return nil, 0, 0, multiTable
+	case !update && i < len(tokens) && !tokens[i].is("WHERE") && !tokens[i].is("RETURNING") &&
+		!tokens[i].is("ORDER") && !tokens[i].is("LIMIT") && !tokens[i].isSymbol(")") && !tokens[i].isSymbol(";"):
+		return nil, 0, 0, multiTable
+	}
+	where = -1
+	depth := 0
+	for ; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.isSymbol("("):
+			depth++
+		case t.isSymbol(")"):
+			if depth == 0 {
+				return target, where, i, nil
+			}
+			depth--
+		case depth > 0:
+		case t.is("WHERE"):
+			if i+1 >= len(tokens) || tokens[i+1].is("CURRENT") {
+				return nil, 0, 0, xerrors.New("WHERE CURRENT OF is not supported with row policies")
+			}
+			where = i
+		case t.is("FROM") && update && where < 0:
+			return nil, 0, 0, multiTable
+		case t.is("RETURNING"), t.is("ORDER"), t.is("LIMIT"), t.isSymbol(";"):
+			return target, where, i, nil
+		}
+	}
+	return target, where, len(tokens), nil
+}
+
+// Tables returns tables read by a query of the connector type, ok is false when they are unknown
+// since the connector does not take SQL or the query can't be analyzed
+func Tables(connectorType, query string) (tables []string, ok bool) {
+	if nonSQL[connectorType] {
+		return nil, false
+	}
+	tokens, err := lex(query, dialects[connectorType])
+	if err != nil {
+		return nil, false
+	}
+	a, err := analyze(tokens)
+	if err != nil {
+		return nil, false
+	}
+	for _, ref := range a.tables {
+		tables = append(tables, strings.Join(ref.parts, "."))
+	}
+	return tables, true
+}
+
+// Columns returns names of columns a query of the connector type refers to anywhere, aliases included.
+// renamed is set when the query may return values of columns it doesn't name under other names:
+// whole rows such as row_to_json(u), stars inside function calls, column alias lists or stars combined
+// by set operations. ok is false when the connector does not take SQL or the query can't be analyzed.
+func Columns(connectorType, query string) (columns []string, renamed bool, ok bool) {
+	if nonSQL[connectorType] {
+		return nil, false, false
+	}
+	tokens, err := lex(query, dialects[connectorType])
+	if err != nil {
+		return nil, false, false
+	}
+	a, err := analyze(tokens)
+	if err != nil {
+		return nil, false, false
+	}
+	stars, setOperation := false, false
+	for i, t := range tokens {
+		switch {
+		case t.kind == tokenIdent && setOperations[t.upper()]:
+			setOperation = true
+		case t.isSymbol("*") && i > 1 && tokens[i-1].isSymbol("(") && !tokens[i-2].is("COUNT"):
+			// to_json(*), tuple(*), COLUMNS(*)
+			renamed = true
+		}
+	}
+	for _, ref := range a.columns {
+		if ref.star {
+			stars = true
+			if len(ref.qualifier) > 0 && ref.index > 0 && !selectItem(tokens[ref.index-1]) {
+				// to_json(u.*)
+				renamed = true
+			}
+			continue
+		}
+		if !ref.alias && len(ref.qualifier) == 0 {
+			if _, ok := a.wholeRow(ref.column); ok {
+				renamed = true
+			}
+		}
+		columns = append(columns, ref.column)
+	}
+	return columns, renamed || a.columnLists || (stars && setOperation), true
+}
+
+// selectItem reports whether a token may precede an item of a select list
+func selectItem(prev token) bool {
+	return prev.is("SELECT") || prev.is("DISTINCT") || prev.is("ALL") || prev.isSymbol(",")
+}
+
+// MatchTable compares possibly qualified table names on their common suffix, so "users" matches "public.users"
+func MatchTable(a, b string) bool {
+	return namesMatch(splitName(a), splitName(b))
+}
+
+// Protected reports whether table has a policy
+func (f *RowFilter) Protected(table string) bool {
+	_, ok := f.policy(tableRef{parts: splitName(table)})
+	return ok
+}
+
+func (f *RowFilter) policy(ref tableRef) (policy, bool) {
+	if ref.function {
+		return policy{}, false
+	}
+	for _, p := range f.policies {
+		if namesMatch(p.table, ref.parts) {
+			return p, true
+		}
+	}
+	return policy{}, false
+}
+
+// render substitutes claim references of policy with bound parameters
+func (f *RowFilter) render(p policy, claims map[string]any, values map[string]any, params *[]model.EndpointParams) (string, error) {
+	var res strings.Builder
+	for _, s := range p.segments {
+		if s.claim == "" {
+			res.WriteString(s.text)
+			continue
+		}
+		name := "rls_claims_" + s.claim
+		if _, ok := values[name]; !ok {
+			value, typ, err := claimValue(claims, s.claim)
+			if err != nil {
+				return "", err
+			}
+			values[name] = value
+			*params = append(*params, model.EndpointParams{Name: name, Type: typ, Required: true})
+		}
+		if f.dialect.atParams {
+			res.WriteString("@" + name)
+		} else {
+			res.WriteString(":" + name)
+		}
+	}
+	return res.String(), nil
+}
+
+// claimValue returns a scalar claim with the endpoint param type it binds as
+func claimValue(claims map[string]any, name string) (any, string, error) {
+	switch v := claims[name].(type) {
+	case nil:
+		return nil, "", fmt.Errorf("%w: claim %s is required", gw_errors.ErrNotAuthorized, name)
+	case string:
+		return v, "string", nil
+	case bool:
+		return v, "boolean", nil
+	case int, int32, int64, uint, uint32, uint64:
+		return v, "number", nil
+	case float64:
+		if v == math.Trunc(v) {
+			return v, "number", nil
+		}
+		return fmt.Sprint(v), "string", nil
+	case fmt.Stringer:
+		return v.String(), "string", nil
+	default:
+		return nil, "", fmt.Errorf("%w: claim %s is not a scalar", gw_errors.ErrNotAuthorized, name)
+	}
+}
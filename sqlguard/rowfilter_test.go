@@ -0,0 +1,248 @@
+package sqlguard
+
+import (
+	"testing"
+
+	gw_errors "github.com/centralmind/gateway/errors"
+	"github.com/centralmind/gateway/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRowFilterApply(t *testing.T) {
+	f, err := NewRowFilter("postgres", map[string]string{
+		"orders":           "tenant_id = :claims.org_id",
+		"public.customers": ":claims.role = 'admin' OR org_id = :claims.org_id",
+	})
+	require.NoError(t, err)
+	claims := map[string]any{"org_id": float64(7), "role": "support"}
+
+	for _, tc := range []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "table keeps its name",
+			query: "SELECT orders.id FROM orders WHERE status = 'new'",
+			want:  "SELECT orders.id FROM (SELECT * FROM orders WHERE (tenant_id = :rls_claims_org_id)) orders WHERE status = 'new'",
+		},
+		{
+			name:  "alias is kept",
+			query: "SELECT o.id FROM orders AS o JOIN public.customers c ON c.id = o.customer_id",
+			want: "SELECT o.id FROM (SELECT * FROM orders WHERE (tenant_id = :rls_claims_org_id)) AS o " +
+				"JOIN (SELECT * FROM public.customers WHERE (:rls_claims_role = 'admin' OR org_id = :rls_claims_org_id)) c ON c.id = o.customer_id",
+		},
+		{
+			name:  "subqueries and CTEs",
+			query: "WITH o AS (SELECT * FROM orders) SELECT * FROM o WHERE id IN (SELECT order_id FROM items)",
+			want:  "WITH o AS (SELECT * FROM (SELECT * FROM orders WHERE (tenant_id = :rls_claims_org_id)) orders) SELECT * FROM o WHERE id IN (SELECT order_id FROM items)",
+		},
+		{
+			name:  "CTE named as the table it reads",
+			query: "WITH orders AS (SELECT * FROM orders) SELECT * FROM orders",
+			want:  "WITH orders AS (SELECT * FROM (SELECT * FROM orders WHERE (tenant_id = :rls_claims_org_id)) orders) SELECT * FROM orders",
+		},
+		{
+			name:  "CTE is not visible outside of its subquery",
+			query: "SELECT * FROM (WITH orders AS (SELECT 1 AS id) SELECT * FROM orders) x, orders",
+			want:  "SELECT * FROM (WITH orders AS (SELECT 1 AS id) SELECT * FROM orders) x, (SELECT * FROM orders WHERE (tenant_id = :rls_claims_org_id)) orders",
+		},
+		{
+			name:  "recursive CTE refers to itself",
+			query: "WITH RECURSIVE orders AS (SELECT 1 AS n UNION ALL SELECT n + 1 FROM orders WHERE n < 3) SELECT * FROM orders",
+			want:  "WITH RECURSIVE orders AS (SELECT 1 AS n UNION ALL SELECT n + 1 FROM orders WHERE n < 3) SELECT * FROM orders",
+		},
+		{
+			name:  "TABLE statement",
+			query: "SELECT * FROM items UNION ALL TABLE orders",
+			want:  "SELECT * FROM items UNION ALL SELECT * FROM (SELECT * FROM orders WHERE (tenant_id = :rls_claims_org_id)) orders",
+		},
+		{
+			name:  "TABLE ONLY statement",
+			query: "TABLE ONLY public.orders",
+			want:  "SELECT * FROM (SELECT * FROM ONLY public.orders WHERE (tenant_id = :rls_claims_org_id)) orders",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			query, params, values, err := f.Apply(tc.query, claims, false)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, query)
+			for _, param := range params {
+				assert.Contains(t, values, param.Name)
+			}
+		})
+	}
+
+	_, params, values, err := f.Apply("SELECT * FROM customers", claims, false)
+	require.NoError(t, err)
+	assert.Equal(t, []model.EndpointParams{
+		{Name: "rls_claims_role", Type: "string", Required: true},
+		{Name: "rls_claims_org_id", Type: "number", Required: true},
+	}, params)
+	assert.Equal(t, map[string]any{"rls_claims_role": "support", "rls_claims_org_id": float64(7)}, values)
+}
+
+func TestRowFilterDenies(t *testing.T) {
+	f, err := NewRowFilter("postgres", map[string]string{"orders": "tenant_id = :claims.org_id"})
+	require.NoError(t, err)
+
+	_, _, _, err = f.Apply("SELECT * FROM orders", map[string]any{}, false)
+	assert.ErrorIs(t, err, gw_errors.ErrNotAuthorized)
+	_, _, _, err = f.Apply("SELECT * FROM orders", map[string]any{"org_id": []any{1, 2}}, false)
+	assert.ErrorIs(t, err, gw_errors.ErrNotAuthorized)
+
+	claims := map[string]any{"org_id": "acme"}
+	_, _, _, err = f.Apply("SELECT * FROM orders JOIN users ON users.id = orders.user_id", claims, false)
+	assert.NoError(t, err)
+	for _, query := range []string{
+		"SELECT * FROM orders JOIN users ON users.id = orders.user_id",
+		"SELECT * FROM read_csv('orders.csv')",
+		"WITH users AS (SELECT * FROM users) SELECT * FROM users",
+		"TABLE users",
+	} {
+		_, _, _, err = f.Apply(query, claims, true)
+		assert.ErrorIs(t, err, ErrRejected, query)
+		assert.ErrorIs(t, f.Check(query), ErrRejected, query)
+	}
+	assert.NoError(t, f.Check("SELECT count(*) FROM orders"))
+	assert.True(t, f.Protected("public.orders"))
+	assert.False(t, f.Protected("users"))
+}
+
+func TestRowFilterDialectParams(t *testing.T) {
+	f, err := NewRowFilter("bigquery", map[string]string{"orders": "tenant_id = :claims.org_id"})
+	require.NoError(t, err)
+	query, _, _, err := f.Apply("SELECT * FROM orders", map[string]any{"org_id": "acme"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM (SELECT * FROM orders WHERE (tenant_id = @rls_claims_org_id)) orders", query)
+}
+
+func TestRowFilterWrites(t *testing.T) {
+	f, err := NewRowFilter("postgres", map[string]string{"orders": "tenant_id = :claims.org_id"})
+	require.NoError(t, err)
+	claims := map[string]any{"org_id": "acme"}
+
+	for _, tc := range []struct {
+		query string
+		want  string
+	}{
+		{
+			query: "UPDATE orders SET status = :status WHERE id = :id",
+			want:  "UPDATE orders SET status = :status WHERE (id = :id) AND (tenant_id = :rls_claims_org_id)",
+		},
+		{
+			query: "DELETE FROM orders WHERE id = :id OR parent_id = :id RETURNING id",
+			want:  "DELETE FROM orders WHERE (id = :id OR parent_id = :id) AND (tenant_id = :rls_claims_org_id) RETURNING id",
+		},
+		{
+			query: "DELETE FROM ONLY public.orders o;",
+			want:  "DELETE FROM ONLY public.orders o WHERE (tenant_id = :rls_claims_org_id);",
+		},
+		{
+			query: "WITH old AS (SELECT id FROM orders WHERE created_at < :at) DELETE FROM orders WHERE id IN (SELECT id FROM old)",
+			want: "WITH old AS (SELECT id FROM (SELECT * FROM orders WHERE (tenant_id = :rls_claims_org_id)) orders WHERE created_at < :at) " +
+				"DELETE FROM orders WHERE (id IN (SELECT id FROM old)) AND (tenant_id = :rls_claims_org_id)",
+		},
+		{
+			query: "WITH gone AS (DELETE FROM orders RETURNING id) SELECT count(*) FROM gone",
+			want:  "WITH gone AS (DELETE FROM orders WHERE (tenant_id = :rls_claims_org_id) RETURNING id) SELECT count(*) FROM gone",
+		},
+		{
+			query: "UPDATE users SET name = :name WHERE id = :id",
+			want:  "UPDATE users SET name = :name WHERE id = :id",
+		},
+		{
+			query: "SELECT * FROM ONLY orders FOR UPDATE",
+			want:  "SELECT * FROM (SELECT * FROM ONLY orders WHERE (tenant_id = :rls_claims_org_id)) orders FOR UPDATE",
+		},
+	} {
+		query, _, _, err := f.Apply(tc.query, claims, false)
+		require.NoError(t, err, tc.query)
+		assert.Equal(t, tc.want, query)
+	}
+
+	for _, query := range []string{
+		"UPDATE orders SET status = 'x' FROM users WHERE users.id = orders.user_id",
+		"DELETE FROM orders USING users WHERE users.id = orders.user_id",
+		"UPDATE orders o JOIN users u ON u.id = o.user_id SET o.status = 'x'",
+		"DELETE o FROM orders o JOIN users u ON u.id = o.user_id",
+		"DELETE FROM orders WHERE CURRENT OF c",
+	} {
+		_, _, _, err := f.Apply(query, claims, false)
+		assert.ErrorIs(t, err, ErrRejected, query)
+	}
+	_, _, _, err = f.Apply("DELETE FROM users WHERE id = :id", claims, true)
+	assert.ErrorIs(t, err, ErrRejected, "strict mode rejects changes of unprotected tables")
+	assert.ErrorIs(t, f.Check("DELETE FROM users"), ErrRejected)
+	assert.NoError(t, f.Check("DELETE FROM orders"))
+}
+
+func TestRowFilterComments(t *testing.T) {
+	policies := map[string]string{"items": "tenant_id = :claims.org_id", "orders": "tenant_id = :claims.org_id"}
+	claims := map[string]any{"org_id": "acme"}
+	mysql, err := NewRowFilter("mysql", policies)
+	require.NoError(t, err)
+	_, _, _, err = mysql.Apply("SELECT * FROM items /*!, orders */", claims, true)
+	assert.ErrorIs(t, err, ErrRejected)
+
+	postgres, err := NewRowFilter("postgres", policies)
+	require.NoError(t, err)
+	query, _, _, err := postgres.Apply("SELECT * FROM items /* /* */ ' */ , orders -- ' */", claims, true)
+	require.NoError(t, err)
+	assert.Contains(t, query, "(SELECT * FROM orders WHERE (tenant_id = :rls_claims_org_id)) orders")
+}
+
+func TestRowFilterInvalidPolicy(t *testing.T) {
+	for _, predicate := range []string{
+		"",
+		"tenant_id = :org_id",
+		"tenant_id = 1; DROP TABLE orders",
+		"tenant_id IN (SELECT id FROM tenants",
+		"tenant_id IN (SELECT id FROM tenants)",
+	} {
+		_, err := NewRowFilter("postgres", map[string]string{"orders": predicate})
+		assert.Error(t, err, predicate)
+	}
+	f, err := NewRowFilter("mongodb", map[string]string{"orders": "tenant_id = 1"})
+	assert.NoError(t, err)
+	assert.Nil(t, f)
+}
+
+func TestQueryColumns(t *testing.T) {
+	columns, renamed, ok := Columns("postgres", "SELECT id, lower(email) AS e FROM users u WHERE u.phone LIKE '1%'")
+	require.True(t, ok)
+	assert.False(t, renamed)
+	assert.Equal(t, []string{"id", "email", "e", "phone"}, columns)
+
+	for _, query := range []string{
+		"SELECT * FROM users",
+		"SELECT u.*, o.id FROM users u JOIN orders o ON o.user_id = u.id",
+		"SELECT count(*) FROM users",
+		"SELECT x.* FROM (SELECT * FROM users) x",
+	} {
+		_, renamed, ok := Columns("postgres", query)
+		require.True(t, ok, query)
+		assert.False(t, renamed, query)
+	}
+	for _, query := range []string{
+		"SELECT row_to_json(u) FROM users u",
+		"SELECT to_json(u.*) FROM users u",
+		"SELECT json_agg(users) FROM users",
+		"WITH x(a, b) AS (SELECT * FROM users) SELECT * FROM x",
+		"SELECT * FROM (SELECT * FROM users) AS t(a, b)",
+		"SELECT id, name FROM orders UNION ALL SELECT * FROM users",
+	} {
+		_, renamed, ok := Columns("postgres", query)
+		require.True(t, ok, query)
+		assert.True(t, renamed, query)
+	}
+	_, renamed, ok = Columns("duckdb", "SELECT lower(COLUMNS(*)) FROM users")
+	require.True(t, ok)
+	assert.True(t, renamed)
+
+	_, _, ok = Columns("postgres", "SELECT (")
+	assert.False(t, ok)
+	_, _, ok = Columns("mongodb", `{"collection": "users"}`)
+	assert.False(t, ok)
+}
@@ -21,19 +21,15 @@ import (
 //go:embed dist
 var swagfs embed.FS
 
-// Schema dynamically generates an OpenAPI 3.1 schema based on the given table schema.
-func Schema(schema model.Config, prefix string, addresses ...string) (*huma.OpenAPI, error) {
+// Schema dynamically generates an OpenAPI 3.1 schema based on the given table schema,
+// connector infers response schemas of endpoint queries.
+func Schema(schema model.Config, connector connectors.Connector, prefix string, addresses ...string) (*huma.OpenAPI, error) {
 	api := huma.DefaultConfig(schema.API.Name, "3.1.0").OpenAPI
 	api.Info.Title = schema.API.Name
 	api.Info.Description = "Config that dynamically generates accessor for data"
 	api.Info.Version = schema.API.Version
 	api.Paths = make(map[string]*huma.PathItem)
 
-	connector, err := connectors.New(schema.Database.Type, schema.Database.Connection)
-	if err != nil {
-		return nil, xerrors.Errorf("unable to init connector: %w", err)
-	}
-
 	// Add all server addresses
 	for i, address := range addresses {
 		var description string
@@ -90,16 +86,18 @@ func Schema(schema model.Config, prefix string, addresses ...string) (*huma.Open
 			}
 			if param.Location == "body" {
 				bodyProps[param.Name] = &huma.Schema{
-					Type:    param.Type,
-					Format:  param.Format,
-					Default: param.Default,
+					Type:        param.Type,
+					Format:      param.Format,
+					Default:     param.Default,
+					Description: param.Description,
 				}
 				continue
 			}
 			params = append(params, &huma.Param{
-				Name:     param.Name,
-				In:       param.Location,
-				Required: param.Required,
+				Name:        param.Name,
+				In:          param.Location,
+				Required:    param.Required,
+				Description: param.Description,
 				Schema: &huma.Schema{
 					Type:    param.Type,
 					Format:  param.Format,
@@ -192,7 +190,7 @@ func Schema(schema model.Config, prefix string, addresses ...string) (*huma.Open
 		}
 	}
 
-	api, err = plugins.Enrich(schema.Plugins, api)
+	api, err := plugins.Enrich(schema.Plugins, api)
 	if err != nil {
 		return nil, xerrors.Errorf("unable to enrich swagger schema: %w", err)
 	}
@@ -449,7 +447,39 @@ func byteHandler(b []byte) http.HandlerFunc {
 	}
 }
 
+// Filter returns a copy of the spec without operations hidden by visible.
+// Paths left without any operation are removed as well.
+func Filter(api *huma.OpenAPI, visible func(operationID string) bool) *huma.OpenAPI {
+	res := *api
+	res.Paths = make(map[string]*huma.PathItem, len(api.Paths))
+	keep := func(op *huma.Operation) *huma.Operation {
+		if op == nil || !visible(op.OperationID) {
+			return nil
+		}
+		return op
+	}
+	for p, item := range api.Paths {
+		filtered := *item
+		filtered.Get = keep(item.Get)
+		filtered.Post = keep(item.Post)
+		filtered.Put = keep(item.Put)
+		filtered.Patch = keep(item.Patch)
+		filtered.Delete = keep(item.Delete)
+		if filtered.Get == nil && filtered.Post == nil && filtered.Put == nil && filtered.Patch == nil && filtered.Delete == nil {
+			continue
+		}
+		res.Paths[p] = &filtered
+	}
+	return &res
+}
+
 func RegisterRoute(mux *http.ServeMux, prefix string, spec []byte) {
+	RegisterRouteHandler(mux, prefix, byteHandler(spec))
+}
+
+// RegisterRouteHandler registers swagger UI with the spec served by handler,
+// which allows serving a different spec for each caller
+func RegisterRouteHandler(mux *http.ServeMux, prefix string, spec http.Handler) {
 	// render the index template with the proper spec name inserted
 	static, err := fs.Sub(swagfs, "dist")
 	if err != nil {
@@ -468,7 +498,7 @@ func RegisterRoute(mux *http.ServeMux, prefix string, spec []byte) {
 	apiJsonPath := path.Join(swaggerPath, "open_api.json")
 
 	// Register the API JSON endpoint
-	mux.HandleFunc(apiJsonPath, byteHandler(spec))
+	mux.Handle(apiJsonPath, spec)
 
 	// Create a simple handler that redirects /swagger to /swagger/
 	rootHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,38 @@
+package xcontext
+
+import "context"
+
+type statsKeyType string
+
+const statsKey statsKeyType = "query_stats"
+
+// QueryStats is filled by connectors with execution details worth reporting to callers
+type QueryStats struct {
+	// EstimatedBytes is the amount of data a query scans, reported by connectors that bill per byte
+	EstimatedBytes int64
+	// Meta is response metadata added by interceptors, e.g. the number of masked values
+	Meta map[string]string
+}
+
+// SetMeta adds response metadata, it is a no-op when stats are not tracked
+func (s *QueryStats) SetMeta(key, value string) {
+	if s == nil {
+		return
+	}
+	if s.Meta == nil {
+		s.Meta = map[string]string{}
+	}
+	s.Meta[key] = value
+}
+
+// WithQueryStats returns a context that collects stats of queries run with it
+func WithQueryStats(ctx context.Context) (context.Context, *QueryStats) {
+	stats := &QueryStats{}
+	return context.WithValue(ctx, statsKey, stats), stats
+}
+
+// Stats returns stats collected for ctx, nil when the caller does not track them
+func Stats(ctx context.Context) *QueryStats {
+	stats, _ := ctx.Value(statsKey).(*QueryStats)
+	return stats
+}